@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// clipboardProvider reads and writes the clipboard. It is an interface so the system
+// clipboard can be disabled (--no-clipboard, noclipboard build tag) or faked in tests.
+type clipboardProvider interface {
+	// Available probes whether the clipboard can be used at all. It is checked before any
+	// output, so that falling back to stdout does not mix the prompt with progress messages.
+	Available() bool
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// errNoClipboard is returned when clipboard support is disabled
+var errNoClipboard = errors.New("clipboard support is disabled")
+
+// noClipboard is a clipboardProvider for headless use: every operation fails
+type noClipboard struct{}
+
+func (noClipboard) Available() bool            { return false }
+func (noClipboard) ReadAll() (string, error)   { return "", errNoClipboard }
+func (noClipboard) WriteAll(text string) error { return errNoClipboard }
+
+// clipboardBackend is the clipboard used for -c, --clipboard-context, and the default output
+var clipboardBackend = defaultClipboard()
+
+// copyOrFallback copies text to the clipboard. If that fails, it warns and writes text to
+// fallback instead. It reports whether the text was copied to the clipboard.
+func copyOrFallback(provider clipboardProvider, text string, fallback io.Writer) bool {
+	if err := provider.WriteAll(text); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Cannot copy to the clipboard (%v); writing the prompt to stdout instead.\n", err)
+		fmt.Fprint(fallback, text)
+		return false
+	}
+	return true
+}
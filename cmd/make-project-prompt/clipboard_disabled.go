@@ -0,0 +1,9 @@
+//go:build noclipboard
+
+package main
+
+// defaultClipboard returns the clipboard used unless --no-clipboard is given. Builds with the
+// noclipboard tag have no clipboard support, for headless servers.
+func defaultClipboard() clipboardProvider {
+	return noClipboard{}
+}
@@ -0,0 +1,26 @@
+//go:build !noclipboard
+
+package main
+
+import "github.com/atotto/clipboard"
+
+// systemClipboard uses the system clipboard (xclip, xsel, or wl-clipboard on Linux)
+type systemClipboard struct{}
+
+func (systemClipboard) ReadAll() (string, error)   { return clipboard.ReadAll() }
+func (systemClipboard) WriteAll(text string) error { return clipboard.WriteAll(text) }
+
+// Available probes the clipboard with a write, as a clipboard tool may be installed on a host
+// without a display. Writing the current content back leaves the clipboard unchanged.
+func (systemClipboard) Available() bool {
+	if clipboard.Unsupported {
+		return false
+	}
+	content, _ := clipboard.ReadAll()
+	return clipboard.WriteAll(content) == nil
+}
+
+// defaultClipboard returns the clipboard used unless --no-clipboard is given
+func defaultClipboard() clipboardProvider {
+	return systemClipboard{}
+}
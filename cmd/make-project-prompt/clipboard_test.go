@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard is an in-memory clipboardProvider
+type fakeClipboard struct {
+	content  string
+	writeErr error
+}
+
+func (f *fakeClipboard) Available() bool          { return f.writeErr == nil }
+func (f *fakeClipboard) ReadAll() (string, error) { return f.content, nil }
+func (f *fakeClipboard) WriteAll(text string) error {
+	if f.writeErr != nil {
+		return f.writeErr
+	}
+	f.content = text
+	return nil
+}
+
+func TestCopyOrFallback(t *testing.T) {
+	t.Run("Copies to the clipboard", func(t *testing.T) {
+		provider := &fakeClipboard{}
+		var stdout strings.Builder
+
+		if !copyOrFallback(provider, "prompt", &stdout) {
+			t.Error("Expected the prompt to be copied")
+		}
+		if provider.content != "prompt" {
+			t.Errorf("Expected clipboard to contain the prompt, got %q", provider.content)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("Expected nothing on stdout, got %q", stdout.String())
+		}
+	})
+
+	t.Run("Falls back to stdout when the clipboard fails", func(t *testing.T) {
+		provider := &fakeClipboard{writeErr: errors.New("no display")}
+		var stdout strings.Builder
+
+		if copyOrFallback(provider, "prompt", &stdout) {
+			t.Error("Expected the copy to fail")
+		}
+		if stdout.String() != "prompt" {
+			t.Errorf("Expected the prompt on stdout, got %q", stdout.String())
+		}
+	})
+
+	t.Run("Disabled clipboard falls back to stdout", func(t *testing.T) {
+		var stdout strings.Builder
+
+		if copyOrFallback(noClipboard{}, "prompt", &stdout) {
+			t.Error("Expected the copy to fail")
+		}
+		if stdout.String() != "prompt" {
+			t.Errorf("Expected the prompt on stdout, got %q", stdout.String())
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// errorCategory classifies fatal errors for scripts, with a stable name and exit code.
+// The exit codes are documented in the README and must not change.
+type errorCategory struct {
+	name     string // "code" field of the --json-errors object
+	exitCode int
+}
+
+var (
+	errGeneric      = errorCategory{"error", 1}          // Anything not classified below
+	errUsage        = errorCategory{"usage", 2}          // Invalid flags or flag combinations
+	errGit          = errorCategory{"git", 3}            // Not in a Git repository, or git failed
+	errNoFiles      = errorCategory{"no_files", 4}       // No file matched or could be included
+	errOutput       = errorCategory{"output", 5}         // The prompt could not be written
+	errConfig       = errorCategory{"config", 6}         // Invalid .mpp.txt, unknown alias or profile
+	errInput        = errorCategory{"input", 7}          // A question, context, list or template could not be read
+	errTooManyFiles = errorCategory{"too_many_files", 8} // More files matched than --max-files
+	errSecrets      = errorCategory{"secrets", 9}        // Secret-like content found with --fail-on-secrets
+)
+
+// categorizedError is an error tagged with its category where it is detected
+type categorizedError struct {
+	category errorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+// withCategory tags err with a category, which fatalErr reports even once err is wrapped
+func withCategory(category errorCategory, err error) error {
+	return &categorizedError{category: category, err: err}
+}
+
+// jsonError is the single-line object fatal errors are reported as with --json-errors
+type jsonError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// fatalf reports a fatal error, as a log line or with --json-errors as a JSON object on stderr,
+// and exits with the exit code of the category
+func fatalf(category errorCategory, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	line, err := json.Marshal(jsonError{Error: strings.TrimPrefix(message, "Error: "), Code: category.name})
+	if jsonErrors && err == nil {
+		fmt.Fprintln(os.Stderr, string(line))
+	} else {
+		log.Print(message)
+	}
+	os.Exit(category.exitCode)
+}
+
+// fatalErr reports err with fatalf, in the category err was tagged with if any, else in category
+func fatalErr(category errorCategory, err error) {
+	fatalf(categoryOf(err, category), "Error: %v", err)
+}
+
+// categoryOf returns the category err, or an error it wraps, was tagged with, else fallback
+func categoryOf(err error, fallback errorCategory) errorCategory {
+	var categorized *categorizedError
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+	return fallback
+}
@@ -3,38 +3,144 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/briossant/make-project-prompt/pkg/config"
 	"github.com/briossant/make-project-prompt/pkg/files"
 	"github.com/briossant/make-project-prompt/pkg/prompt"
 )
 
+// defaultMaxFiles is the default --max-files cap, high enough for real projects but low
+// enough to stop an accidental run on a huge repository
+const defaultMaxFiles = 1000
+
 // Command-line flags
 var (
 	includePatterns      multiStringFlag
 	excludePatterns      multiStringFlag
+	excludeDirs          multiStringFlag
 	forceIncludePatterns multiStringFlag
+	includeIgnored       multiStringFlag
+	trackedOnly          bool
+	untrackedOnly        bool
+	autoReadme           bool
+	autoDocs             bool
+	filesFrom            multiStringFlag
+	filesFrom0           multiStringFlag
+	gitRef               string
+	repoDirs             multiStringFlag
+	repos                []prompt.Repo   // Repositories listed under their label, with several --repo
 	questions            multiStringFlag // Changed to support multiple questions
 	questionFiles        multiStringFlag // Changed to support multiple question files
+	questionsFiles       multiStringFlag
+	questionsDelimiter   string
 	useClipboard         bool
 	outputFile           string
+	appendOutput         bool
 	useStdout            bool
-	quietMode            bool
+	teeOutput            bool
+	noClipboardFlag      bool
+	verbosity            = prompt.VerbosityNormal // --quiet, -v or -vv
+	explainMode          bool
+	interactive          bool
 	showHelp             bool
 	dryRun               bool
+	printCommand         bool
 	aliasName            string
+	saveAliasName        string
 	listAliases          bool
+	showConfig           bool
+	validateAliases      bool
+	configWalkToRoot     bool
 	rawMode              bool
+	oneFilePerQuestion   bool
+	reviewPlanFile       string
+	questionPrefix       string
+	questionSuffix       string
+	numberQuestions      bool
+	questionsOrder       string
+	roleMessage          string
+	lastWords            string
+	beginMarker          string
+	endMarker            string
+	profileName          string
+	promptTemplateFile   string
+	headLines            int
+	tailLines            int
+	skipMinified         bool
+	minifiedThreshold    int
+	longLineThreshold    int
+	indentTabWidth       int
+	maxFiles             int
+	excludeLargerThan    int64
+	maxTotalBytes        int64
+	maxTokens            int
+	autoTrim             bool
+	treeRoot             string
+	treeDepth            int
+	treeMatched          bool
+	useTreeCommand       bool
+	noTree               bool
+	treeOnly             bool
+	annotateLanguage     bool
+	groupByPattern       bool
+	relativeTo           string
+	stripPrefix          string
+	hashContent          bool
+	fileMetadata         bool
+	fileManifest         bool
+	includeDeps          bool
+	compact              bool
+	noEndMarkers         bool
+	dedupeBlankLines     bool
+	filterCommand        string
+	postProcessCommand   string
+	outline              bool
+	noTests              bool
+	noHidden             bool
+	respectLocalExclude  bool
+	maxDepth             int
+	ignoreCase           bool
+	noDefaultExclude     bool
+	compareTo            string
+	showStats            bool
+	jsonErrors           bool
+	selectionTimings     files.Timings // Time spent listing and filtering files, for --stats
+	fileReadTime         time.Duration // Time spent reading the files, for --stats
+	scanSecrets          bool
+	failOnSecrets        bool
+	redact               bool
+	secretPatterns       []prompt.SecretPattern // Extra secret patterns defined with @redact-pattern
+	secretFindings       []prompt.SecretFinding // Secret-like content found with --scan-secrets
+	testPatterns         []string               // Patterns excluded by --no-tests (nil = files.DefaultTestPatterns)
+	defaultQuestion      string                 // Question asked when none is given (from @default-question, "" = placeholder)
+	allowDuplicates      bool
+	strictText           bool
+	followSymlinks       bool
+	includeEmpty         bool
+	binaryBase64         bool
+	includeGenerated     bool
+	textExtensions       []string // Extensions treated as text from --text-ext
+	languages            []string // Languages selected with --lang
+	onlyConflicts        bool
+	warnConflicts        bool
+	slotOverrides        = map[string]string{} // Question slot overrides from --q-slot, by slot name
+	slotOverrideNames    []string              // Slot names in the order they were overridden
 )
 
+// includeTree tells whether the project tree is included in the prompt according to the
+// selected profile; --no-tree takes precedence
+var includeTree = true
+
 // argOrderItem tracks the order of -i, -q, -qf, -c flags for raw mode
 type argOrderItem struct {
-	Type    string // "include", "question", "question_file", "clipboard"
+	Type    string // "include", "question", "question_file", "questions_file", "clipboard"
 	Content string // The pattern or question content
 	Order   int    // Position in argument list
 }
@@ -53,41 +159,250 @@ func (m *multiStringFlag) Set(value string) error {
 	return nil
 }
 
+// slotOverrideFlag is a custom flag type recording --q-slot name=text overrides in slotOverrides
+type slotOverrideFlag struct{}
+
+func (slotOverrideFlag) String() string {
+	return ""
+}
+
+func (slotOverrideFlag) Set(value string) error {
+	name, text, err := config.ParseSlotOverride(value)
+	if err != nil {
+		return err
+	}
+	if _, exists := slotOverrides[name]; !exists {
+		slotOverrideNames = append(slotOverrideNames, name)
+	}
+	slotOverrides[name] = text
+	return nil
+}
+
 // Initialize flags
 func init() {
 	flag.Var(&includePatterns, "i", "Pattern (glob) to INCLUDE files/folders (default: '*' if no -i is provided).\n                 Can be used multiple times (e.g., -i 'src/*' -i '*.py').")
 	flag.Var(&excludePatterns, "e", "Pattern (glob) to EXCLUDE files/folders (e.g., -e '*.log' -e 'tests/data/*').\n                 Can be used multiple times.")
+	flag.Var(&excludeDirs, "exclude-dir", "Name (glob) of directories to EXCLUDE at any depth, with everything under them (e.g., --exclude-dir __pycache__),\n                 unlike -e never matching files. Can be used multiple times.")
+	flag.String("include-from", "", "Read INCLUDE patterns from a file (one glob per line, # for comments). Can be used multiple times.")
+	flag.String("exclude-from", "", "Read EXCLUDE patterns from a file (one glob per line, # for comments). Can be used multiple times.")
+	flag.BoolVar(&noDefaultExclude, "no-default-exclude", false, "Ignore the '@default-exclude: ...' patterns of .mpp.txt for this run.")
 	flag.Var(&forceIncludePatterns, "f", "Pattern (glob) to FORCE INCLUDE files/folders, bypassing file type and size checks.\n                 Can be used multiple times (e.g., -f 'assets/*.bin' -f 'data/*.dat').")
+	flag.Var(&includeIgnored, "include-ignored", "Pattern (glob) to INCLUDE files ignored by Git, still skipping binary and oversized files\n                 (unlike -f). Can be used multiple times.")
+	flag.Var(&repoDirs, "repo", "Run against the Git repository (or directory of one) at this path instead of the current directory.\n                 Listed paths are relative to it; files given to other options (e.g. --output, -qf) stay relative to the current directory.\n                 Can be used multiple times: the files of each repository are then listed under its directory name.")
+	flag.BoolVar(&trackedOnly, "tracked-only", false, "Consider only the files tracked by Git, leaving out new files not yet added (-f still applies).")
+	flag.BoolVar(&untrackedOnly, "untracked-only", false, "Consider only the new files not yet added to Git (untracked and not ignored), e.g. to review just the files you created.")
+	flag.BoolVar(&autoReadme, "auto-readme", false, "Always include the top-level README.* in a leading '--- AUTO-INCLUDED CONTEXT ---' section, regardless of -i/-e.\n                 Nothing is added when there is no README. Not available with --raw, --review-plan or --files-from.")
+	flag.BoolVar(&autoDocs, "auto-docs", false, "With --auto-readme, also include the Markdown files directly under docs/ (docs/*.md).")
+	flag.Var(&filesFrom0, "files-from0", "Like --files-from, with NUL-separated paths (e.g. from find -print0 or git ls-files -z), for paths holding spaces or newlines.")
+	flag.Var(&filesFrom, "files-from", "Read the exact list of files to include from a file (one path per line, - for stdin), without glob matching.\n                 Exclude patterns still apply; cannot be combined with -i, -f or --include-ignored. Can be used multiple times.")
+	flag.StringVar(&gitRef, "at", "", "Read the files and project structure from a Git revision (commit, branch or tag) instead of the working tree.")
 	flag.Var(&questions, "q", "Specifies a question or text for the LLM. Can be used multiple times - all questions will be included.")
+	flag.Var(slotOverrideFlag{}, "q-slot", "Override a named question slot declared by an alias with '-q \"@slot:name default text\"'.\n                 Format: --q-slot name=text. Can be used multiple times.")
+	flag.StringVar(&questionPrefix, "question-prefix", "", "Text prepended to every question (e.g. --question-prefix \"Please \").")
+	flag.StringVar(&questionSuffix, "question-suffix", "", "Text appended to every question (e.g. --question-suffix \" Explain your reasoning.\").")
+	flag.BoolVar(&numberQuestions, "number-questions", false, "Number the questions (1. ..., 2. ...) at the end of the prompt.")
+	flag.StringVar(&questionsOrder, "questions-order", string(prompt.QuestionsAsGiven), "Order of the questions at the end of the prompt: as-given, asc (first given first) or desc (first given last,\n                 where models weight it most). Not available with --raw or --review-plan.")
+	flag.StringVar(&roleMessage, "role-message", "", "Text placed at the very top of the prompt (e.g. --role-message \"You are a Go expert\").")
+	flag.String("extra-context", "", "Additional context placed after the file content. Can be used multiple times.\n                 In --raw mode, it is placed at its argument position.")
+	flag.String("extra-context-file", "", "Path to a file containing additional context, as with --extra-context. Can be used multiple times.")
+	flag.StringVar(&lastWords, "last-words", "", "Text placed at the very end of the prompt.")
+	flag.StringVar(&beginMarker, "begin-marker", "", "Line placed before the whole prompt, for scripts extracting it from mixed output (e.g. <<<PROMPT>>>).")
+	flag.StringVar(&endMarker, "end-marker", "", "Line placed after the whole prompt (e.g. <<<END>>>).")
+	flag.StringVar(&profileName, "profile", "", "Apply a prompt preset defined in .mpp.txt with '@profile name: role=\"...\" tree=false footer=\"...\"'.\n                 --role-message and --last-words take precedence over the profile.")
+	flag.StringVar(&promptTemplateFile, "prompt-template", "", "Path to a Go text/template file rendering the whole prompt instead of the built-in layout.\n                 Available fields: .RoleMessage, .Tree, .TreeHeader, .Files (.Path, .Language, .Hash, .Content),\n                 .Questions, .ExtraContext, .LastWords, .ContentHash.")
 	flag.BoolVar(&useClipboard, "c", false, "Use clipboard content as a question for the LLM.")
+	flag.Bool("clipboard-context", false, "Use clipboard content as additional context (like --extra-context) instead of as a question.")
 	flag.Var(&questionFiles, "qf", "Path to a file containing a question for the LLM. Can be used multiple times.")
+	flag.Var(&questionsFiles, "questions-file", "Path to a file containing several questions separated by a line of --- (see --questions-delimiter).\n                 Each question is added in order; empty ones are skipped. Can be used multiple times.")
+	flag.StringVar(&questionsDelimiter, "questions-delimiter", config.DefaultQuestionsDelimiter, "Line separating the questions of a --questions-file.")
+	flag.BoolVar(&jsonErrors, "json-errors", false, "Report fatal errors on stderr as a single-line JSON object, {\"error\":\"...\",\"code\":\"no_files\"},\n                 instead of a log line (see Exit codes in the README for the codes).")
+	flag.BoolVar(&showStats, "stats", false, "After generating, report on stderr the time spent listing, filtering and reading files, and the size of the prompt.")
+	flag.BoolVar(&scanSecrets, "scan-secrets", false, "Warn on stderr about secret-like content in the included files (AWS keys, private keys, tokens,\n                 long values assigned to API_KEY-like names), with file and line.")
+	flag.BoolVar(&failOnSecrets, "fail-on-secrets", false, "Like --scan-secrets, but abort without writing the prompt when secret-like content is found.")
+	flag.BoolVar(&redact, "redact", false, "Replace secret-like content (as found by --scan-secrets) with ***REDACTED*** in the included files.\n                 Add project-specific patterns with '@redact-pattern name: regexp' in .mpp.txt.")
+	flag.StringVar(&compareTo, "compare-to", "", "After generating, report the change in file count, bytes, and estimated tokens\n                 compared to a previously generated prompt file (on stderr).")
 	flag.StringVar(&outputFile, "output", "", "Write prompt to a file instead of the clipboard.")
+	flag.BoolVar(&appendOutput, "append", false, "With --output, append the prompt to the file, after a timestamped separator, instead of overwriting it.")
 	flag.BoolVar(&useStdout, "stdout", false, "Write prompt to stdout instead of the clipboard.")
-	flag.BoolVar(&quietMode, "quiet", false, "Suppress all non-essential output. Useful with --stdout or --output for scripting.")
+	flag.BoolVar(&noClipboardFlag, "no-clipboard", false, "Disable clipboard support: the prompt is written to stdout unless --output is given.")
+	flag.BoolVar(&teeOutput, "tee", false, "Copy the prompt to the clipboard AND print it to stdout.")
+	flag.Bool("quiet", false, "Suppress all non-essential output. Useful with --stdout or --output for scripting.")
+	flag.Bool("v", false, "Verbose: also report on stderr why each file is included or skipped.")
+	flag.Bool("vv", false, "Very verbose: like -v, and also report the time spent listing files and generating the prompt.")
+	flag.BoolVar(&explainMode, "explain", false, "Report on stderr why each file is skipped (no include match, excluded by a pattern, binary, ...).")
+	flag.BoolVar(&interactive, "interactive", false, "List the candidate files and choose interactively (on stdin) which ones to include before generating.")
 	flag.BoolVar(&dryRun, "dry-run", false, "Perform a dry run. Lists the files that would be included in the prompt without generating it.")
+	flag.BoolVar(&printCommand, "print-command", false, "With --dry-run, also print a make-project-prompt command reproducing the file selection (aliases expanded).")
 	flag.BoolVar(&showHelp, "h", false, "Displays this help message.")
 	flag.StringVar(&aliasName, "a", "", "Use a predefined alias from config files.")
+	flag.StringVar(&saveAliasName, "save-alias", "", "Save the options of this invocation as an alias in the nearest .mpp.txt file (created if needed).")
 	flag.BoolVar(&listAliases, "list-aliases", false, "List all available aliases from config files.")
+	flag.BoolVar(&configWalkToRoot, "config-walk-to-root", false, "Load the .mpp.txt files of every parent directory up to the file system root, instead of stopping\n                 at the Git repository root (the global ~/.mpp.txt is loaded either way).")
+	flag.BoolVar(&validateAliases, "validate-aliases", false, "Check the options of every alias as they would be parsed: unknown flags, missing or malformed values,\n                 and ignored arguments. Exits with status 6 when an alias is invalid.")
+	flag.BoolVar(&showConfig, "show-config", false, "Print the loaded .mpp.txt files in search order, every alias and directive with its source file,\n                 and the definitions ignored because an earlier file already defined them (the first one wins).")
 	flag.BoolVar(&rawMode, "raw", false, "Raw mode: remove pre-written messages and use argument order for positioning.")
+	flag.BoolVar(&oneFilePerQuestion, "one-file-per-question", false, "Pair each -i with the -q following it: the files are immediately followed by their question,\n                 labeled \"Regarding <files>:\". Implies --raw; every -i must be followed by a question.")
+	flag.String("exclude-larger-than", "", "Exclude files larger than this size (e.g. 100k, 2M), unless force included.")
+	flag.IntVar(&maxTokens, "max-tokens", 0, "Warn when the estimated tokens of the prompt (about 4 bytes per token) exceed N (0 = no limit).")
+	flag.BoolVar(&autoTrim, "auto-trim", false, "With --max-tokens, drop the least relevant files until the prompt fits: files matched by later -i patterns go first,\n                 then larger files, then older ones (-f files are kept longest). The dropped files are reported on stderr.")
+	flag.String("max-total-bytes", "", "Stop adding files once the next one would bring the file content above this size (e.g. 500k, 2M);\n                 the files left out are reported on stderr.")
+	flag.IntVar(&maxFiles, "max-files", defaultMaxFiles, "Abort if more than N files match after filtering (0 = no limit).")
+	flag.IntVar(&headLines, "head", 0, "Include the first N lines of files exceeding the size limit instead of skipping them.")
+	flag.IntVar(&tailLines, "tail", 0, "Include the last N lines of files exceeding the size limit instead of skipping them.\n                 Combined with --head, the middle of the file is elided.")
+	flag.BoolVar(&annotateLanguage, "annotate-language", false, "Add the detected language to each file header (e.g. --- FILE: src/app.go (go) ---).")
+	flag.BoolVar(&groupByPattern, "group-by-pattern", false, "Group the files under a header naming the -i/-f pattern that matched them (e.g. === Files matching src/* ===).")
+	flag.StringVar(&relativeTo, "relative-to", "", "Show the file paths in the prompt relative to this directory (files are still read from their real path).")
+	flag.StringVar(&stripPrefix, "strip-prefix", "", "Remove this prefix from the file paths shown in the prompt (applied after --relative-to).")
+	flag.BoolVar(&hashContent, "hash", false, "Add a short content hash to each file header (e.g. --- FILE: app.go [sha256:ab12cd34ef56] ---)\n                 and a combined hash of all included files after the file content.")
+	flag.BoolVar(&includeDeps, "deps", false, "Add a '--- DEPENDENCIES ---' section summarizing the go.mod, package.json and requirements.txt found at the root\n                 (module list and versions, rather than the raw files). Not available with --raw or --review-plan.")
+	flag.BoolVar(&noEndMarkers, "no-end-markers", false, "Keep the '--- FILE: path ---' headers but leave out the '--- END FILE: path ---' lines, halving the separator overhead.\n                 --raw and --review-plan keep the END FILE markers.")
+	flag.BoolVar(&compact, "compact", false, "Separate files with a single '### path' line instead of '--- FILE: path ---' and '--- END FILE: path ---' pairs,\n                 saving tokens on prompts with many files. --raw and --review-plan keep the END FILE markers.")
+	flag.BoolVar(&fileManifest, "file-manifest", false, "List the paths of all included files in an '--- INCLUDED FILES (N) ---' section closing the file content.")
+	flag.BoolVar(&fileMetadata, "file-metadata", false, "Add a compact metadata line after each file header (e.g. size: 1.2KB, modified: 2024-01-02, language: go).")
+	flag.BoolVar(&dedupeBlankLines, "dedupe-blank-between-files", false, "Drop trailing blank lines from file content so that files are separated by exactly one blank line.")
+	flag.BoolVar(&outline, "outline", false, "Include only the outline of Go files (package, imports, types and function signatures, without bodies),\n                 for architecture-level questions. Files of other languages are included whole.")
+	flag.StringVar(&filterCommand, "filter-cmd", "", "Shell command each file's content is piped through before inclusion (e.g. 'jq .').\n                 The file path is available as $MPP_FILE; on failure the raw content is kept.")
+	flag.StringVar(&postProcessCommand, "post-process", "", "Shell command the whole generated prompt is piped through before output (e.g. a minifier).\n                 If it fails, the prompt is not written and its stderr is shown.")
+	flag.String("text-ext", "", "Comma-separated extensions to treat as text (e.g. .foo,.bar); unlike -f, size limits still apply.")
+	flag.BoolVar(&includeGenerated, "include-generated", false, "Keep the files marked linguist-generated in .gitattributes (excluded by default).")
+	flag.BoolVar(&includeEmpty, "include-empty", false, "Include empty files, force included ones included (empty files are skipped by default).")
+	flag.BoolVar(&binaryBase64, "binary-base64", false, "Embed force included binary files base64-encoded, after a note about the encoding.")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "Include symlinked files by reading their target (symlinks are skipped by default).")
+	flag.BoolVar(&strictText, "strict-text", false, "Always inspect file content and skip files with null bytes or many non-printable characters,\n                 whatever their extension (unless force included).")
+	flag.BoolVar(&onlyConflicts, "only-conflicts", false, "Include only files containing git conflict markers (<<<<<<<, =======, >>>>>>>).")
+	flag.BoolVar(&warnConflicts, "warn-conflicts", false, "Warn about included files containing git conflict markers.")
+	flag.String("lang", "", "Comma-separated languages to include (e.g. go,python), as detected from the file extension or shebang;\n                 composes with -i and -e, unless force included.")
+	flag.BoolVar(&ignoreCase, "ignore-case", false, "Match the -i, -e, -f and --include-ignored patterns case-insensitively (e.g. -i '*.MD' matches README.md).")
+	flag.IntVar(&maxDepth, "depth", -1, "Only include files at most N directories deep (0 = top-level files only), whatever the -i patterns, unless force included.")
+	flag.BoolVar(&respectLocalExclude, "respect-local-exclude", false, "Also exclude the tracked files matched by the local ignore rules of .git/info/exclude\n                 (Git only applies them to untracked files). Negated rules (!pattern) are not supported.")
+	flag.BoolVar(&noHidden, "no-hidden", false, "Exclude hidden files, whose path has a component starting with a dot (.env, .gitignore, .github/...), unless force included.")
+	flag.BoolVar(&noTests, "no-tests", false, "Exclude test files (*_test.go, *.test.*, *.spec.*, __tests__/, test/, tests/, ...), unless force included.\n                 The patterns can be overridden with '@test-patterns: ...' in .mpp.txt.")
+	flag.BoolVar(&skipMinified, "skip-minified", false, "Skip files that look minified (average line length above the threshold), unless force included.")
+	flag.IntVar(&minifiedThreshold, "minified-threshold", files.DefaultMinifiedLineLength, "Average line length above which --skip-minified considers a file minified.")
+	flag.IntVar(&indentTabWidth, "tabs", 0, "Replace each run of N spaces of leading indentation with a tab to save tokens.\n                 Strings spanning several lines are kept; languages whose multi-line strings are not recognized (e.g. Rust, C++, shell)\n                 and whitespace-sensitive ones such as Python and YAML are left untouched.")
+	flag.IntVar(&longLineThreshold, "flag-long-lines", 0, "Warn about the included files holding lines longer than N characters, so they can be excluded.")
+	flag.IntVar(&treeDepth, "tree-depth", 0, "Limit the project structure to N directory levels (passed as -L N with --tree-cmd).")
+	flag.BoolVar(&treeMatched, "tree-matched", false, "Build the project structure from the included files only, so it exactly reflects the prompt.")
+	flag.BoolVar(&useTreeCommand, "tree-cmd", false, "Render the project structure with the external 'tree' command instead of the built-in renderer.")
+	flag.BoolVar(&noTree, "no-tree", false, "Leave the project structure out of the prompt.")
+	flag.BoolVar(&treeOnly, "tree-only", false, "Include only the project structure and the questions, without any file content.")
+	flag.StringVar(&treeRoot, "tree-root", "", "Render the project structure rooted at this directory instead of the whole project.")
+	flag.BoolVar(&allowDuplicates, "allow-duplicates", false, "In --raw mode, allow a file matched by several -i/-f patterns to appear more than once.")
+	flag.StringVar(&reviewPlanFile, "review-plan", "", "Path to a review plan file with one 'glob => question' per line.\n                 The files matching each glob are followed by that glob's question.")
 
 	// Override usage message
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-i <include_pattern>] [-e <exclude_pattern>] [-f <force_include_pattern>] [-q \"text\"] [-c] [-qf file] [--raw] [-a \"alias\"] [--list-aliases] [--stdout] [--quiet] [--dry-run] [--output file] [-h]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [-i <include_pattern>] [-e <exclude_pattern>] [--exclude-dir name] [--lang go,python] [--ignore-case] [--no-tests] [--no-hidden] [--respect-local-exclude] [--depth N] [--include-from file] [--exclude-from file] [--no-default-exclude] [-f <force_include_pattern>] [--include-ignored pattern] [--tracked-only] [--untracked-only] [--auto-readme] [--auto-docs] [--files-from file] [--files-from0 file] [--at ref] [--repo path] [-q \"text\"] [--q-slot name=text] [--question-prefix text] [--question-suffix text] [--number-questions] [--questions-order as-given|asc|desc] [--role-message text] [--extra-context text] [--extra-context-file file] [--last-words text] [--begin-marker text] [--end-marker text] [--profile name] [--prompt-template file] [-c] [--clipboard-context] [-qf file] [--questions-file file] [--questions-delimiter text] [--raw] [--one-file-per-question] [--allow-duplicates] [--review-plan file] [--exclude-larger-than size] [--max-files N] [--max-total-bytes size] [--max-tokens N] [--auto-trim] [--head N] [--tail N] [--annotate-language] [--group-by-pattern] [--relative-to dir] [--strip-prefix prefix] [--hash] [--file-metadata] [--no-end-markers] [--compact] [--file-manifest] [--deps] [--dedupe-blank-between-files] [--tabs N] [--filter-cmd command] [--post-process command] [--outline] [--text-ext .foo,.bar] [--include-generated] [--follow-symlinks] [--include-empty] [--binary-base64] [--strict-text] [--only-conflicts] [--warn-conflicts] [--skip-minified] [--minified-threshold N] [--flag-long-lines N] [--no-tree] [--tree-only] [--tree-root dir] [--tree-depth N] [--tree-matched] [--tree-cmd] [-a \"alias\"] [--save-alias name] [--list-aliases] [--validate-aliases] [--show-config] [--config-walk-to-root] [--stdout] [--tee] [--no-clipboard] [--quiet] [-v] [-vv] [--explain] [--interactive] [--dry-run] [--print-command] [--output file] [--append] [--compare-to file] [--scan-secrets] [--fail-on-secrets] [--redact] [--stats] [--json-errors] [-h]\n\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "Options:")
 		// Custom print defaults to match README style
 		fmt.Fprintf(os.Stderr, "  -i <pattern> : %s\n", flag.Lookup("i").Usage)
 		fmt.Fprintf(os.Stderr, "  -e <pattern> : %s\n", flag.Lookup("e").Usage)
+		fmt.Fprintf(os.Stderr, "  --exclude-dir <name> : %s\n", flag.Lookup("exclude-dir").Usage)
+		fmt.Fprintf(os.Stderr, "  --lang <languages> : %s\n", flag.Lookup("lang").Usage)
+		fmt.Fprintf(os.Stderr, "  --ignore-case : %s\n", flag.Lookup("ignore-case").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-tests    : %s\n", flag.Lookup("no-tests").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-hidden   : %s\n", flag.Lookup("no-hidden").Usage)
+		fmt.Fprintf(os.Stderr, "  --respect-local-exclude : %s\n", flag.Lookup("respect-local-exclude").Usage)
+		fmt.Fprintf(os.Stderr, "  --depth N     : %s\n", flag.Lookup("depth").Usage)
+		fmt.Fprintf(os.Stderr, "  --include-from <file> : %s\n", flag.Lookup("include-from").Usage)
+		fmt.Fprintf(os.Stderr, "  --exclude-from <file> : %s\n", flag.Lookup("exclude-from").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-default-exclude : %s\n", flag.Lookup("no-default-exclude").Usage)
 		fmt.Fprintf(os.Stderr, "  -f <pattern> : %s\n", flag.Lookup("f").Usage)
+		fmt.Fprintf(os.Stderr, "  --include-ignored <pattern> : %s\n", flag.Lookup("include-ignored").Usage)
+		fmt.Fprintf(os.Stderr, "  --tracked-only : %s\n", flag.Lookup("tracked-only").Usage)
+		fmt.Fprintf(os.Stderr, "  --untracked-only : %s\n", flag.Lookup("untracked-only").Usage)
+		fmt.Fprintf(os.Stderr, "  --auto-readme : %s\n", flag.Lookup("auto-readme").Usage)
+		fmt.Fprintf(os.Stderr, "  --auto-docs   : %s\n", flag.Lookup("auto-docs").Usage)
+		fmt.Fprintf(os.Stderr, "  --files-from <file> : %s\n", flag.Lookup("files-from").Usage)
+		fmt.Fprintf(os.Stderr, "  --files-from0 <file> : %s\n", flag.Lookup("files-from0").Usage)
+		fmt.Fprintf(os.Stderr, "  --at <ref>    : %s\n", flag.Lookup("at").Usage)
+		fmt.Fprintf(os.Stderr, "  --repo <path> : %s\n", flag.Lookup("repo").Usage)
 		fmt.Fprintf(os.Stderr, "  -q \"text\"    : %s\n", flag.Lookup("q").Usage)
+		fmt.Fprintf(os.Stderr, "  --q-slot name=text : %s\n", flag.Lookup("q-slot").Usage)
+		fmt.Fprintf(os.Stderr, "  --question-prefix \"text\" : %s\n", flag.Lookup("question-prefix").Usage)
+		fmt.Fprintf(os.Stderr, "  --question-suffix \"text\" : %s\n", flag.Lookup("question-suffix").Usage)
+		fmt.Fprintf(os.Stderr, "  --number-questions : %s\n", flag.Lookup("number-questions").Usage)
+		fmt.Fprintf(os.Stderr, "  --questions-order <order> : %s\n", flag.Lookup("questions-order").Usage)
+		fmt.Fprintf(os.Stderr, "  --role-message \"text\" : %s\n", flag.Lookup("role-message").Usage)
+		fmt.Fprintf(os.Stderr, "  --extra-context \"text\" : %s\n", flag.Lookup("extra-context").Usage)
+		fmt.Fprintf(os.Stderr, "  --extra-context-file <file> : %s\n", flag.Lookup("extra-context-file").Usage)
+		fmt.Fprintf(os.Stderr, "  --last-words \"text\" : %s\n", flag.Lookup("last-words").Usage)
+		fmt.Fprintf(os.Stderr, "  --begin-marker \"text\" : %s\n", flag.Lookup("begin-marker").Usage)
+		fmt.Fprintf(os.Stderr, "  --end-marker \"text\" : %s\n", flag.Lookup("end-marker").Usage)
+		fmt.Fprintf(os.Stderr, "  --profile <name> : %s\n", flag.Lookup("profile").Usage)
+		fmt.Fprintf(os.Stderr, "  --prompt-template <file> : %s\n", flag.Lookup("prompt-template").Usage)
 		fmt.Fprintf(os.Stderr, "  -c            : %s\n", flag.Lookup("c").Usage)
+		fmt.Fprintf(os.Stderr, "  --clipboard-context : %s\n", flag.Lookup("clipboard-context").Usage)
 		fmt.Fprintf(os.Stderr, "  -qf <file>    : %s\n", flag.Lookup("qf").Usage)
+		fmt.Fprintf(os.Stderr, "  --questions-file <file> : %s\n", flag.Lookup("questions-file").Usage)
+		fmt.Fprintf(os.Stderr, "  --questions-delimiter <text> : %s\n", flag.Lookup("questions-delimiter").Usage)
 		fmt.Fprintf(os.Stderr, "  --raw         : %s\n", flag.Lookup("raw").Usage)
+		fmt.Fprintf(os.Stderr, "  --one-file-per-question : %s\n", flag.Lookup("one-file-per-question").Usage)
+		fmt.Fprintf(os.Stderr, "  --allow-duplicates : %s\n", flag.Lookup("allow-duplicates").Usage)
+		fmt.Fprintf(os.Stderr, "  --review-plan <file> : %s\n", flag.Lookup("review-plan").Usage)
+		fmt.Fprintf(os.Stderr, "  --exclude-larger-than <size> : %s\n", flag.Lookup("exclude-larger-than").Usage)
+		fmt.Fprintf(os.Stderr, "  --max-files N : %s\n", flag.Lookup("max-files").Usage)
+		fmt.Fprintf(os.Stderr, "  --max-total-bytes <size> : %s\n", flag.Lookup("max-total-bytes").Usage)
+		fmt.Fprintf(os.Stderr, "  --max-tokens N : %s\n", flag.Lookup("max-tokens").Usage)
+		fmt.Fprintf(os.Stderr, "  --auto-trim   : %s\n", flag.Lookup("auto-trim").Usage)
+		fmt.Fprintf(os.Stderr, "  --head N      : %s\n", flag.Lookup("head").Usage)
+		fmt.Fprintf(os.Stderr, "  --tail N      : %s\n", flag.Lookup("tail").Usage)
+		fmt.Fprintf(os.Stderr, "  --annotate-language : %s\n", flag.Lookup("annotate-language").Usage)
+		fmt.Fprintf(os.Stderr, "  --group-by-pattern : %s\n", flag.Lookup("group-by-pattern").Usage)
+		fmt.Fprintf(os.Stderr, "  --relative-to <dir> : %s\n", flag.Lookup("relative-to").Usage)
+		fmt.Fprintf(os.Stderr, "  --strip-prefix <prefix> : %s\n", flag.Lookup("strip-prefix").Usage)
+		fmt.Fprintf(os.Stderr, "  --hash        : %s\n", flag.Lookup("hash").Usage)
+		fmt.Fprintf(os.Stderr, "  --file-metadata : %s\n", flag.Lookup("file-metadata").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-end-markers : %s\n", flag.Lookup("no-end-markers").Usage)
+		fmt.Fprintf(os.Stderr, "  --compact     : %s\n", flag.Lookup("compact").Usage)
+		fmt.Fprintf(os.Stderr, "  --file-manifest : %s\n", flag.Lookup("file-manifest").Usage)
+		fmt.Fprintf(os.Stderr, "  --deps        : %s\n", flag.Lookup("deps").Usage)
+		fmt.Fprintf(os.Stderr, "  --dedupe-blank-between-files : %s\n", flag.Lookup("dedupe-blank-between-files").Usage)
+		fmt.Fprintf(os.Stderr, "  --tabs N      : %s\n", flag.Lookup("tabs").Usage)
+		fmt.Fprintf(os.Stderr, "  --filter-cmd <command> : %s\n", flag.Lookup("filter-cmd").Usage)
+		fmt.Fprintf(os.Stderr, "  --post-process <command> : %s\n", flag.Lookup("post-process").Usage)
+		fmt.Fprintf(os.Stderr, "  --outline     : %s\n", flag.Lookup("outline").Usage)
+		fmt.Fprintf(os.Stderr, "  --text-ext <exts> : %s\n", flag.Lookup("text-ext").Usage)
+		fmt.Fprintf(os.Stderr, "  --include-generated : %s\n", flag.Lookup("include-generated").Usage)
+		fmt.Fprintf(os.Stderr, "  --follow-symlinks : %s\n", flag.Lookup("follow-symlinks").Usage)
+		fmt.Fprintf(os.Stderr, "  --include-empty : %s\n", flag.Lookup("include-empty").Usage)
+		fmt.Fprintf(os.Stderr, "  --binary-base64 : %s\n", flag.Lookup("binary-base64").Usage)
+		fmt.Fprintf(os.Stderr, "  --strict-text : %s\n", flag.Lookup("strict-text").Usage)
+		fmt.Fprintf(os.Stderr, "  --only-conflicts : %s\n", flag.Lookup("only-conflicts").Usage)
+		fmt.Fprintf(os.Stderr, "  --warn-conflicts : %s\n", flag.Lookup("warn-conflicts").Usage)
+		fmt.Fprintf(os.Stderr, "  --skip-minified : %s\n", flag.Lookup("skip-minified").Usage)
+		fmt.Fprintf(os.Stderr, "  --minified-threshold N : %s\n", flag.Lookup("minified-threshold").Usage)
+		fmt.Fprintf(os.Stderr, "  --flag-long-lines N : %s\n", flag.Lookup("flag-long-lines").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-tree     : %s\n", flag.Lookup("no-tree").Usage)
+		fmt.Fprintf(os.Stderr, "  --tree-only   : %s\n", flag.Lookup("tree-only").Usage)
+		fmt.Fprintf(os.Stderr, "  --tree-root <dir> : %s\n", flag.Lookup("tree-root").Usage)
+		fmt.Fprintf(os.Stderr, "  --tree-depth N : %s\n", flag.Lookup("tree-depth").Usage)
+		fmt.Fprintf(os.Stderr, "  --tree-matched : %s\n", flag.Lookup("tree-matched").Usage)
+		fmt.Fprintf(os.Stderr, "  --tree-cmd    : %s\n", flag.Lookup("tree-cmd").Usage)
 		fmt.Fprintf(os.Stderr, "  -a \"alias\"    : %s\n", flag.Lookup("a").Usage)
+		fmt.Fprintf(os.Stderr, "  --save-alias <name> : %s\n", flag.Lookup("save-alias").Usage)
 		fmt.Fprintf(os.Stderr, "  --list-aliases : %s\n", flag.Lookup("list-aliases").Usage)
+		fmt.Fprintf(os.Stderr, "  --validate-aliases : %s\n", flag.Lookup("validate-aliases").Usage)
+		fmt.Fprintf(os.Stderr, "  --show-config : %s\n", flag.Lookup("show-config").Usage)
+		fmt.Fprintf(os.Stderr, "  --config-walk-to-root : %s\n", flag.Lookup("config-walk-to-root").Usage)
 		fmt.Fprintf(os.Stderr, "  --stdout      : %s\n", flag.Lookup("stdout").Usage)
+		fmt.Fprintf(os.Stderr, "  --tee         : %s\n", flag.Lookup("tee").Usage)
+		fmt.Fprintf(os.Stderr, "  --no-clipboard : %s\n", flag.Lookup("no-clipboard").Usage)
 		fmt.Fprintf(os.Stderr, "  --quiet       : %s\n", flag.Lookup("quiet").Usage)
+		fmt.Fprintf(os.Stderr, "  -v, --verbose : %s\n", flag.Lookup("v").Usage)
+		fmt.Fprintf(os.Stderr, "  -vv           : %s\n", flag.Lookup("vv").Usage)
+		fmt.Fprintf(os.Stderr, "  --explain     : %s\n", flag.Lookup("explain").Usage)
+		fmt.Fprintf(os.Stderr, "  --interactive : %s\n", flag.Lookup("interactive").Usage)
 		fmt.Fprintf(os.Stderr, "  --dry-run     : %s\n", flag.Lookup("dry-run").Usage)
+		fmt.Fprintf(os.Stderr, "  --print-command : %s\n", flag.Lookup("print-command").Usage)
 		fmt.Fprintf(os.Stderr, "  --output <file> : %s\n", flag.Lookup("output").Usage)
+		fmt.Fprintf(os.Stderr, "  --append      : %s\n", flag.Lookup("append").Usage)
+		fmt.Fprintf(os.Stderr, "  --compare-to <file> : %s\n", flag.Lookup("compare-to").Usage)
+		fmt.Fprintf(os.Stderr, "  --scan-secrets : %s\n", flag.Lookup("scan-secrets").Usage)
+		fmt.Fprintf(os.Stderr, "  --fail-on-secrets : %s\n", flag.Lookup("fail-on-secrets").Usage)
+		fmt.Fprintf(os.Stderr, "  --redact      : %s\n", flag.Lookup("redact").Usage)
+		fmt.Fprintf(os.Stderr, "  --stats       : %s\n", flag.Lookup("stats").Usage)
+		fmt.Fprintf(os.Stderr, "  --json-errors : %s\n", flag.Lookup("json-errors").Usage)
 		fmt.Fprintf(os.Stderr, "  -h            : %s\n", flag.Lookup("h").Usage)
 
 		fmt.Fprintln(os.Stderr, "\nNote: Multiple -q and -qf options accumulate (all are included in order).")
@@ -101,6 +416,7 @@ func init() {
 		fmt.Fprintln(os.Stderr, "  make-project-prompt -i '*.go' -q \"First question\" -q \"Second question\"  # Both questions included")
 		fmt.Fprintln(os.Stderr, "  make-project-prompt --raw -q \"Header\" -i '*.py' -q \"Footer\"  # Raw mode with positioning")
 		fmt.Fprintln(os.Stderr, "  make-project-prompt -i '*.py' -qf question.txt  # Read question from file")
+		fmt.Fprintln(os.Stderr, "  make-project-prompt --review-plan review.txt  # Ask a question per glob")
 		fmt.Fprintln(os.Stderr, "  make-project-prompt -a js_dev -q \"Review this code\"  # Use the js_dev alias")
 		fmt.Fprintln(os.Stderr, "  make-project-prompt --list-aliases  # List all available aliases")
 	}
@@ -114,12 +430,64 @@ func init() {
 
 // processFilesAndGeneratePrompt handles file processing and prompt generation
 func processFilesAndGeneratePrompt() (string, int, error) {
+	// Replace slot questions declared by aliases with their --q-slot overrides
+	resolveQuestionSlots()
+
+	// Collect the extra context in argument order
+	var extraContexts []prompt.ContentItem
+	for _, item := range argOrder {
+		switch item.Type {
+		case "extra_context":
+			extraContexts = append(extraContexts, prompt.ContentItem{
+				Type:    "extra_context",
+				Content: item.Content,
+				Order:   item.Order,
+			})
+		case "extra_context_file":
+			fileContent, err := os.ReadFile(item.Content)
+			if err != nil {
+				return "", 0, withCategory(errInput, fmt.Errorf("error reading from file %s: %w", item.Content, err))
+			}
+			if len(fileContent) == 0 {
+				return "", 0, withCategory(errInput, fmt.Errorf("file %s is empty", item.Content))
+			}
+			extraContexts = append(extraContexts, prompt.ContentItem{
+				Type:    "extra_context",
+				Content: strings.TrimRight(string(fileContent), "\n"),
+				Order:   item.Order,
+			})
+		case "extra_context_clipboard":
+			clipContent, err := clipboardBackend.ReadAll()
+			if err != nil {
+				return "", 0, withCategory(errInput, fmt.Errorf("error reading from clipboard: %w", err))
+			}
+			if clipContent == "" {
+				return "", 0, withCategory(errInput, fmt.Errorf("clipboard is empty (--clipboard-context)"))
+			}
+			extraContexts = append(extraContexts, prompt.ContentItem{
+				Type:    "extra_context",
+				Content: clipContent,
+				Order:   item.Order,
+			})
+		}
+	}
+
 	// Build ContentItems for raw mode based on argOrder
 	var contentItems []prompt.ContentItem
 	var allFileInfos []files.FileInfo
+	listStart := time.Now()
 
-	if rawMode && len(argOrder) > 0 {
+	if reviewPlanFile != "" {
+		// Review plan: each glob's files are immediately followed by its question
+		items, fileInfos, err := buildReviewPlanItems(reviewPlanFile)
+		if err != nil {
+			return "", 0, err
+		}
+		contentItems = items
+		allFileInfos = fileInfos
+	} else if rawMode && len(argOrder) > 0 {
 		// In raw mode with explicit order, list files per pattern group
+		hasFileGroup := false
 		for _, item := range argOrder {
 			switch item.Type {
 			case "question":
@@ -131,48 +499,59 @@ func processFilesAndGeneratePrompt() (string, int, error) {
 			case "question_file":
 				fileContent, err := os.ReadFile(item.Content)
 				if err != nil {
-					return "", 0, fmt.Errorf("error reading from file %s: %w", item.Content, err)
+					return "", 0, withCategory(errInput, fmt.Errorf("error reading from file %s: %w", item.Content, err))
 				}
 				if len(fileContent) == 0 {
-					return "", 0, fmt.Errorf("file %s is empty", item.Content)
+					return "", 0, withCategory(errInput, fmt.Errorf("file %s is empty", item.Content))
 				}
 				contentItems = append(contentItems, prompt.ContentItem{
 					Type:    "question",
 					Content: string(fileContent),
 					Order:   item.Order,
 				})
+			case "questions_file":
+				fileQuestions, err := config.ReadQuestionsFile(item.Content, questionsDelimiter)
+				if err != nil {
+					return "", 0, err
+				}
+				// The questions share the file's position and keep their file order
+				for _, q := range fileQuestions {
+					contentItems = append(contentItems, prompt.ContentItem{
+						Type:    "question",
+						Content: q,
+						Order:   item.Order,
+					})
+				}
 			case "clipboard":
-				clipContent, err := clipboard.ReadAll()
+				clipContent, err := clipboardBackend.ReadAll()
 				if err != nil {
-					return "", 0, fmt.Errorf("error reading from clipboard: %w", err)
+					return "", 0, withCategory(errInput, fmt.Errorf("error reading from clipboard: %w", err))
 				}
 				if clipContent == "" {
-					return "", 0, fmt.Errorf("clipboard is empty")
+					return "", 0, withCategory(errInput, fmt.Errorf("clipboard is empty"))
 				}
 				contentItems = append(contentItems, prompt.ContentItem{
 					Type:    "question",
 					Content: clipContent,
 					Order:   item.Order,
 				})
-			case "include", "force_include":
+			case "include", "force_include", "include_ignored":
 				// List files for this specific pattern
-				fileConfig := files.Config{
-					IncludePatterns: []string{item.Content},
-					ExcludePatterns: excludePatterns,
-				}
-				if item.Type == "force_include" {
+				fileConfig := newFileConfig([]string{item.Content}, nil)
+				fileConfig.IncludeIgnoredPatterns = nil
+				switch item.Type {
+				case "force_include":
 					fileConfig.ForceIncludePatterns = []string{item.Content}
 					fileConfig.IncludePatterns = []string{}
+				case "include_ignored":
+					fileConfig.IncludeIgnoredPatterns = []string{item.Content}
 				}
 
-				fileInfos, err := files.ListGitFiles(fileConfig)
+				fileInfos, err := listGitFiles(fileConfig)
 				if err != nil {
-					return "", 0, fmt.Errorf("failed to list Git files for pattern %s: %w", item.Content, err)
+					return "", 0, withCategory(errGit, fmt.Errorf("failed to list Git files for pattern %s: %w", item.Content, err))
 				}
 
-				// Add these files to allFileInfos for later counting
-				allFileInfos = append(allFileInfos, fileInfos...)
-
 				// Create a file_group item with the matched files
 				contentItems = append(contentItems, prompt.ContentItem{
 					Type:         "file_group",
@@ -180,96 +559,122 @@ func processFilesAndGeneratePrompt() (string, int, error) {
 					Files:        fileInfos,
 					Order:        item.Order,
 				})
+				hasFileGroup = true
+			case "files_from", "files_from0":
+				lists := []string{item.Content}
+				var fileInfos []files.FileInfo
+				var err error
+				if item.Type == "files_from0" {
+					fileInfos, err = listFilesFrom(nil, lists)
+				} else {
+					fileInfos, err = listFilesFrom(lists, nil)
+				}
+				if err != nil {
+					return "", 0, err
+				}
+				contentItems = append(contentItems, prompt.ContentItem{
+					Type:         "file_group",
+					FilePatterns: []string{item.Content},
+					Files:        fileInfos,
+					Order:        item.Order,
+				})
+				hasFileGroup = true
 			}
 		}
-	} else {
-		// Non-raw mode or raw mode without explicit patterns: list all files at once
-		fileConfig := files.Config{
-			IncludePatterns:      includePatterns,
-			ExcludePatterns:      excludePatterns,
-			ForceIncludePatterns: forceIncludePatterns,
-		}
 
-		fileInfos, err := files.ListGitFiles(fileConfig)
-		if err != nil {
-			return "", 0, fmt.Errorf("failed to list Git files: %w", err)
-		}
-		allFileInfos = fileInfos
-
-		if rawMode && len(argOrder) == 0 {
-			// Raw mode with no explicit -i flags: add files first, then questions
+		// Without any -i, -f, --files-from or --files-from0, all files form a single group placed before
+		// the questions, selected exactly as in default mode
+		if !hasFileGroup {
+			fileInfos, err := listCandidateFiles()
+			if err != nil {
+				return "", 0, err
+			}
 			if len(fileInfos) > 0 {
 				contentItems = append(contentItems, prompt.ContentItem{
 					Type:         "file_group",
 					FilePatterns: []string{"*"},
 					Files:        fileInfos,
-					Order:        0,
+					Order:        -1,
 				})
 			}
+		}
 
-			// Then add questions
-			order := 1
-			for _, q := range questions {
-				contentItems = append(contentItems, prompt.ContentItem{
-					Type:    "question",
-					Content: q,
-					Order:   order,
-				})
-				order++
-			}
+		// Extra context is placed at its argument position
+		contentItems = append(contentItems, extraContexts...)
 
-			for _, qf := range questionFiles {
-				fileContent, err := os.ReadFile(qf)
-				if err != nil {
-					return "", 0, fmt.Errorf("error reading from file %s: %w", qf, err)
-				}
-				if len(fileContent) == 0 {
-					return "", 0, fmt.Errorf("file %s is empty", qf)
-				}
-				contentItems = append(contentItems, prompt.ContentItem{
-					Type:    "question",
-					Content: string(fileContent),
-					Order:   order,
-				})
-				order++
+		// Overlapping patterns can match the same file several times; keep the first occurrence
+		if !allowDuplicates {
+			var suppressed []string
+			contentItems, suppressed = prompt.DedupeFileGroups(contentItems)
+			if len(suppressed) > 0 && verbosity >= prompt.VerbosityNormal {
+				fmt.Fprintf(os.Stderr, "Note: Suppressed %d duplicate file(s) matched by several patterns (use --allow-duplicates to keep them): %s\n",
+					len(suppressed), strings.Join(suppressed, ", "))
 			}
+		}
 
-			if useClipboard {
-				clipContent, err := clipboard.ReadAll()
-				if err != nil {
-					return "", 0, fmt.Errorf("error reading from clipboard: %w", err)
-				}
-				if clipContent == "" {
-					return "", 0, fmt.Errorf("clipboard is empty")
-				}
-				contentItems = append(contentItems, prompt.ContentItem{
-					Type:    "question",
-					Content: clipContent,
-					Order:   order,
-				})
+		// Add the grouped files to allFileInfos for later counting
+		for _, item := range contentItems {
+			if item.Type == "file_group" {
+				allFileInfos = append(allFileInfos, item.Files...)
 			}
 		}
+	} else {
+		// Non-raw mode or raw mode without explicit patterns: list all files at once
+		fileInfos, err := listCandidateFiles()
+		if err != nil {
+			return "", 0, err
+		}
+		allFileInfos = fileInfos
+
+		if rawMode && len(fileInfos) > 0 {
+			// Raw mode without any -i, -f, or question flag: all files form a single group
+			contentItems = append(contentItems, prompt.ContentItem{
+				Type:         "file_group",
+				FilePatterns: []string{"*"},
+				Files:        fileInfos,
+				Order:        0,
+			})
+		}
+	}
+
+	printTiming(fmt.Sprintf("listed %d files", len(allFileInfos)), listStart)
+
+	if maxFiles > 0 && len(allFileInfos) > maxFiles {
+		return "", 0, withCategory(errTooManyFiles, fmt.Errorf("matched %d files; exceeds --max-files %d; narrow your patterns or raise the limit", len(allFileInfos), maxFiles))
 	}
 
-	if len(allFileInfos) == 0 {
+	// The README added by --auto-readme does not count as a match of the patterns
+	if !hasSelectedFiles(allFileInfos) {
 		if len(includePatterns) > 0 || len(forceIncludePatterns) > 0 {
 			allPatterns := append([]string{}, includePatterns...)
 			allPatterns = append(allPatterns, forceIncludePatterns...)
-			return "", 0, fmt.Errorf("no files matched the specified patterns: %v\nTry using different patterns or check if the files exist", allPatterns)
+			return "", 0, withCategory(errNoFiles, fmt.Errorf("no files matched the specified patterns: %v\nTry using different patterns or check if the files exist", allPatterns))
 		}
-		// In raw mode with questions but no files, allow it (questions-only mode)
+		// In raw mode with questions but no files left after exclusion, allow it (questions-only mode)
 		// In other modes, require files
 		isQuestionsOnlyRawMode := rawMode && len(argOrder) > 0
-		if !isQuestionsOnlyRawMode {
-			return "", 0, fmt.Errorf("no files found in the Git repository. Make sure you have committed or staged some files")
+		if !isQuestionsOnlyRawMode && reviewPlanFile == "" {
+			return "", 0, withCategory(errNoFiles, fmt.Errorf("no files found in the Git repository. Make sure you have committed or staged some files"))
+		}
+	}
+
+	if interactive && len(allFileInfos) > 0 {
+		selected, err := pickFiles(allFileInfos, os.Stdin, os.Stderr)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(selected) == 0 {
+			return "", 0, withCategory(errNoFiles, fmt.Errorf("no files selected"))
 		}
+		allFileInfos = selected
+		contentItems = keepSelectedFiles(contentItems, selected)
 	}
 
 	printInfo("Found %d files matching the specified patterns.\n", len(allFileInfos))
 
 	// Collect all questions for default mode (non-raw)
 	var allQuestions []prompt.ContentItem
-	if !rawMode {
+	if !rawMode && reviewPlanFile == "" {
 		order := 0
 
 		// Add questions from -q flags
@@ -286,10 +691,10 @@ func processFilesAndGeneratePrompt() (string, int, error) {
 		for _, qf := range questionFiles {
 			fileContent, err := os.ReadFile(qf)
 			if err != nil {
-				return "", 0, fmt.Errorf("error reading from file %s: %w", qf, err)
+				return "", 0, withCategory(errInput, fmt.Errorf("error reading from file %s: %w", qf, err))
 			}
 			if len(fileContent) == 0 {
-				return "", 0, fmt.Errorf("file %s is empty", qf)
+				return "", 0, withCategory(errInput, fmt.Errorf("file %s is empty", qf))
 			}
 			allQuestions = append(allQuestions, prompt.ContentItem{
 				Type:    "question",
@@ -299,14 +704,30 @@ func processFilesAndGeneratePrompt() (string, int, error) {
 			order++
 		}
 
+		// Add questions from --questions-file flags
+		for _, path := range questionsFiles {
+			fileQuestions, err := config.ReadQuestionsFile(path, questionsDelimiter)
+			if err != nil {
+				return "", 0, err
+			}
+			for _, q := range fileQuestions {
+				allQuestions = append(allQuestions, prompt.ContentItem{
+					Type:    "question",
+					Content: q,
+					Order:   order,
+				})
+				order++
+			}
+		}
+
 		// Add question from clipboard if -c is used
 		if useClipboard {
-			clipContent, err := clipboard.ReadAll()
+			clipContent, err := clipboardBackend.ReadAll()
 			if err != nil {
-				return "", 0, fmt.Errorf("error reading from clipboard: %w", err)
+				return "", 0, withCategory(errInput, fmt.Errorf("error reading from clipboard: %w", err))
 			}
 			if clipContent == "" {
-				return "", 0, fmt.Errorf("clipboard is empty")
+				return "", 0, withCategory(errInput, fmt.Errorf("clipboard is empty"))
 			}
 			allQuestions = append(allQuestions, prompt.ContentItem{
 				Type:    "question",
@@ -317,40 +738,515 @@ func processFilesAndGeneratePrompt() (string, int, error) {
 	}
 
 	// Generate prompt
-	generator := prompt.NewGenerator(allFileInfos, "", quietMode)
-	generator.RawMode = rawMode
+	generator := prompt.NewGenerator(allFileInfos, "", verbosity == prompt.VerbosityQuiet)
+	generator.Verbosity = verbosity
+	generator.RawMode = rawMode || reviewPlanFile != ""
+	generator.LabelQuestions = oneFilePerQuestion
 	generator.Questions = allQuestions
 	generator.ContentItems = contentItems
+	generator.HeadLines = headLines
+	if treeRoot != "" {
+		generator.TreeRoot = files.NormalizeTreeRoot(treeRoot)
+	}
+	generator.TreeDepth = treeDepth
+	generator.QuestionPrefix = questionPrefix
+	generator.QuestionSuffix = questionSuffix
+	generator.NumberQuestions = numberQuestions
+	generator.QuestionsOrder = prompt.QuestionsOrder(questionsOrder)
+	generator.IncludeTree = (includeTree && !noTree) || treeOnly
+	generator.TreeOnly = treeOnly
+	generator.RoleMessage = roleMessage
+	generator.ExtraContext = joinContent(extraContexts)
+	generator.LastWords = lastWords
+	generator.BeginMarker = beginMarker
+	generator.EndMarker = endMarker
+	generator.TreeMatched = treeMatched
+	generator.UseTreeCommand = useTreeCommand
+	generator.GitRef = gitRef
+	generator.Repos = repos
+	generator.AnnotateLanguage = annotateLanguage
+	generator.GroupByPattern = groupByPattern
+	generator.RelativeTo = relativeTo
+	generator.StripPrefix = stripPrefix
+	generator.HashContent = hashContent
+	generator.FileMetadata = fileMetadata
+	generator.NoEndMarkers = noEndMarkers
+	generator.Compact = compact
+	generator.FileManifest = fileManifest
+	generator.IncludeDeps = includeDeps
+	generator.DedupeBlankLines = dedupeBlankLines
+	generator.FilterCommand = filterCommand
+	generator.Outline = outline
+	generator.LongLineThreshold = longLineThreshold
+	generator.IndentTabWidth = indentTabWidth
+	generator.IncludeEmpty = includeEmpty
+	generator.BinaryBase64 = binaryBase64
+	generator.MaxTotalBytes = maxTotalBytes
+	generator.MaxTokens = maxTokens
+	generator.AutoTrim = autoTrim
+	generator.Ranker = prompt.RelevanceRanker(includePatterns)
+	generator.ScanSecrets = scanSecrets || failOnSecrets
+	generator.Redact = redact
+	generator.SecretPatterns = secretPatterns
+	generator.ShowProgress = verbosity == prompt.VerbosityNormal && !useStdout
+	generator.TailLines = tailLines
+	if promptTemplateFile != "" {
+		templateContent, err := os.ReadFile(promptTemplateFile)
+		if err != nil {
+			return "", 0, withCategory(errInput, fmt.Errorf("error reading prompt template: %w", err))
+		}
+		generator.Template = string(templateContent)
+	}
 
-	// Add default question if no questions provided (non-raw mode only)
-	if !rawMode && len(allQuestions) == 0 {
+	// Add default question if no questions provided (non-raw mode only):
+	// the @default-question of .mpp.txt, or a placeholder
+	if !generator.RawMode && len(allQuestions) == 0 {
+		question := defaultQuestion
+		if question == "" {
+			question = "[YOUR QUESTION HERE]"
+		}
 		generator.Questions = []prompt.ContentItem{
 			{
 				Type:    "question",
-				Content: "[YOUR QUESTION HERE]",
+				Content: question,
 				Order:   0,
 			},
 		}
 	}
 
+	generateStart := time.Now()
 	promptText, fileCount, err := generator.Generate()
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to generate prompt: %w", err)
 	}
+	printTiming("generated the prompt", generateStart)
+	fileReadTime = generator.ReadTime()
+	secretFindings = generator.SecretFindings()
 
-	if fileCount == 0 {
-		return "", 0, fmt.Errorf("no files were included in the prompt. All matched files were either binary, too large, or couldn't be read")
+	// With --tree-only, no file is included by design
+	if fileCount == 0 && !treeOnly {
+		return "", 0, withCategory(errNoFiles, fmt.Errorf("no files were included in the prompt. All matched files were either binary, too large, or couldn't be read"))
 	}
 
 	return promptText, fileCount, nil
 }
 
-// expandAliasesInArgs expands any alias arguments in the command line
-func expandAliasesInArgs(args []string) ([]string, error) {
-	// Load aliases from config files
-	cfg, err := config.LoadAliases()
+// joinContent joins the content of items with blank lines
+func joinContent(items []prompt.ContentItem) string {
+	contents := make([]string, 0, len(items))
+	for _, item := range items {
+		contents = append(contents, item.Content)
+	}
+	return strings.Join(contents, "\n\n")
+}
+
+// resolveQuestionSlots resolves questions declared as "@slot:name default text": the default text
+// is replaced by the matching --q-slot override, if any. Overrides for slots that no question
+// declares are asked as regular questions.
+func resolveQuestionSlots() {
+	declared := make(map[string]bool)
+	resolve := func(question string) string {
+		name, text, ok := config.ParseQuestionSlot(question)
+		if !ok {
+			return question
+		}
+		declared[name] = true
+		if override, exists := slotOverrides[name]; exists {
+			return override
+		}
+		return text
+	}
+
+	var resolvedQuestions multiStringFlag
+	for _, q := range questions {
+		if resolved := resolve(q); resolved != "" {
+			resolvedQuestions = append(resolvedQuestions, resolved)
+		}
+	}
+
+	var resolvedOrder []argOrderItem
+	for _, item := range argOrder {
+		if item.Type == "question" {
+			item.Content = resolve(item.Content)
+			if item.Content == "" {
+				continue
+			}
+		}
+		resolvedOrder = append(resolvedOrder, item)
+	}
+
+	// Orders may have gaps where slots resolved to nothing: append after the last item
+	nextOrder := 0
+	if len(resolvedOrder) > 0 {
+		nextOrder = resolvedOrder[len(resolvedOrder)-1].Order + 1
+	}
+	for _, name := range slotOverrideNames {
+		if declared[name] {
+			continue
+		}
+		if verbosity >= prompt.VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: No question declares slot '%s'; its override is added as a regular question.\n", name)
+		}
+		resolvedQuestions = append(resolvedQuestions, slotOverrides[name])
+		resolvedOrder = append(resolvedOrder, argOrderItem{
+			Type:    "question",
+			Content: slotOverrides[name],
+			Order:   nextOrder,
+		})
+		nextOrder++
+	}
+
+	questions = resolvedQuestions
+	argOrder = resolvedOrder
+}
+
+// applyProfile applies the settings of a prompt profile that were not given on the command line
+func applyProfile(profile config.Profile) {
+	if roleMessage == "" {
+		roleMessage = profile.RoleMessage
+	}
+	if lastWords == "" {
+		lastWords = profile.Footer
+	}
+	if profile.IncludeTree != nil {
+		includeTree = *profile.IncludeTree
+	}
+}
+
+// newFileConfig builds the file listing configuration for the given include and force include
+// patterns, applying the exclusion patterns and filtering options shared by every listing
+func newFileConfig(include, forceInclude []string) files.Config {
+	return files.Config{
+		IncludePatterns:        include,
+		ExcludePatterns:        excludePatterns,
+		ExcludeDirs:            excludeDirs,
+		ForceIncludePatterns:   forceInclude,
+		IncludeIgnoredPatterns: includeIgnored,
+		TrackedOnly:            trackedOnly,
+		UntrackedOnly:          untrackedOnly,
+		AutoReadme:             autoReadme,
+		AutoDocs:               autoDocs,
+		StrictText:             strictText,
+		FollowSymlinks:         followSymlinks,
+		IncludeGenerated:       includeGenerated,
+		TextExtensions:         textExtensions,
+		Languages:              languages,
+		OnlyConflicts:          onlyConflicts,
+		WarnConflicts:          warnConflicts,
+		SkipMinified:           skipMinified,
+		MinifiedLineLength:     minifiedThreshold,
+		ExcludeLargerThan:      excludeLargerThan,
+		Explain:                explainMode || verbosity >= prompt.VerbosityVerbose,
+		Timings:                &selectionTimings,
+		ExcludeTests:           noTests,
+		ExcludeHidden:          noHidden,
+		LimitDepth:             maxDepth >= 0,
+		IgnoreCase:             ignoreCase,
+		MaxDepth:               maxDepth,
+		TestPatterns:           testPatterns,
+	}
+}
+
+// listGitFiles lists the Git files selected by a file configuration, in the working tree
+// or at the --at revision
+func listGitFiles(fileConfig files.Config) ([]files.FileInfo, error) {
+	if len(repos) > 0 {
+		return listRepoFiles(fileConfig)
+	}
+	if gitRef != "" {
+		return files.ListGitFilesAt(gitRef, fileConfig)
+	}
+	return files.ListGitFiles(fileConfig)
+}
+
+// listCandidateFiles lists the files selected by the include patterns, or the files
+// given with --files-from and --files-from0 when present
+func listCandidateFiles() ([]files.FileInfo, error) {
+	if hasFileLists() {
+		return listFilesFrom(filesFrom, filesFrom0)
+	}
+	fileInfos, err := listGitFiles(newFileConfig(includePatterns, forceIncludePatterns))
+	if err != nil {
+		return nil, withCategory(errGit, fmt.Errorf("failed to list Git files: %w", err))
+	}
+	return fileInfos, nil
+}
+
+// hasSelectedFiles reports whether some files were selected by the patterns, rather than auto-included
+func hasSelectedFiles(fileInfos []files.FileInfo) bool {
+	for _, info := range fileInfos {
+		if !info.AutoIncluded {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFileLists reports whether the files are given as exact lists rather than patterns
+func hasFileLists() bool {
+	return len(filesFrom) > 0 || len(filesFrom0) > 0
+}
+
+// listFilesFrom reads the exact paths listed in the given files ("-" for stdin), one per
+// line in lists and NUL-separated in nulLists, and returns their FileInfo, with the
+// exclusion patterns applied
+func listFilesFrom(lists, nulLists []string) ([]files.FileInfo, error) {
+	paths, err := readPathLists("--files-from", lists, files.ReadPathList)
+	if err != nil {
+		return nil, err
+	}
+	nulPaths, err := readPathLists("--files-from0", nulLists, files.ReadNulPathList)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfos, err := files.ListExplicitFiles(append(paths, nulPaths...), newFileConfig(nil, nil))
+	if err != nil {
+		return nil, withCategory(errInput, fmt.Errorf("--files-from: %w", err))
+	}
+	return fileInfos, nil
+}
+
+// readPathLists reads the paths listed in the given files ("-" for stdin) with read
+func readPathLists(flagName string, sources []string, read func(io.Reader) ([]string, error)) ([]string, error) {
+	var paths []string
+	for _, source := range sources {
+		var list []string
+		var err error
+		if source == "-" {
+			list, err = read(os.Stdin)
+		} else {
+			var file *os.File
+			file, err = os.Open(source)
+			if err != nil {
+				return nil, withCategory(errInput, fmt.Errorf("%s: %w", flagName, err))
+			}
+			list, err = read(file)
+			file.Close()
+		}
+		if err != nil {
+			return nil, withCategory(errInput, fmt.Errorf("%s %s: %w", flagName, source, err))
+		}
+		paths = append(paths, list...)
+	}
+	return paths, nil
+}
+
+// isFilePatternItem reports whether an argument order item selects files
+func isFilePatternItem(item argOrderItem) bool {
+	switch item.Type {
+	case "include", "force_include", "include_ignored", "files_from", "files_from0":
+		return true
+	}
+	return false
+}
+
+// isQuestionItem reports whether an argument order item gives questions
+func isQuestionItem(item argOrderItem) bool {
+	switch item.Type {
+	case "question", "question_file", "questions_file", "clipboard":
+		return true
+	}
+	return false
+}
+
+// hasFilePatterns reports whether the arguments select files by pattern or list
+func hasFilePatterns(items []argOrderItem) bool {
+	return slices.ContainsFunc(items, isFilePatternItem)
+}
+
+// unpairedPattern returns the first file pattern not followed by a question in the arguments,
+// which --one-file-per-question cannot pair
+func unpairedPattern(items []argOrderItem) (string, bool) {
+	pending := -1 // Index of the first pattern since the last question
+	for i, item := range items {
+		if isFilePatternItem(item) && pending < 0 {
+			pending = i
+		} else if isQuestionItem(item) {
+			pending = -1
+		}
+	}
+	if pending < 0 {
+		return "", false
+	}
+	return items[pending].Content, true
+}
+
+// buildReviewPlanItems parses a review plan file and builds interleaved file groups and questions.
+// Questions given with -q are appended after the last review step.
+func buildReviewPlanItems(path string) ([]prompt.ContentItem, []files.FileInfo, error) {
+	steps, err := config.ParseReviewPlan(path)
+	if err != nil {
+		return nil, nil, withCategory(errInput, fmt.Errorf("failed to parse review plan: %w", err))
+	}
+
+	var contentItems []prompt.ContentItem
+	var allFileInfos []files.FileInfo
+	order := 0
+
+	for _, step := range steps {
+		fileConfig := newFileConfig([]string{step.Glob}, nil)
+
+		fileInfos, err := listGitFiles(fileConfig)
+		if err != nil {
+			return nil, nil, withCategory(errGit, fmt.Errorf("failed to list Git files for pattern %s: %w", step.Glob, err))
+		}
+		if len(fileInfos) == 0 && verbosity >= prompt.VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: Review plan glob '%s' (line %d) matched no files.\n", step.Glob, step.Line)
+		}
+		allFileInfos = append(allFileInfos, fileInfos...)
+
+		contentItems = append(contentItems, prompt.ContentItem{
+			Type:         "file_group",
+			FilePatterns: []string{step.Glob},
+			Files:        fileInfos,
+			Order:        order,
+		})
+		order++
+
+		contentItems = append(contentItems, prompt.ContentItem{
+			Type:    "question",
+			Content: step.Question,
+			Order:   order,
+		})
+		order++
+	}
+
+	for _, q := range questions {
+		contentItems = append(contentItems, prompt.ContentItem{
+			Type:    "question",
+			Content: q,
+			Order:   order,
+		})
+		order++
+	}
+
+	return contentItems, allFileInfos, nil
+}
+
+// saveRequestedAlias records the given arguments as the --save-alias alias, if requested.
+// It is called once the invocation has succeeded, so a failing one is never saved.
+func saveRequestedAlias(args []string) {
+	if saveAliasName == "" {
+		return
+	}
+	path, err := saveAlias(saveAliasName, args)
+	if err != nil {
+		fatalf(errConfig, "Error saving alias: %v", err)
+	}
+	printInfo("Alias '%s' saved to %s\n", saveAliasName, path)
+}
+
+// saveAlias records the given arguments, minus --save-alias itself, as an alias in the nearest config file
+func saveAlias(name string, args []string) (string, error) {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-save-alias" || args[i] == "--save-alias" {
+			i++ // Skip the alias name
+			continue
+		}
+		kept = append(kept, args[i])
+	}
+
+	options, err := config.SerializeOptions(kept)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := config.FindNearestConfigFileUpTo(configStopDir())
 	if err != nil {
-		return nil, fmt.Errorf("failed to load aliases: %w", err)
+		return "", err
+	}
+	if err := config.SaveAlias(path, name, options); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// selectionCommand returns a make-project-prompt command line reproducing the current file
+// selection from the parsed flags, aliases and include/exclude files being already expanded
+func selectionCommand(excludes []string) string {
+	args := []string{"make-project-prompt"}
+	addPatterns := func(flagName string, patterns []string) {
+		for _, pattern := range patterns {
+			args = append(args, flagName, pattern)
+		}
+	}
+	addSwitch := func(flagName string, set bool) {
+		if set {
+			args = append(args, flagName)
+		}
+	}
+
+	addPatterns("--files-from", filesFrom)
+	addPatterns("--files-from0", filesFrom0)
+	addPatterns("--repo", repoDirs)
+	if gitRef != "" {
+		args = append(args, "--at", gitRef)
+	}
+	addPatterns("-i", includePatterns)
+	addPatterns("-f", forceIncludePatterns)
+	addPatterns("--include-ignored", includeIgnored)
+	addSwitch("--tracked-only", trackedOnly)
+	addSwitch("--untracked-only", untrackedOnly)
+	addSwitch("--auto-readme", autoReadme)
+	addSwitch("--auto-docs", autoDocs)
+	addPatterns("-e", excludes)
+	addPatterns("--exclude-dir", excludeDirs)
+	addSwitch("--ignore-case", ignoreCase)
+	addSwitch("--no-tests", noTests)
+	addSwitch("--no-hidden", noHidden)
+	addSwitch("--respect-local-exclude", respectLocalExclude)
+	if maxDepth >= 0 {
+		args = append(args, "--depth", strconv.Itoa(maxDepth))
+	}
+	addSwitch("--no-default-exclude", noDefaultExclude)
+	if len(textExtensions) > 0 {
+		args = append(args, "--text-ext", strings.Join(textExtensions, ","))
+	}
+	if len(languages) > 0 {
+		args = append(args, "--lang", strings.Join(languages, ","))
+	}
+	addSwitch("--include-generated", includeGenerated)
+	addSwitch("--follow-symlinks", followSymlinks)
+	addSwitch("--strict-text", strictText)
+	addSwitch("--only-conflicts", onlyConflicts)
+	addSwitch("--skip-minified", skipMinified)
+	if skipMinified && minifiedThreshold != files.DefaultMinifiedLineLength {
+		args = append(args, "--minified-threshold", strconv.Itoa(minifiedThreshold))
+	}
+	if excludeLargerThan > 0 {
+		args = append(args, "--exclude-larger-than", strconv.FormatInt(excludeLargerThan, 10))
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote single-quotes an argument for a POSIX shell unless it only holds safe characters
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,@%+") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// expandAliasesInArgs expands any alias arguments in the command line
+func expandAliasesInArgs(cfg *config.Config, args []string) ([]string, error) {
+	// Output flags given on the command line replace those provided by aliases
+	commandLineOutput := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-a" || args[i] == "--a" {
+			i++
+			continue
+		}
+		if _, ok := outputFlagName(args[i]); ok {
+			commandLineOutput = true
+		}
 	}
 
 	var expanded []string
@@ -374,6 +1270,9 @@ func expandAliasesInArgs(args []string) ([]string, error) {
 
 			// Expand the alias options
 			aliasArgs := config.ExpandAlias(alias.Options)
+			if commandLineOutput {
+				aliasArgs = withoutOutputFlags(aliasArgs)
+			}
 			expanded = append(expanded, aliasArgs...)
 		} else {
 			// Regular argument
@@ -384,17 +1283,95 @@ func expandAliasesInArgs(args []string) ([]string, error) {
 	return expanded, nil
 }
 
-// customParseArgs parses command-line arguments, collecting all arguments until a new flag is encountered
-func customParseArgs() {
-	args := os.Args[1:] // Skip the program name
-
-	// Reset argOrder for this parse
-	argOrder = []argOrderItem{}
-	orderCounter := 0
+// outputFlags lists the flags choosing where the prompt goes, and whether they take a value
+var outputFlags = map[string]bool{
+	"output": true,
+	"stdout": false,
+	"tee":    false,
+}
 
-	// Define a helper function to check if an argument is a flag
-	isFlag := func(arg string) bool {
-		return strings.HasPrefix(arg, "-")
+// outputFlagName returns the name of the output flag arg is, if any
+func outputFlagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", false
+	}
+	name := strings.TrimLeft(arg, "-")
+	_, ok := outputFlags[name]
+	return name, ok
+}
+
+// withoutOutputFlags removes the output flags, and their values, from args
+func withoutOutputFlags(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		if name, ok := outputFlagName(args[i]); ok {
+			if outputFlags[name] {
+				i++ // Skip the value
+			}
+			continue
+		}
+		result = append(result, args[i])
+	}
+	return result
+}
+
+// parseExtensionList splits a comma-separated list of file extensions, adding the
+// leading dot where it is missing
+func parseExtensionList(value string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+// parseLanguageList parses a comma-separated list of language names, lowercased
+func parseLanguageList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// checkQuestionsOrder checks the value of --questions-order
+func checkQuestionsOrder(value string) error {
+	if !slices.Contains(prompt.QuestionsOrders, prompt.QuestionsOrder(value)) {
+		return fmt.Errorf("invalid value %q for --questions-order: expected as-given, asc or desc", value)
+	}
+	return nil
+}
+
+// parseCountFlag parses the value of a flag expecting a non-negative integer
+func parseCountFlag(flagName, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid value %q for %s: expected a non-negative integer", value, flagName)
+	}
+	return n, nil
+}
+
+// customParseArgs parses command-line arguments, collecting all arguments until a new flag is encountered
+func customParseArgs() error {
+	args := os.Args[1:] // Skip the program name
+
+	// Reset argOrder for this parse
+	argOrder = []argOrderItem{}
+	orderCounter := 0
+
+	// Define a helper function to check if an argument is a flag.
+	// A lone "-" is a value meaning stdin (e.g. --files-from -).
+	isFlag := func(arg string) bool {
+		return strings.HasPrefix(arg, "-") && arg != "-"
 	}
 
 	var currentFlag string
@@ -419,27 +1396,190 @@ func customParseArgs() {
 				})
 				orderCounter++
 				continue
+			} else if currentFlag == "-clipboard-context" || currentFlag == "--clipboard-context" {
+				argOrder = append(argOrder, argOrderItem{
+					Type:  "extra_context_clipboard",
+					Order: orderCounter,
+				})
+				orderCounter++
+				continue
 			} else if currentFlag == "-stdout" || currentFlag == "--stdout" {
 				useStdout = true
 				continue
+			} else if currentFlag == "-tee" || currentFlag == "--tee" {
+				teeOutput = true
+				continue
+			} else if currentFlag == "-append" || currentFlag == "--append" {
+				appendOutput = true
+				continue
+			} else if currentFlag == "-no-clipboard" || currentFlag == "--no-clipboard" {
+				noClipboardFlag = true
+				continue
+			} else if currentFlag == "-explain" || currentFlag == "--explain" {
+				explainMode = true
+				continue
+			} else if currentFlag == "-interactive" || currentFlag == "--interactive" {
+				interactive = true
+				continue
 			} else if currentFlag == "-quiet" || currentFlag == "--quiet" {
-				quietMode = true
+				verbosity = prompt.VerbosityQuiet
+				continue
+			} else if currentFlag == "-v" || currentFlag == "--verbose" {
+				verbosity = prompt.VerbosityVerbose
+				continue
+			} else if currentFlag == "-vv" {
+				verbosity = prompt.VerbosityDebug
+				continue
+			} else if currentFlag == "-stats" || currentFlag == "--stats" {
+				showStats = true
+				continue
+			} else if currentFlag == "-scan-secrets" || currentFlag == "--scan-secrets" {
+				scanSecrets = true
+				continue
+			} else if currentFlag == "-fail-on-secrets" || currentFlag == "--fail-on-secrets" {
+				failOnSecrets = true
+				continue
+			} else if currentFlag == "-tracked-only" || currentFlag == "--tracked-only" {
+				trackedOnly = true
+				continue
+			} else if currentFlag == "-untracked-only" || currentFlag == "--untracked-only" {
+				untrackedOnly = true
+				continue
+			} else if currentFlag == "-auto-readme" || currentFlag == "--auto-readme" {
+				autoReadme = true
+				continue
+			} else if currentFlag == "-auto-docs" || currentFlag == "--auto-docs" {
+				autoDocs = true
+				continue
+			} else if currentFlag == "-redact" || currentFlag == "--redact" {
+				redact = true
+				continue
+			} else if currentFlag == "-json-errors" || currentFlag == "--json-errors" {
+				jsonErrors = true
 				continue
 			} else if currentFlag == "-dry-run" || currentFlag == "--dry-run" {
 				dryRun = true
 				continue
+			} else if currentFlag == "-print-command" || currentFlag == "--print-command" {
+				printCommand = true
+				continue
 			} else if currentFlag == "-list-aliases" || currentFlag == "--list-aliases" {
 				listAliases = true
 				continue
+			} else if currentFlag == "-validate-aliases" || currentFlag == "--validate-aliases" {
+				validateAliases = true
+				continue
+			} else if currentFlag == "-show-config" || currentFlag == "--show-config" {
+				showConfig = true
+				continue
+			} else if currentFlag == "-config-walk-to-root" || currentFlag == "--config-walk-to-root" {
+				configWalkToRoot = true
+				continue
 			} else if currentFlag == "-raw" || currentFlag == "--raw" {
 				rawMode = true
 				continue
+			} else if currentFlag == "-one-file-per-question" || currentFlag == "--one-file-per-question" {
+				oneFilePerQuestion = true
+				continue
+			} else if currentFlag == "-allow-duplicates" || currentFlag == "--allow-duplicates" {
+				allowDuplicates = true
+				continue
+			} else if currentFlag == "-no-tree" || currentFlag == "--no-tree" {
+				noTree = true
+				continue
+			} else if currentFlag == "-tree-only" || currentFlag == "--tree-only" {
+				treeOnly = true
+				continue
+			} else if currentFlag == "-tree-cmd" || currentFlag == "--tree-cmd" {
+				useTreeCommand = true
+				continue
+			} else if currentFlag == "-annotate-language" || currentFlag == "--annotate-language" {
+				annotateLanguage = true
+				continue
+			} else if currentFlag == "-group-by-pattern" || currentFlag == "--group-by-pattern" {
+				groupByPattern = true
+				continue
+			} else if currentFlag == "-number-questions" || currentFlag == "--number-questions" {
+				numberQuestions = true
+				continue
+			} else if currentFlag == "-hash" || currentFlag == "--hash" {
+				hashContent = true
+				continue
+			} else if currentFlag == "-file-metadata" || currentFlag == "--file-metadata" {
+				fileMetadata = true
+				continue
+			} else if currentFlag == "-no-end-markers" || currentFlag == "--no-end-markers" {
+				noEndMarkers = true
+				continue
+			} else if currentFlag == "-auto-trim" || currentFlag == "--auto-trim" {
+				autoTrim = true
+				continue
+			} else if currentFlag == "-outline" || currentFlag == "--outline" {
+				outline = true
+				continue
+			} else if currentFlag == "-compact" || currentFlag == "--compact" {
+				compact = true
+				continue
+			} else if currentFlag == "-deps" || currentFlag == "--deps" {
+				includeDeps = true
+				continue
+			} else if currentFlag == "-file-manifest" || currentFlag == "--file-manifest" {
+				fileManifest = true
+				continue
+			} else if currentFlag == "-dedupe-blank-between-files" || currentFlag == "--dedupe-blank-between-files" {
+				dedupeBlankLines = true
+				continue
+			} else if currentFlag == "-ignore-case" || currentFlag == "--ignore-case" {
+				ignoreCase = true
+				continue
+			} else if currentFlag == "-no-tests" || currentFlag == "--no-tests" {
+				noTests = true
+				continue
+			} else if currentFlag == "-no-hidden" || currentFlag == "--no-hidden" {
+				noHidden = true
+				continue
+			} else if currentFlag == "-respect-local-exclude" || currentFlag == "--respect-local-exclude" {
+				respectLocalExclude = true
+				continue
+			} else if currentFlag == "-no-default-exclude" || currentFlag == "--no-default-exclude" {
+				noDefaultExclude = true
+				continue
+			} else if currentFlag == "-tree-matched" || currentFlag == "--tree-matched" {
+				treeMatched = true
+				continue
+			} else if currentFlag == "-only-conflicts" || currentFlag == "--only-conflicts" {
+				onlyConflicts = true
+				continue
+			} else if currentFlag == "-warn-conflicts" || currentFlag == "--warn-conflicts" {
+				warnConflicts = true
+				continue
+			} else if currentFlag == "-include-generated" || currentFlag == "--include-generated" {
+				includeGenerated = true
+				continue
+			} else if currentFlag == "-follow-symlinks" || currentFlag == "--follow-symlinks" {
+				followSymlinks = true
+				continue
+			} else if currentFlag == "-include-empty" || currentFlag == "--include-empty" {
+				includeEmpty = true
+				continue
+			} else if currentFlag == "-binary-base64" || currentFlag == "--binary-base64" {
+				binaryBase64 = true
+				continue
+			} else if currentFlag == "-strict-text" || currentFlag == "--strict-text" {
+				strictText = true
+				continue
+			} else if currentFlag == "-skip-minified" || currentFlag == "--skip-minified" {
+				skipMinified = true
+				continue
 			}
 
 			// For flags that take a value, get the next argument
 			if i+1 < len(args) && !isFlag(args[i+1]) {
 				value := args[i+1]
 				i++ // Skip the value in the next iteration
+				if isCallerFileFlag(currentFlag) {
+					value = callerPath(value)
+				}
 
 				// Process the flag and its value
 				switch currentFlag {
@@ -451,6 +1591,40 @@ func customParseArgs() {
 						Order:   orderCounter,
 					})
 					orderCounter++
+				case "-q-slot", "--q-slot":
+					if err := (slotOverrideFlag{}).Set(value); err != nil {
+						return err
+					}
+				case "-question-prefix", "--question-prefix":
+					questionPrefix = value
+				case "-question-suffix", "--question-suffix":
+					questionSuffix = value
+				case "-role-message", "--role-message":
+					roleMessage = value
+				case "-extra-context", "--extra-context":
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "extra_context",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-extra-context-file", "--extra-context-file":
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "extra_context_file",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-last-words", "--last-words":
+					lastWords = value
+				case "-begin-marker", "--begin-marker":
+					beginMarker = value
+				case "-end-marker", "--end-marker":
+					endMarker = value
+				case "-profile", "--profile":
+					profileName = value
+				case "-prompt-template", "--prompt-template":
+					promptTemplateFile = value
 				case "-qf", "--qf":
 					questionFiles = append(questionFiles, value)
 					argOrder = append(argOrder, argOrderItem{
@@ -461,6 +1635,8 @@ func customParseArgs() {
 					orderCounter++
 				case "-output", "--output":
 					outputFile = value
+				case "-compare-to", "--compare-to":
+					compareTo = value
 				case "-i", "--i":
 					includePatterns = append(includePatterns, value)
 					argOrder = append(argOrder, argOrderItem{
@@ -471,6 +1647,67 @@ func customParseArgs() {
 					orderCounter++
 				case "-e", "--e":
 					excludePatterns = append(excludePatterns, value)
+				case "-exclude-dir", "--exclude-dir":
+					excludeDirs = append(excludeDirs, value)
+				case "-include-ignored", "--include-ignored":
+					includeIgnored = append(includeIgnored, value)
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "include_ignored",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-files-from", "--files-from":
+					filesFrom = append(filesFrom, value)
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "files_from",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-files-from0", "--files-from0":
+					filesFrom0 = append(filesFrom0, value)
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "files_from0",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-questions-file", "--questions-file":
+					questionsFiles = append(questionsFiles, value)
+					argOrder = append(argOrder, argOrderItem{
+						Type:    "questions_file",
+						Content: value,
+						Order:   orderCounter,
+					})
+					orderCounter++
+				case "-questions-delimiter", "--questions-delimiter":
+					questionsDelimiter = value
+				case "-questions-order", "--questions-order":
+					if err := checkQuestionsOrder(value); err != nil {
+						return err
+					}
+					questionsOrder = value
+				case "-include-from", "--include-from":
+					patterns, err := config.ReadPatternFile(value)
+					if err != nil {
+						return withCategory(errInput, fmt.Errorf("--include-from %s: %w", value, err))
+					}
+					for _, pattern := range patterns {
+						includePatterns = append(includePatterns, pattern)
+						argOrder = append(argOrder, argOrderItem{
+							Type:    "include",
+							Content: pattern,
+							Order:   orderCounter,
+						})
+						orderCounter++
+					}
+				case "-exclude-from", "--exclude-from":
+					patterns, err := config.ReadPatternFile(value)
+					if err != nil {
+						return withCategory(errInput, fmt.Errorf("--exclude-from %s: %w", value, err))
+					}
+					excludePatterns = append(excludePatterns, patterns...)
 				case "-f", "--f":
 					forceIncludePatterns = append(forceIncludePatterns, value)
 					argOrder = append(argOrder, argOrderItem{
@@ -481,6 +1718,97 @@ func customParseArgs() {
 					orderCounter++
 				case "-a", "--a":
 					aliasName = value
+				case "-save-alias", "--save-alias":
+					saveAliasName = value
+				case "-review-plan", "--review-plan":
+					reviewPlanFile = value
+				case "-head", "--head":
+					n, err := parseCountFlag("--head", value)
+					if err != nil {
+						return err
+					}
+					headLines = n
+				case "-tree-root", "--tree-root":
+					treeRoot = value
+				case "-relative-to", "--relative-to":
+					relativeTo = value
+				case "-at", "--at":
+					gitRef = value
+				case "-repo", "--repo":
+					// Applied before parsing, see repoArg
+					if !slices.Contains(repoDirs, value) {
+						return withCategory(errUsage, fmt.Errorf("--repo must be given on the command line, not in an alias"))
+					}
+				case "-strip-prefix", "--strip-prefix":
+					stripPrefix = value
+				case "-filter-cmd", "--filter-cmd":
+					filterCommand = value
+				case "-post-process", "--post-process":
+					postProcessCommand = value
+				case "-text-ext", "--text-ext":
+					textExtensions = append(textExtensions, parseExtensionList(value)...)
+				case "-lang", "--lang":
+					languages = append(languages, parseLanguageList(value)...)
+				case "-depth", "--depth":
+					n, err := parseCountFlag("--depth", value)
+					if err != nil {
+						return err
+					}
+					maxDepth = n
+				case "-tree-depth", "--tree-depth":
+					n, err := parseCountFlag("--tree-depth", value)
+					if err != nil {
+						return err
+					}
+					treeDepth = n
+				case "-minified-threshold", "--minified-threshold":
+					n, err := parseCountFlag("--minified-threshold", value)
+					if err != nil {
+						return err
+					}
+					minifiedThreshold = n
+				case "-tabs", "--tabs":
+					n, err := parseCountFlag("--tabs", value)
+					if err != nil {
+						return err
+					}
+					indentTabWidth = n
+				case "-flag-long-lines", "--flag-long-lines":
+					n, err := parseCountFlag("--flag-long-lines", value)
+					if err != nil {
+						return err
+					}
+					longLineThreshold = n
+				case "-exclude-larger-than", "--exclude-larger-than":
+					n, err := parseSizeFlag("--exclude-larger-than", value)
+					if err != nil {
+						return err
+					}
+					excludeLargerThan = n
+				case "-max-total-bytes", "--max-total-bytes":
+					n, err := parseSizeFlag("--max-total-bytes", value)
+					if err != nil {
+						return err
+					}
+					maxTotalBytes = n
+				case "-max-tokens", "--max-tokens":
+					n, err := parseCountFlag("--max-tokens", value)
+					if err != nil {
+						return err
+					}
+					maxTokens = n
+				case "-max-files", "--max-files":
+					n, err := parseCountFlag("--max-files", value)
+					if err != nil {
+						return err
+					}
+					maxFiles = n
+				case "-tail", "--tail":
+					n, err := parseCountFlag("--tail", value)
+					if err != nil {
+						return err
+					}
+					tailLines = n
 				}
 			}
 		} else if currentFlag == "-i" || currentFlag == "--i" {
@@ -515,21 +1843,96 @@ func customParseArgs() {
 			orderCounter++
 		} else if currentFlag == "-qf" || currentFlag == "--qf" {
 			// This is a non-flag argument following -qf, add it to questionFiles
-			questionFiles = append(questionFiles, arg)
+			questionFiles = append(questionFiles, callerPath(arg))
 			argOrder = append(argOrder, argOrderItem{
 				Type:    "question_file",
-				Content: arg,
+				Content: callerPath(arg),
 				Order:   orderCounter,
 			})
 			orderCounter++
 		}
 	}
+
+	return nil
 }
 
-// checkDependencies checks if all required dependencies are available
-func checkDependencies() error {
-	// Check if inside a Git repository
+// sizeUnits maps size suffixes (lowercase, without a trailing "b") to their multipliers
+var sizeUnits = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+}
+
+// parseSizeFlag parses a size such as 512, 100k, 2M, or 1.5MB (binary units) into bytes
+func parseSizeFlag(flagName, value string) (int64, error) {
+	invalid := fmt.Errorf("invalid value %q for %s: expected a size such as 512, 100k, or 2M", value, flagName)
+
+	number := strings.TrimRight(value, "kKmMgGbB")
+	unit := strings.TrimSuffix(strings.ToLower(value[len(number):]), "b")
+	multiplier, ok := sizeUnits[unit]
+	if !ok || number == "" || strings.Trim(number, "0123456789.") != "" {
+		return 0, invalid
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// configStopDir returns the directory the search for .mpp.txt files stops at: the root of the
+// Git repository, or "" (the file system root) with --config-walk-to-root or outside a repository
+func configStopDir() string {
+	if configWalkToRoot {
+		return ""
+	}
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// applyLocalExclude adds the rules of the repository's .git/info/exclude file, if any,
+// to the exclusion patterns and excluded directories. The rules are anchored at the
+// repository root, while the listed paths are relative to the working directory.
+func applyLocalExclude() error {
+	output, err := exec.Command("git", "rev-parse", "--git-path", "info/exclude").Output()
+	if err != nil {
+		return fmt.Errorf("failed to locate .git/info/exclude: %w", err)
+	}
+	path := strings.TrimSpace(string(output))
+	output, err = exec.Command("git", "rev-parse", "--show-prefix").Output()
+	if err != nil {
+		return fmt.Errorf("failed to locate the working directory in the repository: %w", err)
+	}
+	prefix := strings.TrimSpace(string(output))
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	patterns, err := config.ReadGitignorePatterns(path)
+	if err != nil {
+		return err
+	}
+	patterns = patterns.RelativeTo(prefix)
+	excludePatterns = append(excludePatterns, patterns.Excludes...)
+	excludeDirs = append(excludeDirs, patterns.DirNames...)
+	if verbosity >= prompt.VerbosityNormal {
+		for _, negation := range patterns.Negations {
+			fmt.Fprintf(os.Stderr, "Warning: Negated rule '%s' of %s is not supported and is ignored.\n", negation, path)
+		}
+	}
+	return nil
+}
+
+// checkWorkTree checks that dir ("" = the working directory) is inside a Git working tree
+func checkWorkTree(dir string) error {
 	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd.Dir = dir
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -539,6 +1942,22 @@ func checkDependencies() error {
 			return fmt.Errorf("you are not inside a Git repository or git is not installed.\nThis tool uses 'git ls-files' to list files and respect .gitignore")
 		}
 	}
+	return nil
+}
+
+// checkDependencies checks if all required dependencies are available
+func checkDependencies() error {
+	// Check if inside a Git repository, or with several --repo that each of them is one
+	if len(repos) == 0 {
+		if err := checkWorkTree(""); err != nil {
+			return err
+		}
+	}
+	for _, repo := range repos {
+		if err := checkWorkTree(repo.Dir); err != nil {
+			return fmt.Errorf("repository '%s': %w", repo.Label, err)
+		}
+	}
 
 	// Check for required commands
 	requiredCommands := []string{"git"}
@@ -554,7 +1973,10 @@ func checkDependencies() error {
 	}
 
 	// Check for optional commands
-	optionalCommands := []string{"file", "tree"}
+	optionalCommands := []string{"file"}
+	if useTreeCommand && includeTree && !noTree {
+		optionalCommands = append(optionalCommands, "tree")
+	}
 	for _, cmdName := range optionalCommands {
 		if _, err := exec.LookPath(cmdName); err != nil {
 			printInfo("Warning: Optional command '%s' not found. Some features may not work correctly.\n", cmdName)
@@ -566,31 +1988,159 @@ func checkDependencies() error {
 	return nil
 }
 
+// appendPrompt appends a prompt to a file, separated from the previous prompts by a
+// timestamped line
+func appendPrompt(path, promptText string, now time.Time) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > 0 {
+		separator := fmt.Sprintf("\n===== PROMPT APPENDED AT %s =====\n\n", now.Format(time.RFC3339))
+		if _, err := file.WriteString(separator); err != nil {
+			return err
+		}
+	}
+	_, err = file.WriteString(promptText)
+	return err
+}
+
 // printInfo prints informational messages unless quiet mode is enabled or stdout is used
 func printInfo(format string, a ...interface{}) {
-	if !quietMode && !useStdout {
+	if verbosity >= prompt.VerbosityNormal && !useStdout {
 		fmt.Printf(format, a...)
 	}
 }
 
+// printStats reports on stderr the time spent per phase and the size of the prompt
+func printStats(total time.Duration, promptText string, fileCount int) {
+	round := func(d time.Duration) time.Duration { return d.Round(time.Millisecond) }
+	fmt.Fprintf(os.Stderr, "Stats:\n")
+	fmt.Fprintf(os.Stderr, "  Git listing:  %s\n", round(selectionTimings.Listing))
+	fmt.Fprintf(os.Stderr, "  Filtering:    %s\n", round(selectionTimings.Filtering))
+	fmt.Fprintf(os.Stderr, "  File reading: %s\n", round(fileReadTime))
+	fmt.Fprintf(os.Stderr, "  Total:        %s\n", round(total))
+	fmt.Fprintf(os.Stderr, "  Files: %d, bytes: %d\n", fileCount, len(promptText))
+}
+
+// writeAliasValidation writes the result of --validate-aliases, the problems of each invalid
+// alias or OK, and returns the number of invalid aliases
+func writeAliasValidation(w io.Writer, cfg *config.Config) int {
+	aliases := sortedAliases(cfg)
+	if len(aliases) == 0 {
+		fmt.Fprintln(w, "No aliases found in .mpp.txt config files.")
+		return 0
+	}
+
+	problems := aliasProblems(cfg)
+	for _, alias := range aliases {
+		if len(problems[alias.Name]) == 0 {
+			fmt.Fprintf(w, "  %s: OK\n", alias.Name)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s\n", alias.Name, alias.Options)
+		fmt.Fprintf(w, "    (defined in %s)\n", alias.Source)
+		for _, problem := range problems[alias.Name] {
+			fmt.Fprintf(w, "    Error: %s\n", problem)
+		}
+	}
+	return len(problems)
+}
+
+// writeConfig writes the resolved configuration for --show-config: the config files in search
+// order, the aliases and directives in effect with their source, and the suppressed definitions
+func writeConfig(w io.Writer, cfg *config.Config) {
+	if len(cfg.Files) == 0 {
+		fmt.Fprintln(w, "No .mpp.txt config files found.")
+		return
+	}
+
+	fmt.Fprintln(w, "Config files (search order, nearest first):")
+	for i, path := range cfg.Files {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, path)
+	}
+
+	aliases := sortedAliases(cfg)
+	fmt.Fprintf(w, "\nAliases (%d):\n", len(aliases))
+	for _, alias := range aliases {
+		fmt.Fprintf(w, "  %s: %s\n", alias.Name, alias.Options)
+		fmt.Fprintf(w, "    (defined in %s)\n", alias.Source)
+	}
+
+	names := make([]string, 0, len(cfg.Directives))
+	for name := range cfg.Directives {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(w, "\nDirectives (%d):\n", len(names))
+	for _, name := range names {
+		directive := cfg.Directives[name]
+		fmt.Fprintf(w, "  @%s: %s\n", directive.Name, directive.Value)
+		fmt.Fprintf(w, "    (defined in %s)\n", directive.Source)
+	}
+
+	fmt.Fprintf(w, "\nSuppressed duplicates (%d, the first definition wins):\n", len(cfg.Suppressed))
+	for _, suppressed := range cfg.Suppressed {
+		name := suppressed.Name
+		if suppressed.Kind == "directive" {
+			name = "@" + name
+		}
+		fmt.Fprintf(w, "  %s %s in %s\n", suppressed.Kind, name, suppressed.Source)
+		fmt.Fprintf(w, "    (ignored, the definition in %s is in effect)\n", suppressed.KeptSource)
+	}
+}
+
+// printTiming reports on stderr the time spent on a step since start, with -vv
+func printTiming(step string, start time.Time) {
+	if verbosity >= prompt.VerbosityDebug {
+		fmt.Fprintf(os.Stderr, "Timing: %s in %s.\n", step, time.Since(start).Round(time.Millisecond))
+	}
+}
+
 func main() {
 	// Store original args before parsing to determine flag order later
 	originalArgs := make([]string, len(os.Args))
 	copy(originalArgs, os.Args)
 
-	// Check if --list-aliases is requested before expanding aliases
+	// Check if --list-aliases, --validate-aliases or --show-config is requested and how far config files are searched
+	// before expanding aliases, and whether errors before the arguments are parsed are reported as JSON
 	for _, arg := range os.Args[1:] {
 		if arg == "-list-aliases" || arg == "--list-aliases" {
 			listAliases = true
-			break
 		}
+		if arg == "-validate-aliases" || arg == "--validate-aliases" {
+			validateAliases = true
+		}
+		if arg == "-show-config" || arg == "--show-config" {
+			showConfig = true
+		}
+		if arg == "-config-walk-to-root" || arg == "--config-walk-to-root" {
+			configWalkToRoot = true
+		}
+		if arg == "-json-errors" || arg == "--json-errors" {
+			jsonErrors = true
+		}
+	}
+
+	// Run in the --repo directory, whose .mpp.txt files apply
+	repoDirs = repoArgs(os.Args[1:])
+	if len(repoDirs) == 1 {
+		enterRepo(repoDirs[0])
+	} else if len(repoDirs) > 1 {
+		repos = loadRepos(repoDirs)
 	}
 
 	// Handle --list-aliases early
 	if listAliases {
-		cfg, err := config.LoadAliases()
+		cfg, err := config.LoadAliasesUpTo(configStopDir())
 		if err != nil {
-			log.Fatalf("Error loading aliases: %v", err)
+			fatalf(errConfig, "Error loading aliases: %v", err)
 		}
 
 		aliases := cfg.ListAliases()
@@ -599,25 +2149,59 @@ func main() {
 			os.Exit(0)
 		}
 
+		problems := aliasProblems(cfg)
 		fmt.Println("Available aliases:")
 		for _, alias := range aliases {
 			fmt.Printf("  %s: %s\n", alias.Name, alias.Options)
 			fmt.Printf("    (defined in %s)\n", alias.Source)
+			for _, problem := range problems[alias.Name] {
+				fmt.Printf("    Warning: %s\n", problem)
+			}
+		}
+		os.Exit(0)
+	}
+
+	// Handle --validate-aliases early, reporting every alias before failing
+	if validateAliases {
+		cfg, err := config.LoadAliasesUpTo(configStopDir())
+		if err != nil {
+			fatalf(errConfig, "Error loading aliases: %v", err)
 		}
+		if invalid := writeAliasValidation(os.Stdout, cfg); invalid > 0 {
+			fatalf(errConfig, "Error: %d alias(es) have invalid options.", invalid)
+		}
+		os.Exit(0)
+	}
+
+	// Handle --show-config early, before a broken alias can stop the run
+	if showConfig {
+		cfg, err := config.LoadAliasesUpTo(configStopDir())
+		if err != nil {
+			fatalf(errConfig, "Error loading aliases: %v", err)
+		}
+		writeConfig(os.Stdout, cfg)
 		os.Exit(0)
 	}
 
+	// Load aliases and directives from config files
+	cfg, err := config.LoadAliasesUpTo(configStopDir())
+	if err != nil {
+		fatalf(errConfig, "Error loading aliases: %v", err)
+	}
+
 	// Expand any aliases in the arguments
-	expandedArgs, err := expandAliasesInArgs(os.Args[1:])
+	expandedArgs, err := expandAliasesInArgs(cfg, os.Args[1:])
 	if err != nil {
-		log.Fatalf("Error expanding aliases: %v", err)
+		fatalf(errConfig, "Error expanding aliases: %v", err)
 	}
 
 	// Replace os.Args with expanded arguments for parsing
 	os.Args = append([]string{os.Args[0]}, expandedArgs...)
 
 	// Custom argument parsing to handle multiple arguments per flag
-	customParseArgs()
+	if err := customParseArgs(); err != nil {
+		fatalErr(errUsage, err)
+	}
 
 	// Show help if requested
 	if showHelp {
@@ -625,16 +2209,161 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Apply the test patterns configured in .mpp.txt
+	if directive, ok := cfg.GetDirective(config.TestPatternsDirective); ok {
+		testPatterns = strings.Fields(directive.Value)
+	}
+
+	// Apply the selected prompt profile; explicit flags take precedence
+	if profileName != "" {
+		profile, ok, err := cfg.GetProfile(profileName)
+		if err != nil {
+			fatalErr(errConfig, err)
+		}
+		if !ok {
+			fatalf(errConfig, "Error: Profile '%s' not found. Defined profiles: %s", profileName, strings.Join(cfg.ListProfiles(), ", "))
+		}
+		applyProfile(profile)
+	}
+
+	// Apply the secret patterns configured in .mpp.txt
+	redactPatterns, err := cfg.GetRedactPatterns()
+	if err != nil {
+		fatalErr(errConfig, err)
+	}
+	for _, pattern := range redactPatterns {
+		secretPatterns = append(secretPatterns, prompt.SecretPattern{Kind: pattern.Name, Pattern: pattern.Pattern})
+	}
+
+	// Apply the default question configured in .mpp.txt; questions given on the command line override it
+	if directive, ok := cfg.GetDirective(config.DefaultQuestionDirective); ok {
+		defaultQuestion = directive.Value
+	}
+
+	// The reproduced command leaves the default excludes to .mpp.txt
+	userExcludes := append([]string{}, excludePatterns...)
+
+	// Apply the default excludes configured in .mpp.txt
+	if directive, ok := cfg.GetDirective(config.DefaultExcludeDirective); ok && !noDefaultExclude {
+		excludePatterns = append(excludePatterns, strings.Fields(directive.Value)...)
+	}
+
+	// Apply the local ignore rules of .git/info/exclude to the tracked files too
+	if respectLocalExclude && len(repos) == 0 {
+		if err := applyLocalExclude(); err != nil {
+			fatalErr(errGit, err)
+		}
+	}
+
 	// Validate output options
 	if useStdout && outputFile != "" {
-		log.Fatalf("Error: Cannot use both --stdout and --output options at the same time.")
+		fatalf(errUsage, "Error: Cannot use both --stdout and --output options at the same time.")
+	}
+	if oneFilePerQuestion {
+		if reviewPlanFile != "" || promptTemplateFile != "" || treeOnly || autoReadme || includeDeps || prompt.QuestionsOrder(questionsOrder) != prompt.QuestionsAsGiven {
+			fatalf(errUsage, "Error: --one-file-per-question places each question after its files; it cannot be combined with --review-plan, --prompt-template, --tree-only, --auto-readme, --deps or --questions-order.")
+		}
+		if pattern, ok := unpairedPattern(argOrder); ok {
+			fatalf(errUsage, "Error: '%s' is not followed by a question; --one-file-per-question pairs each -i with the -q following it.", pattern)
+		}
+		if !hasFilePatterns(argOrder) {
+			fatalf(errUsage, "Error: --one-file-per-question requires -i patterns to pair with the questions.")
+		}
+		rawMode = true
+	}
+	if promptTemplateFile != "" && (rawMode || reviewPlanFile != "") {
+		fatalf(errUsage, "Error: --prompt-template cannot be combined with --raw or --review-plan.")
+	}
+	if hasFileLists() && (len(includePatterns) > 0 || len(forceIncludePatterns) > 0 || len(includeIgnored) > 0 || reviewPlanFile != "") {
+		fatalf(errUsage, "Error: --files-from gives the exact list of files; it cannot be combined with -i, -f, --include-ignored or --review-plan.")
+	}
+	if treeOnly && (noTree || rawMode || reviewPlanFile != "" || promptTemplateFile != "") {
+		fatalf(errUsage, "Error: --tree-only cannot be combined with --no-tree, --raw, --review-plan or --prompt-template.")
+	}
+	if strings.HasPrefix(gitRef, "-") {
+		fatalf(errUsage, "Error: Invalid revision '%s' for --at.", gitRef)
+	}
+	if gitRef != "" && (hasFileLists() || len(includeIgnored) > 0 || useTreeCommand) {
+		fatalf(errUsage, "Error: --at reads the files of a commit; it cannot be combined with --files-from, --include-ignored or --tree-cmd.")
+	}
+	if trackedOnly && untrackedOnly {
+		fatalf(errUsage, "Error: --tracked-only and --untracked-only cannot be used together.")
+	}
+	if (trackedOnly || untrackedOnly) && (gitRef != "" || hasFileLists()) {
+		fatalf(errUsage, "Error: --tracked-only and --untracked-only filter the Git listing; they cannot be combined with --at or --files-from.")
+	}
+	if len(repos) > 0 && (gitRef != "" || hasFileLists() || treeRoot != "" || useTreeCommand) {
+		fatalf(errUsage, "Error: Several --repo cannot be combined with --at, --files-from, --tree-root or --tree-cmd.")
+	}
+	if respectLocalExclude && len(repos) > 0 {
+		fatalf(errUsage, "Error: --respect-local-exclude cannot be combined with several --repo.")
+	}
+	if autoDocs && !autoReadme {
+		fatalf(errUsage, "Error: --auto-docs requires --auto-readme.")
+	}
+	if autoReadme && (rawMode || reviewPlanFile != "" || hasFileLists()) {
+		fatalf(errUsage, "Error: --auto-readme cannot be combined with --raw, --review-plan or --files-from.")
+	}
+	if prompt.QuestionsOrder(questionsOrder) != prompt.QuestionsAsGiven && (rawMode || reviewPlanFile != "") {
+		fatalf(errUsage, "Error: --questions-order cannot be combined with --raw or --review-plan, which place the questions by their position.")
+	}
+	if includeDeps && (rawMode || reviewPlanFile != "") {
+		fatalf(errUsage, "Error: --deps cannot be combined with --raw or --review-plan.")
+	}
+	if autoTrim && maxTokens == 0 {
+		fatalf(errUsage, "Error: --auto-trim requires --max-tokens.")
+	}
+	if appendOutput && outputFile == "" {
+		fatalf(errUsage, "Error: --append requires --output.")
+	}
+	if printCommand && !dryRun {
+		fatalf(errUsage, "Error: --print-command requires --dry-run.")
+	}
+	if teeOutput && (useStdout || outputFile != "") {
+		fatalf(errUsage, "Error: --tee already prints to stdout and copies to the clipboard; it cannot be combined with --stdout or --output.")
+	}
+	for _, language := range languages {
+		if !files.IsKnownLanguage(language) {
+			fatalf(errUsage, "Error: Unknown language '%s' for --lang. Supported languages: %s", language, strings.Join(files.Languages(), ", "))
+		}
+	}
+
+	// Validate review plan options: the plan supplies the files and questions of each step
+	if reviewPlanFile != "" && (len(forceIncludePatterns) > 0 || len(questionFiles) > 0 || useClipboard) {
+		fatalf(errUsage, "Error: --review-plan cannot be combined with -f, -qf or -c.")
+	}
+
+	// Validate the tree root
+	if treeRoot != "" {
+		if info, err := os.Stat(treeRoot); err != nil || !info.IsDir() {
+			fatalf(errUsage, "Error: --tree-root '%s' is not a directory.", treeRoot)
+		}
+	}
+
+	// Validate the directory displayed paths are relative to
+	if relativeTo != "" {
+		if info, err := os.Stat(relativeTo); err != nil || !info.IsDir() {
+			fatalf(errUsage, "Error: --relative-to '%s' is not a directory.", relativeTo)
+		}
+	}
+
+	// Without a usable clipboard (--no-clipboard, headless server), fall back to stdout
+	if noClipboardFlag {
+		clipboardBackend = noClipboard{}
+	}
+	if outputFile == "" && !useStdout && !clipboardBackend.Available() {
+		if verbosity >= prompt.VerbosityNormal && !noClipboardFlag {
+			fmt.Fprintln(os.Stderr, "Warning: No clipboard available; writing the prompt to stdout instead.")
+		}
+		useStdout = true
+		teeOutput = false
 	}
 
 	printInfo("Starting make-project-prompt (Go version)...\n")
 
 	// Check dependencies
 	if err := checkDependencies(); err != nil {
-		log.Fatalf("Error: %v", err)
+		fatalErr(errGit, err)
 	}
 
 	// Display options
@@ -645,34 +2374,44 @@ func main() {
 	if len(forceIncludePatterns) > 0 {
 		printInfo("Force inclusion patterns: %v\n", forceIncludePatterns)
 	}
+	if len(includeIgnored) > 0 {
+		printInfo("Ignored file inclusion patterns: %v\n", includeIgnored)
+	}
+	if len(filesFrom) > 0 {
+		printInfo("File lists: %v\n", filesFrom)
+	}
+	if len(filesFrom0) > 0 {
+		printInfo("NUL-separated file lists: %v\n", filesFrom0)
+	}
 	if len(questions) > 0 {
 		printInfo("Questions from -q: %v\n", questions)
 	}
 	if len(questionFiles) > 0 {
 		printInfo("Question files from -qf: %v\n", questionFiles)
 	}
+	if len(questionsFiles) > 0 {
+		printInfo("Questions files: %v\n", questionsFiles)
+	}
 	if useClipboard {
 		printInfo("Using clipboard content as question\n")
 	}
 	if rawMode {
 		printInfo("Raw mode enabled\n")
 	}
+	if reviewPlanFile != "" {
+		printInfo("Review plan: %s\n", reviewPlanFile)
+	}
 
 	// If dry-run is requested, list files and exit.
 	if dryRun {
 		printInfo("--- Performing a dry run ---\n")
-		fileConfig := files.Config{
-			IncludePatterns:      includePatterns,
-			ExcludePatterns:      excludePatterns,
-			ForceIncludePatterns: forceIncludePatterns,
-		}
-		fileInfos, err := files.ListGitFiles(fileConfig)
+		fileInfos, err := listCandidateFiles()
 		if err != nil {
-			log.Fatalf("Error: %v", err)
+			fatalErr(errGeneric, err)
 		}
 
 		if len(fileInfos) == 0 {
-			log.Fatalf("Dry run: No files would be included with the current filters.")
+			fatalf(errNoFiles, "Dry run: No files would be included with the current filters.")
 		}
 
 		fmt.Println("The following files would be included in the prompt:")
@@ -680,40 +2419,106 @@ func main() {
 			fmt.Println("- " + info.Path)
 		}
 		fmt.Printf("\nTotal files: %d\n", len(fileInfos))
+		if printCommand {
+			fmt.Printf("\nCommand reproducing this selection:\n%s\n", selectionCommand(userExcludes))
+		}
+		saveRequestedAlias(expandedArgs)
 		os.Exit(0) // Exit successfully after the dry run
 	}
 
+	// Read the previous prompt before generating, so a missing file fails fast
+	var previousPrompt []byte
+	if compareTo != "" {
+		previousPrompt, err = os.ReadFile(compareTo)
+		if err != nil {
+			fatalf(errInput, "Error reading --compare-to file: %v", err)
+		}
+	}
+
 	// Process files and generate prompt
-	prompt, fileCount, err := processFilesAndGeneratePrompt()
+	start := time.Now()
+	promptText, fileCount, err := processFilesAndGeneratePrompt()
 	if err != nil {
-		log.Fatalf("Error: %v", err)
+		fatalErr(errGeneric, err)
+	}
+	if showStats && verbosity >= prompt.VerbosityNormal && !useStdout {
+		printStats(time.Since(start), promptText, fileCount)
+	}
+
+	// Warn about secret-like content before the prompt leaves the machine. This goes to
+	// stderr so it is shown even with --stdout or --quiet.
+	for _, finding := range secretFindings {
+		fmt.Fprintf(os.Stderr, "Warning: Possible %s in '%s' line %d.\n", finding.Kind, finding.Path, finding.Line)
+	}
+	if failOnSecrets && len(secretFindings) > 0 {
+		fatalf(errSecrets, "Error: Found %d possible secret(s) in the included files; the prompt was not written (--fail-on-secrets).", len(secretFindings))
+	}
+
+	// Pipe the prompt through the --post-process command, after the secret check so a
+	// prompt that fails it never reaches the command
+	if postProcessCommand != "" {
+		promptText, err = prompt.PostProcess(postProcessCommand, promptText)
+		if err != nil {
+			fatalf(errGeneric, "Error: --post-process command failed: %v", err)
+		}
+	}
+
+	// Report the size change against the previous prompt. This goes to stderr so
+	// it is shown even with --stdout or --quiet.
+	if compareTo != "" {
+		previous := prompt.ComputeStats(string(previousPrompt))
+		current := prompt.ComputeStats(promptText)
+		fmt.Fprintf(os.Stderr, "Comparison to %s:\n%s", compareTo, prompt.FormatComparison(previous, current))
 	}
 
 	// Handle output based on flags
 	if useStdout {
 		// Write to stdout and exit. This is critical for clean scripting output.
-		fmt.Print(prompt)
+		fmt.Print(promptText)
+		saveRequestedAlias(expandedArgs)
 		os.Exit(0)
 	} else if outputFile != "" {
 		// Write to file
-		err = os.WriteFile(outputFile, []byte(prompt), 0644)
+		if appendOutput {
+			err = appendPrompt(outputFile, promptText, time.Now())
+		} else {
+			err = os.WriteFile(outputFile, []byte(promptText), 0644)
+		}
 		if err != nil {
-			log.Fatalf("Error writing to output file: %v", err)
+			fatalf(errOutput, "Error writing to output file: %v", err)
 		}
 		printInfo("-------------------------------------\n")
-		printInfo("Prompt generated and written to %s!\n", outputFile)
+		if appendOutput {
+			printInfo("Prompt generated and appended to %s!\n", outputFile)
+		} else {
+			printInfo("Prompt generated and written to %s!\n", outputFile)
+		}
 	} else {
 		// Copy to clipboard (default)
-		if err := clipboard.WriteAll(prompt); err != nil {
-			log.Fatalf("Error copying to clipboard: %v\nYou may need to install a clipboard manager or run this tool in a graphical environment.", err)
+		if !copyOrFallback(clipboardBackend, promptText, os.Stdout) {
+			os.Exit(0)
+		}
+		if teeOutput {
+			// Also echo the prompt for a sanity check
+			fmt.Print(promptText)
+			if !strings.HasSuffix(promptText, "\n") {
+				printInfo("\n")
+			}
 		}
 		printInfo("-------------------------------------\n")
 		printInfo("Prompt generated and copied to clipboard!\n")
 	}
 
+	// Record this invocation as an alias now that it is known to work
+	saveRequestedAlias(expandedArgs)
+
 	// User feedback
-	printInfo("Number of files included: %d\n", fileCount)
-	if len(questions) == 0 && len(questionFiles) == 0 && !useClipboard {
+	if verbosity >= prompt.VerbosityNormal && !useStdout {
+		summary := prompt.ComputeStats(promptText)
+		summary.Files = fileCount
+		fmt.Fprintln(os.Stderr, prompt.FormatSummary(summary))
+	}
+	if len(questions) == 0 && len(questionFiles) == 0 && len(questionsFiles) == 0 && !useClipboard && defaultQuestion == "" {
 		printInfo("NOTE: No question specified. Remember to replace '[YOUR QUESTION HERE]'.\n")
 	}
 	if !useStdout {
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briossant/make-project-prompt/pkg/config"
+)
+
+func TestExpandAliasesInArgs_OutputOverride(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Aliases["ci"] = config.Alias{Name: "ci", Options: "-i src/* --output prompt.txt -q Review"}
+	cfg.Aliases["interactive"] = config.Alias{Name: "interactive", Options: "-i src/* --stdout"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Alias output is kept without command-line output", []string{"-a", "ci"}, "-i src/* --output prompt.txt -q Review"},
+		{"Command-line --stdout replaces alias --output", []string{"-a", "ci", "--stdout"}, "-i src/* -q Review --stdout"},
+		{"Command-line --output replaces alias --stdout", []string{"--output", "out.txt", "-a", "interactive"}, "--output out.txt -i src/*"},
+		{"Command-line --tee replaces alias --stdout", []string{"-a", "interactive", "--tee"}, "-i src/* --tee"},
+		{"Command-line --append keeps alias --output", []string{"-a", "ci", "--append"}, "-i src/* --output prompt.txt -q Review --append"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expanded, err := expandAliasesInArgs(cfg, tc.args)
+			if err != nil {
+				t.Fatalf("expandAliasesInArgs returned error: %v", err)
+			}
+			if got := strings.Join(expanded, " "); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  string
+		expected []string
+	}{
+		{"Valid options", `-i *.go *.md -e vendor --max-files 10 --stdout -q "Review this"`, nil},
+		{"Unknown flag", "-ii *.go", []string{"unknown flag -ii"}},
+		{"Malformed value", "--max-total-bytes lots", []string{`invalid value "lots" for --max-total-bytes: expected a size such as 512, 100k, or 2M`}},
+		{"Missing value", "--head --stdout", []string{"flag --head is missing its value"}},
+		{"Ignored argument", "--stdout src/*.go", []string{"argument 'src/*.go' after --stdout is ignored"}},
+		{"Nested alias", "-a other", []string{"-a cannot be used in an alias: aliases are not expanded inside aliases"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := validateOptions(config.ExpandAlias(tc.options))
+			if strings.Join(problems, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("Expected %q, got %q", tc.expected, problems)
+			}
+		})
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Files = []string{"/repo/app/.mpp.txt", "/repo/.mpp.txt"}
+	cfg.Aliases["go"] = config.Alias{Name: "go", Options: "-i *.go", Source: "/repo/app/.mpp.txt"}
+	cfg.Directives["default-exclude"] = config.Directive{Name: "default-exclude", Value: "dist", Source: "/repo/.mpp.txt"}
+	cfg.Suppressed = []config.Suppressed{{Kind: "alias", Name: "go", Source: "/repo/.mpp.txt", KeptSource: "/repo/app/.mpp.txt"}}
+
+	var output strings.Builder
+	writeConfig(&output, cfg)
+
+	expected := `Config files (search order, nearest first):
+  1. /repo/app/.mpp.txt
+  2. /repo/.mpp.txt
+
+Aliases (1):
+  go: -i *.go
+    (defined in /repo/app/.mpp.txt)
+
+Directives (1):
+  @default-exclude: dist
+    (defined in /repo/.mpp.txt)
+
+Suppressed duplicates (1, the first definition wins):
+  alias go in /repo/.mpp.txt
+    (ignored, the definition in /repo/app/.mpp.txt is in effect)
+`
+	if output.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output.String())
+	}
+}
+
+func TestAppendPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.txt")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := appendPrompt(path, "first prompt\n", now); err != nil {
+		t.Fatalf("appendPrompt returned error: %v", err)
+	}
+	if err := appendPrompt(path, "second prompt\n", now); err != nil {
+		t.Fatalf("appendPrompt returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	expected := "first prompt\n\n===== PROMPT APPENDED AT 2024-05-01T12:00:00Z =====\n\nsecond prompt\n"
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(content))
+	}
+}
+
+func TestSelectionCommand(t *testing.T) {
+	defer func() {
+		includePatterns, forceIncludePatterns, noTests = nil, nil, false
+	}()
+	includePatterns = multiStringFlag{"src/**/*.go", "README.md"}
+	forceIncludePatterns = multiStringFlag{"docs/it's.md"}
+	noTests = true
+
+	expected := `make-project-prompt -i 'src/**/*.go' -i README.md -f 'docs/it'\''s.md' -e 'src/legacy dir' --no-tests`
+	if got := selectionCommand([]string{"src/legacy dir"}); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestUnpairedPattern(t *testing.T) {
+	include := func(pattern string) argOrderItem { return argOrderItem{Type: "include", Content: pattern} }
+	question := argOrderItem{Type: "question", Content: "Why?"}
+	extra := argOrderItem{Type: "extra_context", Content: "Context"}
+
+	tests := []struct {
+		name     string
+		items    []argOrderItem
+		expected string
+	}{
+		{"every pattern is paired", []argOrderItem{question, include("a.go"), include("b.go"), question, include("c.go"), extra, question}, ""},
+		{"last pattern has no question", []argOrderItem{include("a.go"), question, include("b.go"), include("c.go")}, "b.go"},
+		{"no pattern", []argOrderItem{question}, ""},
+	}
+	for _, tc := range tests {
+		pattern, ok := unpairedPattern(tc.items)
+		if pattern != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("%s: expected %q, got %q (%v)", tc.name, tc.expected, pattern, ok)
+		}
+	}
+}
+
+func TestParseExtensionList(t *testing.T) {
+	got := parseExtensionList(".foo, bar,,.Baz ")
+	expected := []string{".foo", ".bar", ".Baz"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	tagged := withCategory(errNoFiles, errors.New("no files selected"))
+
+	tests := []struct {
+		name     string
+		err      error
+		expected errorCategory
+	}{
+		{"Untagged error", errors.New("boom"), errGeneric},
+		{"Tagged error", tagged, errNoFiles},
+		{"Wrapped tagged error", fmt.Errorf("failed: %w", tagged), errNoFiles},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := categoryOf(tc.err, errGeneric); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+	if tagged.Error() != "no files selected" {
+		t.Errorf("Expected the message to be kept, got %q", tagged.Error())
+	}
+}
+
+func TestCallerPath(t *testing.T) {
+	if got := callerPath("notes.txt"); got != "notes.txt" {
+		t.Errorf("Expected paths to be kept without --repo, got %s", got)
+	}
+
+	callerDir = "/home/user"
+	defer func() { callerDir = "" }()
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"notes.txt", "/home/user/notes.txt"},
+		{"../shared/q.txt", "/home/shared/q.txt"},
+		{"/tmp/prompt.txt", "/tmp/prompt.txt"},
+		{"-", "-"},
+	}
+	for _, tc := range tests {
+		if got := callerPath(tc.path); got != tc.expected {
+			t.Errorf("callerPath(%q): expected %s, got %s", tc.path, tc.expected, got)
+		}
+	}
+
+	if got := repoArgs([]string{"--repo", "api", "-q", "Review", "--repo", "../other"}); strings.Join(got, ",") != "api,../other" {
+		t.Errorf("Expected --repo api and ../other, got %q", got)
+	}
+}
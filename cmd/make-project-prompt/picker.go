@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+	"github.com/briossant/make-project-prompt/pkg/prompt"
+)
+
+// errPickerAborted is returned when the user quits the interactive picker
+var errPickerAborted = errors.New("interactive selection aborted")
+
+// pickFiles runs a numbered multi-select loop: the candidate files are listed on out with
+// their selection state, and commands read from in toggle them until an empty line confirms.
+// All files start selected.
+func pickFiles(candidates []files.FileInfo, in io.Reader, out io.Writer) ([]files.FileInfo, error) {
+	selected := make([]bool, len(candidates))
+	for i := range selected {
+		selected[i] = true
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		count := 0
+		for i, file := range candidates {
+			mark := " "
+			if selected[i] {
+				mark = "x"
+				count++
+			}
+			fmt.Fprintf(out, "  [%s] %3d  %s\n", mark, i+1, file.Path)
+		}
+		fmt.Fprintf(out, "%d of %d files selected. Toggle with numbers or ranges (e.g. 1 3 5-7), 'a' for all, 'n' for none,\n", count, len(candidates))
+		fmt.Fprint(out, "'q' to quit, or press Enter to confirm: ")
+
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			// End of input confirms the current selection
+			fmt.Fprintln(out)
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			break
+		}
+
+		switch line {
+		case "a":
+			setAll(selected, true)
+			continue
+		case "n":
+			setAll(selected, false)
+			continue
+		case "q":
+			return nil, errPickerAborted
+		}
+
+		for _, field := range strings.Fields(line) {
+			first, last, err := parsePickerRange(field, len(candidates))
+			if err != nil {
+				fmt.Fprintf(out, "Ignoring %v\n", err)
+				continue
+			}
+			for i := first; i <= last; i++ {
+				selected[i-1] = !selected[i-1]
+			}
+		}
+	}
+
+	var result []files.FileInfo
+	for i, file := range candidates {
+		if selected[i] {
+			result = append(result, file)
+		}
+	}
+	return result, nil
+}
+
+// setAll sets every selection state to value
+func setAll(selected []bool, value bool) {
+	for i := range selected {
+		selected[i] = value
+	}
+}
+
+// parsePickerRange parses "N" or "N-M" into an inclusive 1-based range within [1, max]
+func parsePickerRange(field string, max int) (int, int, error) {
+	firstStr, lastStr, isRange := strings.Cut(field, "-")
+	first, err := strconv.Atoi(firstStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid selection %q", field)
+	}
+	last := first
+	if isRange {
+		last, err = strconv.Atoi(lastStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid selection %q", field)
+		}
+	}
+	if first < 1 || last > max || first > last {
+		return 0, 0, fmt.Errorf("out of range selection %q", field)
+	}
+	return first, last, nil
+}
+
+// keepSelectedFiles drops the files that were not selected from the file groups of items,
+// removing groups left empty
+func keepSelectedFiles(items []prompt.ContentItem, selected []files.FileInfo) []prompt.ContentItem {
+	keep := make(map[string]bool, len(selected))
+	for _, file := range selected {
+		keep[file.Path] = true
+	}
+
+	var result []prompt.ContentItem
+	for _, item := range items {
+		if item.Type == "file_group" {
+			var groupFiles []files.FileInfo
+			for _, file := range item.Files {
+				if keep[file.Path] {
+					groupFiles = append(groupFiles, file)
+				}
+			}
+			if len(groupFiles) == 0 {
+				continue
+			}
+			item.Files = groupFiles
+		}
+		result = append(result, item)
+	}
+	return result
+}
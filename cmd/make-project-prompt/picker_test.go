@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+	"github.com/briossant/make-project-prompt/pkg/prompt"
+)
+
+func pickerCandidates() []files.FileInfo {
+	return []files.FileInfo{
+		{Path: "a.go"}, {Path: "b.go"}, {Path: "c.go"}, {Path: "d.go"},
+	}
+}
+
+func pickedPaths(picked []files.FileInfo) string {
+	var paths []string
+	for _, file := range picked {
+		paths = append(paths, file.Path)
+	}
+	return strings.Join(paths, ",")
+}
+
+func TestPickFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Enter keeps everything", "\n", "a.go,b.go,c.go,d.go"},
+		{"End of input keeps everything", "", "a.go,b.go,c.go,d.go"},
+		{"Toggle numbers", "1 3\n\n", "b.go,d.go"},
+		{"Toggle a range", "2-4\n\n", "a.go"},
+		{"None then pick", "n\n2\n\n", "b.go"},
+		{"All after none", "n\na\n\n", "a.go,b.go,c.go,d.go"},
+		{"Invalid selections are ignored", "0 9 x 3-2\n1\n\n", "b.go,c.go,d.go"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			picked, err := pickFiles(pickerCandidates(), strings.NewReader(tc.input), &out)
+			if err != nil {
+				t.Fatalf("pickFiles returned error: %v", err)
+			}
+			if got := pickedPaths(picked); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("Quit aborts", func(t *testing.T) {
+		var out strings.Builder
+		_, err := pickFiles(pickerCandidates(), strings.NewReader("q\n"), &out)
+		if !errors.Is(err, errPickerAborted) {
+			t.Errorf("Expected errPickerAborted, got %v", err)
+		}
+	})
+
+	t.Run("Lists files with their state", func(t *testing.T) {
+		var out strings.Builder
+		if _, err := pickFiles(pickerCandidates(), strings.NewReader("2\n\n"), &out); err != nil {
+			t.Fatalf("pickFiles returned error: %v", err)
+		}
+		if !strings.Contains(out.String(), "[ ]   2  b.go") {
+			t.Errorf("Expected b.go to be listed as unselected, got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), "3 of 4 files selected") {
+			t.Errorf("Expected selection count, got:\n%s", out.String())
+		}
+	})
+}
+
+func TestKeepSelectedFiles(t *testing.T) {
+	items := []prompt.ContentItem{
+		{Type: "file_group", Files: []files.FileInfo{{Path: "a.go"}, {Path: "b.go"}}, Order: 0},
+		{Type: "question", Content: "Why?", Order: 1},
+		{Type: "file_group", Files: []files.FileInfo{{Path: "c.go"}}, Order: 2},
+	}
+
+	result := keepSelectedFiles(items, []files.FileInfo{{Path: "b.go"}})
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 items (empty group dropped), got %d", len(result))
+	}
+	if got := pickedPaths(result[0].Files); got != "b.go" {
+		t.Errorf("Expected first group to contain only b.go, got %q", got)
+	}
+	if result[1].Type != "question" {
+		t.Errorf("Expected the question to be kept, got %q", result[1].Type)
+	}
+}
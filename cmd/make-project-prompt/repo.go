@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+	"github.com/briossant/make-project-prompt/pkg/prompt"
+)
+
+// callerDir is the directory mpp was started in, when --repo made it run in another one
+var callerDir string
+
+// callerFileFlags lists the flags (without leading dashes) whose value is a file read or
+// written by mpp itself, resolved from the directory mpp was started in
+var callerFileFlags = map[string]bool{
+	"qf":                 true,
+	"questions-file":     true,
+	"extra-context-file": true,
+	"include-from":       true,
+	"exclude-from":       true,
+	"files-from":         true,
+	"files-from0":        true,
+	"review-plan":        true,
+	"prompt-template":    true,
+	"output":             true,
+	"compare-to":         true,
+}
+
+// repoArgs returns the values of the --repo flags given on the command line. They are
+// looked up before the arguments are parsed, since aliases are read from the repository.
+func repoArgs(args []string) []string {
+	var dirs []string
+	for i, arg := range args {
+		if (arg == "-repo" || arg == "--repo") && i+1 < len(args) {
+			dirs = append(dirs, args[i+1])
+		}
+	}
+	return dirs
+}
+
+// enterRepo makes dir the working directory, in which git runs and files are read,
+// remembering the directory mpp was started in for callerPath
+func enterRepo(dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		fatalf(errGeneric, "Error: Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fatalf(errUsage, "Error: Cannot use --repo %s: %v", dir, err)
+	}
+	callerDir = wd
+}
+
+// loadRepos returns the repositories of several --repo, labeled with their directory name.
+// mpp keeps running in the current directory, whose .mpp.txt files apply.
+func loadRepos(dirs []string) []prompt.Repo {
+	var loaded []prompt.Repo
+	labels := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			fatalf(errUsage, "Error: Cannot use --repo %s: %v", dir, err)
+		}
+		if info, err := os.Stat(absDir); err != nil || !info.IsDir() {
+			fatalf(errUsage, "Error: Cannot use --repo %s: not a directory", dir)
+		}
+		label := filepath.Base(absDir)
+		if previous, exists := labels[label]; exists {
+			fatalf(errUsage, "Error: --repo %s and --repo %s have the same name '%s'.", previous, dir, label)
+		}
+		labels[label] = dir
+		loaded = append(loaded, prompt.Repo{Label: label, Dir: absDir})
+	}
+	return loaded
+}
+
+// listRepoFiles lists the Git files selected by a file configuration in each repository,
+// with their paths prefixed by the repository label
+func listRepoFiles(fileConfig files.Config) ([]files.FileInfo, error) {
+	var fileInfos []files.FileInfo
+	for _, repo := range repos {
+		repoFiles, err := listFilesIn(repo.Dir, fileConfig)
+		if err != nil {
+			return nil, fmt.Errorf("repository '%s': %w", repo.Label, err)
+		}
+		for _, info := range repoFiles {
+			info.Path = repo.Label + "/" + info.Path
+			fileInfos = append(fileInfos, info)
+		}
+	}
+	return fileInfos, nil
+}
+
+// listFilesIn lists the Git files selected by a file configuration from dir, returning to
+// the working directory afterwards
+func listFilesIn(dir string, fileConfig files.Config) ([]files.FileInfo, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			fatalf(errGeneric, "Error: Failed to return to %s: %v", wd, err)
+		}
+	}()
+	return files.ListGitFiles(fileConfig)
+}
+
+// callerPath resolves a path given on the command line from the directory mpp was started in,
+// when --repo changed the working directory. A lone "-" (stdin or stdout) is kept.
+func callerPath(path string) string {
+	if callerDir == "" || path == "" || path == "-" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(callerDir, path)
+}
+
+// isCallerFileFlag tells whether the value of a flag is resolved with callerPath
+func isCallerFileFlag(flagName string) bool {
+	return callerFileFlags[strings.TrimLeft(flagName, "-")]
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/briossant/make-project-prompt/pkg/config"
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+// multiValueFlags take every following argument up to the next flag as a value
+var multiValueFlags = map[string]bool{"i": true, "e": true, "f": true, "q": true, "qf": true}
+
+// flagSpellings maps the flag names accepted by customParseArgs but not registered with
+// the flag package to the registered name
+var flagSpellings = map[string]string{"verbose": "v"}
+
+// commandLineOnlyFlags are the flags that have no effect, or fail, in alias options
+var commandLineOnlyFlags = map[string]string{
+	"a":                   "aliases are not expanded inside aliases",
+	"repo":                "it must be given on the command line",
+	"list-aliases":        "it must be given on the command line",
+	"show-config":         "it must be given on the command line",
+	"validate-aliases":    "it must be given on the command line",
+	"config-walk-to-root": "the config files are already loaded when aliases are expanded",
+}
+
+// flagValueChecks validate the values of the flags customParseArgs parses, beyond strings
+var flagValueChecks = map[string]func(value string) error{
+	"q-slot": func(value string) error {
+		_, _, err := config.ParseSlotOverride(value)
+		return err
+	},
+	"questions-order": checkQuestionsOrder,
+	"lang": func(value string) error {
+		for _, language := range parseLanguageList(value) {
+			if !files.IsKnownLanguage(language) {
+				return fmt.Errorf("unknown language '%s' for --lang", language)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	for _, name := range []string{"head", "tail", "depth", "tree-depth", "minified-threshold", "tabs", "flag-long-lines", "max-tokens", "max-files"} {
+		flagName := "--" + name
+		flagValueChecks[name] = func(value string) error {
+			_, err := parseCountFlag(flagName, value)
+			return err
+		}
+	}
+	for _, name := range []string{"exclude-larger-than", "max-total-bytes"} {
+		flagName := "--" + name
+		flagValueChecks[name] = func(value string) error {
+			_, err := parseSizeFlag(flagName, value)
+			return err
+		}
+	}
+}
+
+// lookupFlag returns the registered flag an argument names, if any
+func lookupFlag(arg string) (*flag.Flag, bool) {
+	name := strings.TrimLeft(arg, "-")
+	if registered, ok := flagSpellings[name]; ok {
+		name = registered
+	}
+	registered := flag.Lookup(name)
+	return registered, registered != nil
+}
+
+// isBoolFlag reports whether a registered flag is a switch, taking no value
+func isBoolFlag(registered *flag.Flag) bool {
+	boolValue, ok := registered.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolValue.IsBoolFlag()
+}
+
+// validateOptions checks options the way customParseArgs parses them, and returns a description
+// of each unknown flag, missing or malformed value, and argument that would be silently ignored
+func validateOptions(args []string) []string {
+	var problems []string
+	isFlag := func(arg string) bool {
+		return strings.HasPrefix(arg, "-") && arg != "-"
+	}
+
+	var current *flag.Flag // Flag taking the next arguments, nil when none does
+	currentArg := ""
+	unknown := false // The arguments following an unknown flag are already reported with it
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !isFlag(arg) {
+			if current == nil && !unknown {
+				if currentArg == "" {
+					problems = append(problems, fmt.Sprintf("argument '%s' is not the value of any flag and is ignored", arg))
+				} else {
+					problems = append(problems, fmt.Sprintf("argument '%s' after %s is ignored", arg, currentArg))
+				}
+			}
+			continue
+		}
+
+		current, currentArg = nil, arg
+		registered, ok := lookupFlag(arg)
+		unknown = !ok
+		if unknown {
+			problems = append(problems, fmt.Sprintf("unknown flag %s", arg))
+			continue
+		}
+		if reason, ok := commandLineOnlyFlags[registered.Name]; ok {
+			problems = append(problems, fmt.Sprintf("%s cannot be used in an alias: %s", arg, reason))
+		}
+		if isBoolFlag(registered) {
+			continue
+		}
+
+		if i+1 >= len(args) || isFlag(args[i+1]) {
+			problems = append(problems, fmt.Sprintf("flag %s is missing its value", arg))
+			continue
+		}
+		i++
+		if check, ok := flagValueChecks[registered.Name]; ok {
+			if err := check(args[i]); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if multiValueFlags[registered.Name] {
+			current = registered
+		}
+	}
+	return problems
+}
+
+// aliasProblems validates the options of every alias and returns the problems found, by alias name
+func aliasProblems(cfg *config.Config) map[string][]string {
+	problems := make(map[string][]string)
+	for _, alias := range cfg.ListAliases() {
+		if aliasIssues := validateOptions(config.ExpandAlias(alias.Options)); len(aliasIssues) > 0 {
+			problems[alias.Name] = aliasIssues
+		}
+	}
+	return problems
+}
+
+// sortedAliases returns the aliases of a config sorted by name
+func sortedAliases(cfg *config.Config) []config.Alias {
+	aliases := cfg.ListAliases()
+	slices.SortFunc(aliases, func(a, b config.Alias) int { return strings.Compare(a.Name, b.Name) })
+	return aliases
+}
@@ -6,6 +6,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -17,22 +18,61 @@ type Alias struct {
 	Source  string // Path to the config file where this alias was defined
 }
 
-// Config holds all loaded aliases
+// Directive represents a "@name: value" setting that applies to every run, unlike aliases
+// which must be explicitly selected
+type Directive struct {
+	Name   string // Directive name, without the leading @
+	Value  string
+	Source string // Path to the config file where this directive was defined
+}
+
+// directivePrefix marks a config line as a directive rather than an alias definition
+const directivePrefix = "@"
+
+// TestPatternsDirective overrides the patterns excluded by --no-tests
+const TestPatternsDirective = "test-patterns"
+
+// DefaultExcludeDirective lists exclude patterns applied to every run (unless --no-default-exclude)
+const DefaultExcludeDirective = "default-exclude"
+
+// DefaultQuestionDirective is the question asked when none is given on the command line
+const DefaultQuestionDirective = "default-question"
+
+// Suppressed records an alias or directive ignored because an earlier config file in the
+// search order already defined it: the first one wins
+type Suppressed struct {
+	Kind       string // "alias" or "directive"
+	Name       string
+	Source     string // Config file of the ignored definition
+	KeptSource string // Config file of the definition in effect
+}
+
+// Config holds all loaded aliases and directives
 type Config struct {
-	Aliases map[string]Alias // Key is the alias name
+	Aliases    map[string]Alias     // Key is the alias name
+	Directives map[string]Directive // Key is the directive name
+	Files      []string             // Config files loaded, in search order (nearest first)
+	Suppressed []Suppressed         // Definitions ignored in favor of an earlier one, in search order
 }
 
 // NewConfig creates a new empty config
 func NewConfig() *Config {
 	return &Config{
-		Aliases: make(map[string]Alias),
+		Aliases:    make(map[string]Alias),
+		Directives: make(map[string]Directive),
 	}
 }
 
 // LoadAliases loads aliases from .mpp.txt files, searching recursively up the directory tree
 func LoadAliases() (*Config, error) {
+	return LoadAliasesUpTo("")
+}
+
+// LoadAliasesUpTo loads aliases from .mpp.txt files, searching up the directory tree from the
+// current directory to stopDir, included ("" = the file system root). When the search stops
+// before the root, the global .mpp.txt of the home directory is loaded last, if not visited.
+func LoadAliasesUpTo(stopDir string) (*Config, error) {
 	config := NewConfig()
-	seenAliases := make(map[string]string) // Track where each alias was first seen
 
 	// Start from current directory
 	currentDir, err := os.Getwd()
@@ -41,29 +81,12 @@ func LoadAliases() (*Config, error) {
 	}
 
 	// Walk up the directory tree
+	stopped := false
 	for {
-		configPath := filepath.Join(currentDir, ".mpp.txt")
-
-		// Check if config file exists
-		if _, err := os.Stat(configPath); err == nil {
-			// Load aliases from this file
-			aliases, err := parseConfigFile(configPath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to parse config file %s: %v\n", configPath, err)
-			} else {
-				// Add aliases, checking for duplicates
-				for _, alias := range aliases {
-					if existingSource, exists := seenAliases[alias.Name]; exists {
-						// Alias already exists - first one wins
-						fmt.Fprintf(os.Stderr, "Warning: alias [%s] is duplicated (first defined in %s, also in %s)\n",
-							alias.Name, existingSource, configPath)
-					} else {
-						// Add the alias
-						config.Aliases[alias.Name] = alias
-						seenAliases[alias.Name] = configPath
-					}
-				}
-			}
+		config.loadFile(filepath.Join(currentDir, configFileName))
+		if stopDir != "" && sameDir(currentDir, stopDir) {
+			stopped = true
+			break
 		}
 
 		// Move to parent directory
@@ -75,18 +98,94 @@ func LoadAliases() (*Config, error) {
 		currentDir = parent
 	}
 
+	// The global config still applies to every project, with the lowest priority
+	if home, err := os.UserHomeDir(); stopped && err == nil && !config.visited(home) {
+		config.loadFile(filepath.Join(home, configFileName))
+	}
+
 	return config, nil
 }
 
-// parseConfigFile parses a single .mpp.txt config file
-func parseConfigFile(path string) ([]Alias, error) {
+// loadFile adds the aliases and directives of a config file, if it exists, to the ones
+// already loaded: the first definition of a name wins
+func (c *Config) loadFile(configPath string) {
+	if _, err := os.Stat(configPath); err != nil {
+		return
+	}
+
+	aliases, directives, err := parseConfigFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to parse config file %s: %v\n", configPath, err)
+		return
+	}
+	c.Files = append(c.Files, configPath)
+
+	// Add aliases, checking for duplicates
+	for _, alias := range aliases {
+		if existing, exists := c.Aliases[alias.Name]; exists {
+			// Alias already exists - first one wins
+			fmt.Fprintf(os.Stderr, "Warning: alias [%s] is duplicated (first defined in %s, also in %s)\n",
+				alias.Name, existing.Source, configPath)
+			c.Suppressed = append(c.Suppressed, Suppressed{
+				Kind:       "alias",
+				Name:       alias.Name,
+				Source:     configPath,
+				KeptSource: existing.Source,
+			})
+		} else {
+			c.Aliases[alias.Name] = alias
+		}
+	}
+
+	// Directives from the nearest config file win, without warnings:
+	// overriding a parent directory's settings is expected
+	for _, directive := range directives {
+		if existing, exists := c.Directives[directive.Name]; !exists {
+			c.Directives[directive.Name] = directive
+		} else {
+			c.Suppressed = append(c.Suppressed, Suppressed{
+				Kind:       "directive",
+				Name:       directive.Name,
+				Source:     configPath,
+				KeptSource: existing.Source,
+			})
+		}
+	}
+}
+
+// visited reports whether the config file of dir was already loaded
+func (c *Config) visited(dir string) bool {
+	for _, path := range c.Files {
+		if sameDir(filepath.Dir(path), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDir reports whether two paths name the same directory, symlinks resolved
+func sameDir(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// parseConfigFile parses a single .mpp.txt config file into its aliases and directives
+func parseConfigFile(path string) ([]Alias, []Directive, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
 	var aliases []Alias
+	var directives []Directive
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
 
@@ -109,6 +208,22 @@ func parseConfigFile(path string) ([]Alias, error) {
 		name := strings.TrimSpace(parts[0])
 		options := strings.TrimSpace(parts[1])
 
+		// Parse directive: "@name: value"
+		if strings.HasPrefix(name, directivePrefix) {
+			// Spaces are normalized, as in "@profile  review"
+			directiveName := strings.Join(strings.Fields(strings.TrimPrefix(name, directivePrefix)), " ")
+			if directiveName == "" {
+				fmt.Fprintf(os.Stderr, "Warning: Empty directive name at %s:%d\n", path, lineNum)
+				continue
+			}
+			directives = append(directives, Directive{
+				Name:   directiveName,
+				Value:  options,
+				Source: path,
+			})
+			continue
+		}
+
 		if name == "" {
 			fmt.Fprintf(os.Stderr, "Warning: Empty alias name at %s:%d\n", path, lineNum)
 			continue
@@ -122,10 +237,10 @@ func parseConfigFile(path string) ([]Alias, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return aliases, nil
+	return aliases, directives, nil
 }
 
 // GetAlias retrieves an alias by name
@@ -134,6 +249,12 @@ func (c *Config) GetAlias(name string) (Alias, bool) {
 	return alias, exists
 }
 
+// GetDirective retrieves a directive by name (without the leading @)
+func (c *Config) GetDirective(name string) (Directive, bool) {
+	directive, exists := c.Directives[name]
+	return directive, exists
+}
+
 // ListAliases returns all aliases sorted by name
 func (c *Config) ListAliases() []Alias {
 	aliases := make([]Alias, 0, len(c.Aliases))
@@ -143,15 +264,26 @@ func (c *Config) ListAliases() []Alias {
 	return aliases
 }
 
-// ExpandAlias takes an alias and returns the expanded options as a slice of arguments
+// ExpandAlias takes an alias and returns the expanded options as a slice of arguments.
+// As in a shell, quotes group words into a single argument, and a backslash escapes a space,
+// a tab, a quote or a backslash outside quotes, and a double quote or a backslash inside
+// double quotes. Other backslashes are kept as is, so patterns like "\d+" need no escaping.
 func ExpandAlias(options string) []string {
-	// Simple shell-like parsing that respects quotes
+	// Simple shell-like parsing that respects quotes and escapes
 	var args []string
 	var current strings.Builder
 	inQuotes := false
 	quoteChar := rune(0)
+	runes := []rune(options)
+
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if ch == '\\' && i+1 < len(runes) && isEscapable(runes[i+1], inQuotes, quoteChar) {
+			i++
+			current.WriteRune(runes[i])
+			continue
+		}
 
-	for _, ch := range options {
 		if inQuotes {
 			if ch == quoteChar {
 				inQuotes = false
@@ -180,3 +312,342 @@ func ExpandAlias(options string) []string {
 
 	return args
 }
+
+// isEscapable reports whether a backslash escapes the next character: single quotes keep
+// their content verbatim, double quotes only escape themselves and backslashes
+func isEscapable(next rune, inQuotes bool, quoteChar rune) bool {
+	switch {
+	case !inQuotes:
+		return strings.ContainsRune(" \t\"'\\", next)
+	case quoteChar == '"':
+		return next == '"' || next == '\\'
+	default:
+		return false
+	}
+}
+
+// questionSlotPrefix marks a question as the default text of a named slot (e.g. "@slot:focus text")
+const questionSlotPrefix = "@slot:"
+
+// ParseQuestionSlot parses a question declared as "@slot:name default text".
+// It returns the slot name and its default text, and false if the question is not a slot.
+func ParseQuestionSlot(question string) (string, string, bool) {
+	if !strings.HasPrefix(question, questionSlotPrefix) {
+		return "", "", false
+	}
+
+	rest := strings.TrimPrefix(question, questionSlotPrefix)
+	name, text, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return "", "", false
+	}
+
+	return name, strings.TrimSpace(text), true
+}
+
+// ParseSlotOverride parses a "name=text" slot override as given to --q-slot
+func ParseSlotOverride(value string) (string, string, error) {
+	name, text, found := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", "", fmt.Errorf("invalid slot override %q (expected format 'name=text')", value)
+	}
+	return name, text, nil
+}
+
+// ReadPatternFile reads newline-separated glob patterns from a file.
+// Empty lines and lines starting with # are ignored.
+func ReadPatternFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pattern file: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pattern file %s: %w", path, err)
+	}
+
+	return patterns, nil
+}
+
+// GitignorePatterns holds the rules of a gitignore-syntax file, translated to exclusion options
+type GitignorePatterns struct {
+	Excludes  []string // Exclusion globs, as given with -e
+	DirNames  []string // Directory name globs matching at any depth, as given with --exclude-dir
+	Negations []string // Negated rules (!pattern), which exclusions cannot express, as written
+}
+
+// ReadGitignorePatterns reads a file in gitignore syntax, such as .git/info/exclude, and
+// translates its rules: a rule holding a slash is anchored at the repository root, a rule
+// without one matches a file or directory name at any depth, and a trailing slash restricts
+// it to directories. Blank lines and # comments are ignored.
+func ReadGitignorePatterns(path string) (GitignorePatterns, error) {
+	var patterns GitignorePatterns
+	file, err := os.Open(path)
+	if err != nil {
+		return patterns, fmt.Errorf("failed to open ignore file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			patterns.Negations = append(patterns.Negations, line)
+			continue
+		}
+		// A leading backslash escapes a literal # or !
+		if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+			line = line[1:]
+		}
+
+		dirOnly := strings.HasSuffix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.Contains(line, "/"):
+			// Excludes also exclude everything under a matching directory
+			patterns.Excludes = append(patterns.Excludes, strings.TrimPrefix(line, "/"))
+		case dirOnly:
+			patterns.DirNames = append(patterns.DirNames, line)
+		default:
+			patterns.Excludes = append(patterns.Excludes, "**/"+line)
+			patterns.DirNames = append(patterns.DirNames, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return patterns, fmt.Errorf("failed to read ignore file %s: %w", path, err)
+	}
+	return patterns, nil
+}
+
+// RelativeTo returns the patterns matching paths relative to a subdirectory of the repository,
+// given as printed by 'git rev-parse --show-prefix' ("" at the root). Anchored rules are
+// rebased on it, or dropped when they point elsewhere; a rule matching the subdirectory
+// itself or one of its parents excludes everything.
+func (p GitignorePatterns) RelativeTo(prefix string) GitignorePatterns {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return p
+	}
+	dirs := strings.Split(prefix, "/")
+
+	relative := GitignorePatterns{DirNames: p.DirNames, Negations: p.Negations}
+	excludeAll := false
+	for _, exclude := range p.Excludes {
+		if strings.HasPrefix(exclude, "**/") {
+			// Matches at any depth, wherever the listing starts
+			relative.Excludes = append(relative.Excludes, exclude)
+			continue
+		}
+		rebased, ok := rebaseRule(strings.Split(exclude, "/"), dirs)
+		if !ok {
+			continue
+		}
+		if rebased == "" {
+			excludeAll = true
+			continue
+		}
+		relative.Excludes = append(relative.Excludes, rebased)
+	}
+	for _, name := range p.DirNames {
+		for _, dir := range dirs {
+			if matched, _ := path.Match(name, dir); matched {
+				excludeAll = true
+			}
+		}
+	}
+	if excludeAll {
+		relative.Excludes = append(relative.Excludes, "**")
+	}
+	return relative
+}
+
+// rebaseRule matches the leading components of an anchored rule against the directories of a
+// subdirectory and returns the rest of the rule, "" when the rule matches the subdirectory
+// or one of its parents, and false when it cannot match anything under it
+func rebaseRule(components, dirs []string) (string, bool) {
+	for i, dir := range dirs {
+		if i == len(components) {
+			return "", true
+		}
+		if components[i] == "**" {
+			// The rest of the rule may match at any depth below
+			rest := strings.Join(components[i+1:], "/")
+			if rest == "" {
+				return "", true
+			}
+			return "**/" + rest, true
+		}
+		if matched, _ := path.Match(components[i], dir); !matched {
+			return "", false
+		}
+	}
+	if len(components) == len(dirs) {
+		return "", true
+	}
+	return strings.Join(components[len(dirs):], "/"), true
+}
+
+// DefaultQuestionsDelimiter is the line separating the questions of a questions file
+const DefaultQuestionsDelimiter = "---"
+
+// ReadQuestionsFile reads a file holding several questions separated by lines equal to
+// delimiter (surrounding whitespace ignored). Questions are returned in file order, trimmed;
+// empty segments are skipped.
+func ReadQuestionsFile(path, delimiter string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read questions file: %w", err)
+	}
+
+	var questions []string
+	var current []string
+	flush := func() {
+		question := strings.TrimSpace(strings.Join(current, "\n"))
+		if question != "" {
+			questions = append(questions, question)
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == delimiter {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("questions file %s holds no question", path)
+	}
+	return questions, nil
+}
+
+// configFileName is the name of the alias configuration files
+const configFileName = ".mpp.txt"
+
+// FindNearestConfigFile returns the path of the closest .mpp.txt file, searching from the
+// current directory up to the root. If none exists, it returns the path of a .mpp.txt file
+// in the current directory.
+func FindNearestConfigFile() (string, error) {
+	return FindNearestConfigFileUpTo("")
+}
+
+// FindNearestConfigFileUpTo is FindNearestConfigFile with the search stopping at stopDir,
+// included ("" = the file system root), as in LoadAliasesUpTo
+func FindNearestConfigFileUpTo(stopDir string) (string, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	dir := currentDir
+	for {
+		configPath := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, nil
+		}
+		if stopDir != "" && sameDir(dir, stopDir) {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// Reached root
+			break
+		}
+		dir = parent
+	}
+
+	return filepath.Join(currentDir, configFileName), nil
+}
+
+// SaveAlias appends an alias definition to the config file at path, creating the file if needed.
+// It fails if the name is invalid or already defined in that file.
+func SaveAlias(path, name, options string) error {
+	if name == "" || strings.ContainsAny(name, ":\n") || strings.HasPrefix(name, "#") || strings.HasPrefix(name, directivePrefix) {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	if strings.Contains(options, "\n") {
+		return fmt.Errorf("alias options cannot contain newlines")
+	}
+
+	var existing []byte
+	if _, err := os.Stat(path); err == nil {
+		aliases, _, err := parseConfigFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		for _, alias := range aliases {
+			if alias.Name == name {
+				return fmt.Errorf("alias '%s' already exists in %s", name, path)
+			}
+		}
+		existing, err = os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	line := name + ": " + options + "\n"
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		line = "\n" + line
+	}
+	_, err = file.WriteString(line)
+	return err
+}
+
+// SerializeOptions joins arguments into an option string that ExpandAlias parses back
+// into the same arguments, quoting them where needed
+func SerializeOptions(args []string) (string, error) {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\"'\\") {
+			quoted = append(quoted, arg)
+			continue
+		}
+		switch {
+		case arg == "":
+			return "", fmt.Errorf("empty arguments cannot be saved in an alias")
+		case strings.Contains(arg, "\n"):
+			return "", fmt.Errorf("argument %q cannot be saved in an alias: it contains a newline", arg)
+		case !strings.Contains(arg, `"`):
+			quoted = append(quoted, `"`+strings.ReplaceAll(arg, `\`, `\\`)+`"`)
+		case !strings.Contains(arg, "'"):
+			quoted = append(quoted, "'"+arg+"'")
+		default:
+			return "", fmt.Errorf("argument %q cannot be saved in an alias: it contains both quote characters", arg)
+		}
+	}
+	return strings.Join(quoted, " "), nil
+}
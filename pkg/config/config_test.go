@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -37,6 +39,31 @@ func TestExpandAlias(t *testing.T) {
 			input:    "",
 			expected: []string{},
 		},
+		{
+			name:     "Quoted glob with a space",
+			input:    `-i "my dir/*.go" -e 'old files/**'`,
+			expected: []string{"-i", "my dir/*.go", "-e", "old files/**"},
+		},
+		{
+			name:     "Escaped spaces",
+			input:    `-i my\ dir/*.go -q Focus\ on\ main`,
+			expected: []string{"-i", "my dir/*.go", "-q", "Focus on main"},
+		},
+		{
+			name:     "Escaped quotes and backslashes",
+			input:    `-q it\'s -q "Say \"hi\"" -e back\\slash`,
+			expected: []string{"-q", "it's", "-q", `Say "hi"`, "-e", `back\slash`},
+		},
+		{
+			name:     "Quoted text glued to a word",
+			input:    `-i src/"my dir"/*.go`,
+			expected: []string{"-i", "src/my dir/*.go"},
+		},
+		{
+			name:     "Other backslashes are kept",
+			input:    `-q "match \d+" -q 'C:\temp\"x"' -e a\b`,
+			expected: []string{"-q", `match \d+`, "-q", `C:\temp\"x"`, "-e", `a\b`},
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,7 +103,7 @@ empty_options:
 		t.Fatalf("Failed to write config file: %v", err)
 	}
 
-	aliases, err := parseConfigFile(configPath)
+	aliases, _, err := parseConfigFile(configPath)
 	if err != nil {
 		t.Fatalf("Failed to parse config file: %v", err)
 	}
@@ -176,3 +203,545 @@ common: --role-message "From project"
 		t.Error("Expected 'project_alias' to exist")
 	}
 }
+
+func TestParseReviewPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	planPath := filepath.Join(tmpDir, "review.txt")
+
+	planContent := `# Review plan
+src/**/*.go => Check error handling
+docs/*.md => Is the documentation accurate?
+
+`
+	if err := os.WriteFile(planPath, []byte(planContent), 0644); err != nil {
+		t.Fatalf("Failed to write review plan: %v", err)
+	}
+
+	steps, err := ParseReviewPlan(planPath)
+	if err != nil {
+		t.Fatalf("Failed to parse review plan: %v", err)
+	}
+
+	expected := []ReviewStep{
+		{Glob: "src/**/*.go", Question: "Check error handling", Line: 2},
+		{Glob: "docs/*.md", Question: "Is the documentation accurate?", Line: 3},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %d: %v", len(expected), len(steps), steps)
+	}
+	for i, step := range steps {
+		if step != expected[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i, expected[i], step)
+		}
+	}
+
+	t.Run("Invalid line returns an error", func(t *testing.T) {
+		invalidPath := filepath.Join(tmpDir, "invalid.txt")
+		if err := os.WriteFile(invalidPath, []byte("src/*.go Check this\n"), 0644); err != nil {
+			t.Fatalf("Failed to write review plan: %v", err)
+		}
+		if _, err := ParseReviewPlan(invalidPath); err == nil {
+			t.Error("Expected an error for a line without '=>'")
+		}
+	})
+
+	t.Run("Empty plan returns an error", func(t *testing.T) {
+		emptyPath := filepath.Join(tmpDir, "empty.txt")
+		if err := os.WriteFile(emptyPath, []byte("# only a comment\n"), 0644); err != nil {
+			t.Fatalf("Failed to write review plan: %v", err)
+		}
+		if _, err := ParseReviewPlan(emptyPath); err == nil {
+			t.Error("Expected an error for an empty review plan")
+		}
+	})
+}
+
+func TestParseQuestionSlot(t *testing.T) {
+	tests := []struct {
+		question     string
+		expectedName string
+		expectedText string
+		expectedOK   bool
+	}{
+		{"@slot:focus Focus on error handling", "focus", "Focus on error handling", true},
+		{"@slot:tone", "tone", "", true},
+		{"Regular question", "", "", false},
+		{"@slot: missing name", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			name, text, ok := ParseQuestionSlot(tt.question)
+			if name != tt.expectedName || text != tt.expectedText || ok != tt.expectedOK {
+				t.Errorf("Expected (%q, %q, %v), got (%q, %q, %v)",
+					tt.expectedName, tt.expectedText, tt.expectedOK, name, text, ok)
+			}
+		})
+	}
+}
+
+func TestParseSlotOverride(t *testing.T) {
+	name, text, err := ParseSlotOverride("focus=Look for race conditions = data races")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name != "focus" || text != "Look for race conditions = data races" {
+		t.Errorf("Unexpected override: %q=%q", name, text)
+	}
+
+	if _, _, err := ParseSlotOverride("no separator"); err == nil {
+		t.Error("Expected an error for an override without '='")
+	}
+	if _, _, err := ParseSlotOverride("=text"); err == nil {
+		t.Error("Expected an error for an override without a name")
+	}
+}
+
+func TestReadPatternFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	patternPath := filepath.Join(tmpDir, "patterns.txt")
+
+	content := `# Services
+services/**/*.go
+
+  libs/*  
+# Docs
+docs/*.md
+`
+	if err := os.WriteFile(patternPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	patterns, err := ReadPatternFile(patternPath)
+	if err != nil {
+		t.Fatalf("Failed to read pattern file: %v", err)
+	}
+
+	expected := []string{"services/**/*.go", "libs/*", "docs/*.md"}
+	if len(patterns) != len(expected) {
+		t.Fatalf("Expected %d patterns, got %d: %v", len(expected), len(patterns), patterns)
+	}
+	for i, pattern := range patterns {
+		if pattern != expected[i] {
+			t.Errorf("Pattern %d: expected %q, got %q", i, expected[i], pattern)
+		}
+	}
+
+	if _, err := ReadPatternFile(filepath.Join(tmpDir, "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing pattern file")
+	}
+}
+
+func TestReadQuestionsFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	questionsPath := filepath.Join(tmpDir, "questions.txt")
+
+	content := "Is the error handling consistent?\n---\n\n---\nAre there race conditions?\nCheck the workers.\n  ---  \nIs the API documented?\n"
+	if err := os.WriteFile(questionsPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write questions file: %v", err)
+	}
+
+	questions, err := ReadQuestionsFile(questionsPath, DefaultQuestionsDelimiter)
+	if err != nil {
+		t.Fatalf("Failed to read questions file: %v", err)
+	}
+	expected := []string{
+		"Is the error handling consistent?",
+		"Are there race conditions?\nCheck the workers.",
+		"Is the API documented?",
+	}
+	if strings.Join(questions, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, questions)
+	}
+
+	t.Run("Custom delimiter", func(t *testing.T) {
+		questions, err := ReadQuestionsFile(questionsPath, "===")
+		if err != nil {
+			t.Fatalf("Failed to read questions file: %v", err)
+		}
+		if len(questions) != 1 {
+			t.Errorf("Expected the whole file as one question, got %q", questions)
+		}
+	})
+
+	t.Run("File without questions", func(t *testing.T) {
+		emptyPath := filepath.Join(tmpDir, "empty.txt")
+		if err := os.WriteFile(emptyPath, []byte("---\n\n---\n"), 0644); err != nil {
+			t.Fatalf("Failed to write questions file: %v", err)
+		}
+		if _, err := ReadQuestionsFile(emptyPath, DefaultQuestionsDelimiter); err == nil {
+			t.Error("Expected an error for a file without questions")
+		}
+	})
+}
+
+func TestSerializeOptions(t *testing.T) {
+	args := []string{"-i", "src/**/*.go", "-q", "Review this code", "-q", `Say "hi"`, "-q", "It's fine"}
+
+	options, err := SerializeOptions(args)
+	if err != nil {
+		t.Fatalf("SerializeOptions failed: %v", err)
+	}
+
+	expected := `-i src/**/*.go -q "Review this code" -q 'Say "hi"' -q "It's fine"`
+	if options != expected {
+		t.Errorf("Expected %q, got %q", expected, options)
+	}
+
+	// The serialized options must expand back to the same arguments
+	expanded := ExpandAlias(options)
+	if len(expanded) != len(args) {
+		t.Fatalf("Expected %d args after expansion, got %d: %v", len(args), len(expanded), expanded)
+	}
+	for i, arg := range expanded {
+		if arg != args[i] {
+			t.Errorf("Arg %d: expected %q, got %q", i, args[i], arg)
+		}
+	}
+
+	// Backslashes survive the round trip
+	for _, arg := range []string{`a\\b`, `trailing\`, `C:\dir "x"`, `my dir\`} {
+		options, err := SerializeOptions([]string{arg})
+		if err != nil {
+			t.Fatalf("SerializeOptions(%q) failed: %v", arg, err)
+		}
+		if expanded := ExpandAlias(options); len(expanded) != 1 || expanded[0] != arg {
+			t.Errorf("Expected %q to expand back from %q, got %q", arg, options, expanded)
+		}
+	}
+
+	if _, err := SerializeOptions([]string{"-q", `both " and '`}); err == nil {
+		t.Error("Expected an error for an argument containing both quote characters")
+	}
+}
+
+func TestSaveAlias(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".mpp.txt")
+
+	// The file is created if needed
+	if err := SaveAlias(configPath, "first", "-i *.go"); err != nil {
+		t.Fatalf("SaveAlias failed: %v", err)
+	}
+
+	// Aliases are appended, even if the file does not end with a newline
+	if err := os.WriteFile(configPath, []byte("first: -i *.go"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	if err := SaveAlias(configPath, "second", `-q "Review this"`); err != nil {
+		t.Fatalf("SaveAlias failed: %v", err)
+	}
+
+	aliases, _, err := parseConfigFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to parse config file: %v", err)
+	}
+	if len(aliases) != 2 || aliases[1].Name != "second" || aliases[1].Options != `-q "Review this"` {
+		t.Errorf("Unexpected aliases after saving: %+v", aliases)
+	}
+
+	if err := SaveAlias(configPath, "first", "-i *.md"); err == nil {
+		t.Error("Expected an error when saving an alias that already exists")
+	}
+	if err := SaveAlias(configPath, "bad:name", "-i *.md"); err == nil {
+		t.Error("Expected an error for an invalid alias name")
+	}
+}
+
+func TestLoadAliases_Directives(t *testing.T) {
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "project")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	parentConfig := "@test-patterns: *_test.go\nshared: -i *.md\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".mpp.txt"), []byte(parentConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	childConfig := "@test-patterns: *.spec.js __tests__/\nlocal: -i *.go\n@: missing name\n"
+	if err := os.WriteFile(filepath.Join(subDir, ".mpp.txt"), []byte(childConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWD)
+	if err := os.Chdir(subDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	cfg, err := LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases failed: %v", err)
+	}
+
+	directive, ok := cfg.GetDirective(TestPatternsDirective)
+	if !ok {
+		t.Fatal("Expected the test-patterns directive to be loaded")
+	}
+	if directive.Value != "*.spec.js __tests__/" {
+		t.Errorf("Expected the nearest directive to win, got %q from %s", directive.Value, directive.Source)
+	}
+	if len(cfg.Directives) != 1 {
+		t.Errorf("Expected 1 directive, got %d: %v", len(cfg.Directives), cfg.Directives)
+	}
+
+	// Directives are not aliases
+	if _, exists := cfg.GetAlias("@test-patterns"); exists {
+		t.Error("Expected directives not to be loaded as aliases")
+	}
+	if len(cfg.ListAliases()) != 2 {
+		t.Errorf("Expected 2 aliases, got %v", cfg.ListAliases())
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	profile, err := ParseProfile("review", `role="You are a strict code reviewer" tree=false footer='List issues by severity.'`)
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	if profile.RoleMessage != "You are a strict code reviewer" {
+		t.Errorf("Unexpected role: %q", profile.RoleMessage)
+	}
+	if profile.Footer != "List issues by severity." {
+		t.Errorf("Unexpected footer: %q", profile.Footer)
+	}
+	if profile.IncludeTree == nil || *profile.IncludeTree {
+		t.Errorf("Expected the tree to be disabled, got %v", profile.IncludeTree)
+	}
+
+	profile, err = ParseProfile("docs", `footer="Keep it short."`)
+	if err != nil {
+		t.Fatalf("ParseProfile failed: %v", err)
+	}
+	if profile.IncludeTree != nil || profile.RoleMessage != "" {
+		t.Errorf("Expected unset settings to stay unchanged, got %+v", profile)
+	}
+
+	for _, invalid := range []string{"role", "tree=maybe", "colour=blue"} {
+		if _, err := ParseProfile("bad", invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Directives["profile review"] = Directive{Name: "profile review", Value: `role="Reviewer" tree=false`, Source: ".mpp.txt"}
+	cfg.Directives["profile broken"] = Directive{Name: "profile broken", Value: "tree", Source: ".mpp.txt"}
+	cfg.Directives[TestPatternsDirective] = Directive{Name: TestPatternsDirective, Value: "*_test.go"}
+
+	profile, ok, err := cfg.GetProfile("review")
+	if !ok || err != nil {
+		t.Fatalf("Expected profile review, got ok=%v err=%v", ok, err)
+	}
+	if profile.RoleMessage != "Reviewer" || profile.Source != ".mpp.txt" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+
+	if _, ok, _ := cfg.GetProfile("missing"); ok {
+		t.Error("Expected no profile named missing")
+	}
+	if _, ok, err := cfg.GetProfile("broken"); !ok || err == nil {
+		t.Errorf("Expected an error for the broken profile, got ok=%v err=%v", ok, err)
+	}
+
+	if names := cfg.ListProfiles(); strings.Join(names, ",") != "broken,review" {
+		t.Errorf("Expected profiles broken and review, got %v", names)
+	}
+}
+
+func TestGetRedactPatterns(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Directives["redact-pattern stripe"] = Directive{Name: "redact-pattern stripe", Value: `sk_live_[0-9a-zA-Z]{24}`, Source: ".mpp.txt"}
+	cfg.Directives["redact-pattern internal"] = Directive{Name: "redact-pattern internal", Value: `corp-[0-9]{8}`, Source: ".mpp.txt"}
+	cfg.Directives[DefaultQuestionDirective] = Directive{Name: DefaultQuestionDirective, Value: "Review"}
+
+	patterns, err := cfg.GetRedactPatterns()
+	if err != nil {
+		t.Fatalf("GetRedactPatterns failed: %v", err)
+	}
+	if len(patterns) != 2 || patterns[0].Name != "internal" || patterns[1].Name != "stripe" {
+		t.Fatalf("Expected patterns internal and stripe, got %+v", patterns)
+	}
+	if !patterns[0].Pattern.MatchString("id corp-12345678") {
+		t.Error("Expected the internal pattern to be compiled")
+	}
+
+	cfg.Directives["redact-pattern broken"] = Directive{Name: "redact-pattern broken", Value: `[a-`, Source: ".mpp.txt"}
+	if _, err := cfg.GetRedactPatterns(); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected an error naming the broken pattern, got %v", err)
+	}
+}
+
+func TestLoadAliases_Sources(t *testing.T) {
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "project")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	parentPath := filepath.Join(tmpDir, ".mpp.txt")
+	childPath := filepath.Join(subDir, ".mpp.txt")
+	if err := os.WriteFile(parentPath, []byte("shared: -i *.md\n@default-exclude: vendor\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.WriteFile(childPath, []byte("shared: -i *.go\n@default-exclude: dist\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWD)
+	if err := os.Chdir(subDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	cfg, err := LoadAliases()
+	if err != nil {
+		t.Fatalf("LoadAliases failed: %v", err)
+	}
+
+	// Paths are compared after resolving symlinks, as the temporary directory may be one
+	resolve := func(path string) string {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", path, err)
+		}
+		return resolved
+	}
+	if len(cfg.Files) < 2 || resolve(cfg.Files[0]) != resolve(childPath) || resolve(cfg.Files[1]) != resolve(parentPath) {
+		t.Errorf("Expected %s then %s, got %v", childPath, parentPath, cfg.Files)
+	}
+
+	if len(cfg.Suppressed) != 2 {
+		t.Fatalf("Expected 2 suppressed definitions, got %v", cfg.Suppressed)
+	}
+	for i, kind := range []string{"alias", "directive"} {
+		suppressed := cfg.Suppressed[i]
+		if suppressed.Kind != kind || resolve(suppressed.Source) != resolve(parentPath) || resolve(suppressed.KeptSource) != resolve(childPath) {
+			t.Errorf("Expected the %s of %s to be suppressed in favor of %s, got %+v", kind, parentPath, childPath, suppressed)
+		}
+	}
+}
+
+func TestLoadAliasesUpTo(t *testing.T) {
+	tmpDir := t.TempDir()
+	homeDir := filepath.Join(tmpDir, "home")
+	repoDir := filepath.Join(tmpDir, "work", "repo")
+	subDir := filepath.Join(repoDir, "src")
+	for _, dir := range []string{homeDir, subDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	t.Setenv("HOME", homeDir)
+
+	configs := map[string]string{
+		homeDir:                       "global: -i *.md\n",
+		filepath.Join(tmpDir, "work"): "outside: -i *.txt\n",
+		repoDir:                       "repo: -i *.go\n",
+	}
+	for dir, content := range configs {
+		if err := os.WriteFile(filepath.Join(dir, ".mpp.txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWD)
+	if err := os.Chdir(subDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	t.Run("Stops at the given directory, then loads the global config", func(t *testing.T) {
+		cfg, err := LoadAliasesUpTo(repoDir)
+		if err != nil {
+			t.Fatalf("LoadAliasesUpTo failed: %v", err)
+		}
+		if _, exists := cfg.GetAlias("outside"); exists {
+			t.Error("Expected the config above the stop directory to be skipped")
+		}
+		for _, name := range []string{"repo", "global"} {
+			if _, exists := cfg.GetAlias(name); !exists {
+				t.Errorf("Expected alias '%s' to be loaded", name)
+			}
+		}
+		if len(cfg.Files) != 2 || filepath.Dir(cfg.Files[1]) != homeDir {
+			t.Errorf("Expected the global config to be loaded last, got %v", cfg.Files)
+		}
+	})
+
+	t.Run("Walks to the root without a stop directory", func(t *testing.T) {
+		cfg, err := LoadAliasesUpTo("")
+		if err != nil {
+			t.Fatalf("LoadAliasesUpTo failed: %v", err)
+		}
+		if _, exists := cfg.GetAlias("outside"); !exists {
+			t.Error("Expected the config above the repository to be loaded")
+		}
+		if _, exists := cfg.GetAlias("global"); exists {
+			t.Error("Expected the global config not to be loaded outside of the walk")
+		}
+	})
+}
+
+func TestReadGitignorePatterns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exclude")
+	content := "# git ls-files --others --exclude-from=.git/info/exclude\n\n*.log\nscratch/\n/notes.md\ndocs/drafts/  \n!keep.log\n\\#odd\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write ignore file: %v", err)
+	}
+
+	patterns, err := ReadGitignorePatterns(path)
+	if err != nil {
+		t.Fatalf("ReadGitignorePatterns failed: %v", err)
+	}
+	expected := GitignorePatterns{
+		Excludes:  []string{"**/*.log", "notes.md", "docs/drafts", "**/#odd"},
+		DirNames:  []string{"*.log", "scratch", "#odd"},
+		Negations: []string{"!keep.log"},
+	}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, patterns)
+	}
+
+	if _, err := ReadGitignorePatterns(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestGitignorePatternsRelativeTo(t *testing.T) {
+	patterns := GitignorePatterns{
+		Excludes: []string{"**/*.log", "src/test", "build", "*/generated", "src/**/tmp"},
+		DirNames: []string{"*.log", "scratch"},
+	}
+
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"", []string{"**/*.log", "src/test", "build", "*/generated", "src/**/tmp"}},
+		{"src/", []string{"**/*.log", "test", "generated", "**/tmp"}},
+		{"docs/", []string{"**/*.log", "generated"}},
+		{"src/test/", []string{"**/*.log", "**/tmp", "**"}},
+		{"scratch/notes/", []string{"**/*.log", "**"}},
+	}
+	for _, tc := range tests {
+		relative := patterns.RelativeTo(tc.prefix)
+		if !reflect.DeepEqual(relative.Excludes, tc.expected) {
+			t.Errorf("RelativeTo(%q): expected %v, got %v", tc.prefix, tc.expected, relative.Excludes)
+		}
+		if !reflect.DeepEqual(relative.DirNames, patterns.DirNames) {
+			t.Errorf("RelativeTo(%q): expected the directory names to be kept, got %v", tc.prefix, relative.DirNames)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProfileDirective is the directive defining a named prompt preset:
+// "@profile name: role=\"...\" tree=false footer=\"...\""
+const ProfileDirective = "profile"
+
+// Profile is a named preset of generator settings. Unlike an alias, it sets how the
+// prompt is written rather than which files are selected.
+type Profile struct {
+	Name        string
+	RoleMessage string // Text placed at the very top of the prompt ("" = unchanged)
+	Footer      string // Text placed at the very end of the prompt ("" = unchanged)
+	IncludeTree *bool  // Whether the project tree is included (nil = unchanged)
+	Source      string // Path to the config file where this profile was defined
+}
+
+// GetProfile retrieves a profile by name and parses its settings
+func (c *Config) GetProfile(name string) (Profile, bool, error) {
+	directive, exists := c.Directives[ProfileDirective+" "+name]
+	if !exists {
+		return Profile{}, false, nil
+	}
+	profile, err := ParseProfile(name, directive.Value)
+	if err != nil {
+		return Profile{}, true, fmt.Errorf("profile '%s' in %s: %w", name, directive.Source, err)
+	}
+	profile.Source = directive.Source
+	return profile, true, nil
+}
+
+// ListProfiles returns the names of the defined profiles, sorted
+func (c *Config) ListProfiles() []string {
+	var names []string
+	for name := range c.Directives {
+		if profileName, ok := strings.CutPrefix(name, ProfileDirective+" "); ok {
+			names = append(names, profileName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseProfile parses the "key=value" settings of a profile. Values containing spaces
+// must be quoted. Supported keys are role, footer, and tree.
+func ParseProfile(name, value string) (Profile, error) {
+	profile := Profile{Name: name}
+	for _, setting := range ExpandAlias(value) {
+		key, val, ok := strings.Cut(setting, "=")
+		if !ok {
+			return Profile{}, fmt.Errorf("invalid setting %q (expected key=value)", setting)
+		}
+		switch key {
+		case "role":
+			profile.RoleMessage = val
+		case "footer":
+			profile.Footer = val
+		case "tree":
+			includeTree, err := strconv.ParseBool(val)
+			if err != nil {
+				return Profile{}, fmt.Errorf("invalid tree value %q (expected true or false)", val)
+			}
+			profile.IncludeTree = &includeTree
+		default:
+			return Profile{}, fmt.Errorf("unknown setting %q (expected role, footer, or tree)", key)
+		}
+	}
+	return profile, nil
+}
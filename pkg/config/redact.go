@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactPatternDirective is the directive defining an extra secret pattern, scanned by
+// --scan-secrets and masked by --redact: "@redact-pattern name: regexp"
+const RedactPatternDirective = "redact-pattern"
+
+// RedactPattern is a named regular expression matching secrets specific to a project
+type RedactPattern struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Source  string // Path to the config file where this pattern was defined
+}
+
+// GetRedactPatterns compiles the redact patterns, sorted by name
+func (c *Config) GetRedactPatterns() ([]RedactPattern, error) {
+	var patterns []RedactPattern
+	for key, directive := range c.Directives {
+		name, ok := strings.CutPrefix(key, RedactPatternDirective+" ")
+		if !ok {
+			continue
+		}
+		pattern, err := regexp.Compile(directive.Value)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern '%s' in %s: %w", name, directive.Source, err)
+		}
+		patterns = append(patterns, RedactPattern{Name: name, Pattern: pattern, Source: directive.Source})
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].Name < patterns[j].Name })
+	return patterns, nil
+}
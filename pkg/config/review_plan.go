@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// reviewPlanSeparator separates the glob from its question in a review plan line
+const reviewPlanSeparator = "=>"
+
+// ReviewStep pairs a glob with the question asked about the files it matches
+type ReviewStep struct {
+	Glob     string
+	Question string
+	Line     int // Line number in the review plan file
+}
+
+// ParseReviewPlan parses a review plan file where each line has the format "glob => question".
+// Empty lines and lines starting with # are ignored.
+func ParseReviewPlan(path string) ([]ReviewStep, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var steps []ReviewStep
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, reviewPlanSeparator, 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid review plan entry at %s:%d (expected format 'glob => question')", path, lineNum)
+		}
+
+		glob := strings.TrimSpace(parts[0])
+		question := strings.TrimSpace(parts[1])
+		if glob == "" {
+			return nil, fmt.Errorf("empty glob at %s:%d", path, lineNum)
+		}
+		if question == "" {
+			return nil, fmt.Errorf("empty question at %s:%d", path, lineNum)
+		}
+
+		steps = append(steps, ReviewStep{
+			Glob:     glob,
+			Question: question,
+			Line:     lineNum,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("review plan %s contains no entries", path)
+	}
+
+	return steps, nil
+}
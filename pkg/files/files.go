@@ -3,13 +3,22 @@
 package files
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"mime"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
 )
 
 // FileInfo represents information about a file
@@ -18,39 +27,112 @@ type FileInfo struct {
 	IsText    bool
 	IsForced  bool
 	Size      int64
+	ModTime   time.Time
 	IsRegular bool
+	Language  string // Detected language name ("" if unknown)
+
+	MatchedPattern string // The -i, -f or --include-ignored pattern that selected the file ("" if none was given)
+	AutoIncluded   bool   // Added by AutoReadme or AutoDocs, not selected by any pattern
+	Content        []byte // Content already read while classifying the file (nil = read it when needed)
 }
 
+// DefaultMinifiedLineLength is the average line length above which a file is considered minified
+const DefaultMinifiedLineLength = 500
+
+// textSniffSize is the number of bytes inspected when sniffing file content
+const textSniffSize = 512
+
+// maxNonPrintableRatio is the share of non-printable bytes above which content is considered binary
+const maxNonPrintableRatio = 0.1
+
+// minifiedSampleSize is the number of bytes read to estimate the average line length
+const minifiedSampleSize = 64 * 1024
+
 // Config holds configuration for file operations
 type Config struct {
-	IncludePatterns      []string
-	ExcludePatterns      []string
-	ForceIncludePatterns []string
+	IncludePatterns        []string
+	ExcludePatterns        []string
+	ExcludeDirs            []string // Directory names (globs) excluding every non-forced path under such a directory, at any depth
+	ForceIncludePatterns   []string
+	StrictText             bool     // Always sniff file content, rejecting binary-looking files regardless of extension
+	OnlyConflicts          bool     // Keep only non-forced files containing git conflict markers
+	WarnConflicts          bool     // Warn about files containing git conflict markers
+	SkipMinified           bool     // Exclude non-forced files that look minified
+	MinifiedLineLength     int      // Average line length threshold for SkipMinified (0 = DefaultMinifiedLineLength)
+	IncludeIgnoredPatterns []string // Git-ignored files to include, unlike ForceIncludePatterns still filtered
+	TrackedOnly            bool     // List only the files tracked by Git, leaving out the untracked ones
+	UntrackedOnly          bool     // List only the untracked (and not ignored) files, leaving out the tracked ones
+	AutoReadme             bool     // Add the top-level README.* files regardless of the include and exclude patterns
+	AutoDocs               bool     // Add the docs/*.md files regardless of the include and exclude patterns
+	ExcludeLargerThan      int64    // Exclude non-forced files larger than this many bytes (0 = no limit)
+	Explain                bool     // Report on stderr why each file is skipped
+	ExcludeTests           bool     // Exclude non-forced files matching the test patterns
+	ExcludeHidden          bool     // Exclude non-forced files with a path component starting with a dot (e.g. .env, .github/)
+	LimitDepth             bool     // Exclude non-forced files more than MaxDepth directories deep
+	IgnoreCase             bool     // Match the include, exclude, and force include patterns case-insensitively
+	MaxDepth               int      // Directory depth kept with LimitDepth (0 = files at the top level only)
+	TestPatterns           []string // Test patterns for ExcludeTests (nil = DefaultTestPatterns)
+	FollowSymlinks         bool     // Read symlinked files through their target instead of skipping them
+	IncludeGenerated       bool     // Keep non-forced files marked linguist-generated in .gitattributes
+	GitAttributesFile      string   // .gitattributes file marking generated files ("" = DefaultGitAttributesFile)
+	TextExtensions         []string // Extensions (e.g. ".foo") always treated as text, still subject to the size checks
+	Languages              []string // Keep only the non-forced files detected as one of these languages (nil = any)
+	Timings                *Timings // Accumulates the time spent listing and filtering files (nil = not measured)
+
+	report io.Writer // Where warnings and explanations about a file being enriched go (nil = stderr)
+}
+
+// reportf writes a warning or explanation about a file. Files enriched concurrently each
+// report to their own buffer, so that these messages can be printed in file order.
+func (c Config) reportf(format string, args ...interface{}) {
+	w := c.report
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
+// Timings accumulates the time spent in the phases of the file selection, over one or more listings
+type Timings struct {
+	Listing   time.Duration // Listing the candidate paths with git
+	Filtering time.Duration // Matching the patterns and classifying the candidates
+}
+
+// addListing adds the time elapsed since start to the listing phase, when timings are measured
+func (t *Timings) addListing(start time.Time) {
+	if t != nil {
+		t.Listing += time.Since(start)
+	}
+}
+
+// addFiltering adds the time elapsed since start to the filtering phase, when timings are measured
+func (t *Timings) addFiltering(start time.Time) {
+	if t != nil {
+		t.Filtering += time.Since(start)
+	}
+}
+
+// DefaultTestPatterns lists the test file conventions excluded by ExcludeTests.
+// Patterns ending with / match a directory anywhere in the path, others match the file name.
+var DefaultTestPatterns = []string{
+	"*_test.go",
+	"*.test.*",
+	"*.spec.*",
+	"test_*.py",
+	"*_test.py",
+	"*_spec.rb",
+	"__tests__/",
+	"test/",
+	"tests/",
 }
 
 // ListGitFiles returns a list of files tracked by Git.
 // It is now much simpler. It only gets the list, it does not filter it.
 func ListGitFiles(config Config) ([]FileInfo, error) {
-	// Base command to get all tracked files
-	args := []string{"ls-files", "-co", "--exclude-standard", "--"}
-
-	// Run the git command to get all tracked files
-	cmd := exec.Command("git", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+	start := time.Now()
+	fileList, err := listCandidatePaths(config)
 	if err != nil {
-		if stderr.Len() > 0 {
-			return nil, fmt.Errorf("failed to run git ls-files: %s: %w", strings.TrimSpace(stderr.String()), err)
-		}
-		return nil, fmt.Errorf("failed to run git ls-files: %w", err)
-	}
-
-	output := strings.TrimSpace(stdout.String())
-	var fileList []string
-	if output != "" {
-		fileList = strings.Split(output, "\n")
+		return nil, err
 	}
 
 	// If we have force include patterns, we need to find files matching those patterns
@@ -62,25 +144,41 @@ func ListGitFiles(config Config) ([]FileInfo, error) {
 			fileSet[file] = true
 		}
 
-		// For each force include pattern, find matching files on disk
-		for _, pattern := range config.ForceIncludePatterns {
-			// Check if pattern contains glob metacharacters
-			hasGlobChars := strings.ContainsAny(pattern, "*?[]")
-
-			if !hasGlobChars {
-				// Pattern is a literal file path - check if it exists
-				if _, err := os.Stat(pattern); err == nil && !fileSet[pattern] {
-					fileList = append(fileList, pattern)
-					fileSet[pattern] = true
+		if config.IgnoreCase {
+			// The files on disk missing from the listing are the ignored ones: match them
+			// with the patterns, as looking them up by name would be case-sensitive
+			ignoredList, err := listIgnoredPaths()
+			if err != nil {
+				return nil, err
+			}
+			forceIncludes := compilePatternSet(config.ForceIncludePatterns, true)
+			for _, file := range ignoredList {
+				if !fileSet[file] && forceIncludes.matches(file) {
+					fileList = append(fileList, file)
+					fileSet[file] = true
 				}
-			} else {
-				// Pattern contains glob metacharacters - expand it
-				matches, err := filepath.Glob(pattern)
-				if err == nil {
-					for _, match := range matches {
-						if !fileSet[match] {
-							fileList = append(fileList, match)
-							fileSet[match] = true
+			}
+		} else {
+			// For each force include pattern, find matching files on disk
+			for _, pattern := range config.ForceIncludePatterns {
+				// Check if pattern contains glob metacharacters
+				hasGlobChars := strings.ContainsAny(pattern, "*?[]")
+
+				if !hasGlobChars {
+					// Pattern is a literal file path - check if it exists
+					if _, err := os.Stat(pattern); err == nil && !fileSet[pattern] {
+						fileList = append(fileList, pattern)
+						fileSet[pattern] = true
+					}
+				} else {
+					// Pattern contains glob metacharacters - expand it
+					matches, err := filepath.Glob(pattern)
+					if err == nil {
+						for _, match := range matches {
+							if !fileSet[match] {
+								fileList = append(fileList, match)
+								fileSet[match] = true
+							}
 						}
 					}
 				}
@@ -88,183 +186,830 @@ func ListGitFiles(config Config) ([]FileInfo, error) {
 		}
 	}
 
+	// Ignored files matching --include-ignored patterns become candidates, still subject
+	// to the normal text and size filtering
+	if len(config.IncludeIgnoredPatterns) > 0 {
+		ignoredList, err := listIgnoredPaths()
+		if err != nil {
+			return nil, err
+		}
+		fileSet := make(map[string]bool, len(fileList))
+		for _, file := range fileList {
+			fileSet[file] = true
+		}
+		includeIgnored := compilePatternSet(config.IncludeIgnoredPatterns, config.IgnoreCase)
+		for _, file := range ignoredList {
+			if !fileSet[file] && includeIgnored.matches(file) {
+				fileList = append(fileList, file)
+				fileSet[file] = true
+			}
+		}
+	}
+
+	config.Timings.addListing(start)
+
 	// The ALL-IMPORTANT change: We now pass the full list to our pure filter function.
 	return filterAndEnrichFiles(fileList, config)
 }
 
-// matchesPattern checks if a file path matches a pattern (supports glob patterns including **)
-func matchesPattern(file, pattern string) bool {
-	// First try exact match
-	if file == pattern {
-		return true
+// ListExplicitFiles returns the FileInfo of an exact list of paths, bypassing the Git listing
+// and the include patterns. Exclude patterns and the content checks still apply.
+// Every path must exist and be a file.
+func ListExplicitFiles(paths []string, config Config) ([]FileInfo, error) {
+	seen := make(map[string]bool, len(paths))
+	var fileList []string
+	for _, path := range paths {
+		path = filepath.ToSlash(filepath.Clean(path))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("listed file '%s' does not exist: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("listed path '%s' is a directory", path)
+		}
+		if !seen[path] {
+			seen[path] = true
+			fileList = append(fileList, path)
+		}
 	}
 
-	// Handle ** patterns for recursive directory matching
-	if strings.Contains(pattern, "**") {
-		return matchesRecursivePattern(file, pattern)
+	config.IncludePatterns = nil
+	config.ForceIncludePatterns = nil
+	config.IncludeIgnoredPatterns = nil
+	return filterAndEnrichFiles(fileList, config)
+}
+
+// ReadPathList reads newline-separated paths. Paths are taken verbatim: only empty
+// lines and carriage returns are dropped.
+func ReadPathList(r io.Reader) ([]string, error) {
+	var paths []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		paths = append(paths, line)
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
 
-	// For simple patterns without **, use filepath.Match
-	matched, err := filepath.Match(pattern, file)
+// ReadNulPathList reads NUL-separated paths, as written by find -print0 or git ls-files -z.
+// Paths are taken verbatim, newlines included: only empty entries are dropped.
+func ReadNulPathList(r io.Reader) ([]string, error) {
+	content, err := io.ReadAll(r)
 	if err != nil {
-		// Log error to stderr but don't fail the match
-		fmt.Fprintf(os.Stderr, "Warning: Invalid pattern %q: %v\n", pattern, err)
-		return false
+		return nil, err
 	}
-	return matched
+	var paths []string
+	for _, path := range strings.Split(string(content), "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// listGitPaths returns the paths of tracked and untracked (but not ignored) files
+func listGitPaths() ([]string, error) {
+	return runGitLsFiles("-co", "--exclude-standard")
+}
+
+// listCandidatePaths returns the Git-listed paths, restricted to the tracked or the untracked
+// files with TrackedOnly or UntrackedOnly
+func listCandidatePaths(config Config) ([]string, error) {
+	switch {
+	case config.TrackedOnly:
+		return runGitLsFiles("-c")
+	case config.UntrackedOnly:
+		return runGitLsFiles("-o", "--exclude-standard")
+	}
+	return listGitPaths()
+}
+
+// listIgnoredPaths returns the paths of untracked files ignored by the standard Git ignore rules
+func listIgnoredPaths() ([]string, error) {
+	return runGitLsFiles("-o", "-i", "--exclude-standard")
+}
+
+// runGitLsFiles runs git ls-files with the given options and returns the listed paths
+func runGitLsFiles(options ...string) ([]string, error) {
+	return runGitLsFilesIn("", options...)
+}
+
+// runGitLsFilesIn runs git ls-files in a directory ("" = the working directory) and returns
+// the listed paths, relative to that directory. Paths are listed NUL-separated so they are
+// never quoted, whatever the core.quotePath setting.
+func runGitLsFilesIn(dir string, options ...string) ([]string, error) {
+	args := append([]string{"ls-files", "-z"}, options...)
+	args = append(args, "--")
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("failed to run git ls-files: %s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return nil, fmt.Errorf("failed to run git ls-files: %w", err)
+	}
+
+	output := strings.TrimSuffix(stdout.String(), "\x00")
+	var fileList []string
+	if output != "" {
+		fileList = strings.Split(output, "\x00")
+	}
+
+	return fileList, nil
 }
 
-// matchesRecursivePattern handles patterns with ** (recursive directory matching)
-// It properly handles multiple ** segments in a pattern
-func matchesRecursivePattern(file, pattern string) bool {
-	// Split pattern by /** to handle patterns like "src/**/*.go"
-	// This handles the most common case: prefix/**/suffix
-	parts := strings.Split(pattern, "**")
+// compiledPattern is a glob pattern prepared once so it can be matched against many paths
+type compiledPattern struct {
+	pattern   string
+	literal   bool   // No glob metacharacters: only an exact match is possible
+	recursive bool   // Contains ** for recursive directory matching
+	valid     bool   // Whether the pattern is valid for filepath.Match
+	prefix    string // For recursive patterns: the directory before the first **
+	suffix    string // For recursive patterns: the glob after the last **
+}
 
-	// Get prefix (before first **)
-	prefix := parts[0]
-	prefix = strings.TrimSuffix(prefix, "/")
+// compilePattern prepares a pattern for matching. Invalid patterns are reported once.
+func compilePattern(pattern string) compiledPattern {
+	p := compiledPattern{
+		pattern:   pattern,
+		literal:   !strings.ContainsAny(pattern, "*?[\\"),
+		recursive: strings.Contains(pattern, "**"),
+		valid:     true,
+	}
 
+	if p.recursive {
+		// Split pattern by ** to handle patterns like "src/**/*.go"
+		// This handles the most common case: prefix/**/suffix
+		parts := strings.Split(pattern, "**")
+		p.prefix = strings.TrimSuffix(parts[0], "/")
+		p.suffix = strings.TrimPrefix(parts[len(parts)-1], "/")
+	} else if !p.literal {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			// Log error to stderr but don't fail the match
+			fmt.Fprintf(os.Stderr, "Warning: Invalid pattern %q: %v\n", pattern, err)
+			p.valid = false
+		}
+	}
+
+	return p
+}
+
+// match checks if a file path matches the pattern
+func (p compiledPattern) match(file string) bool {
+	// First try exact match
+	if file == p.pattern {
+		return true
+	}
+	if p.literal || !p.valid {
+		return false
+	}
+
+	// Handle ** patterns for recursive directory matching
+	if p.recursive {
+		return p.matchRecursive(file)
+	}
+
+	// For simple patterns without **, use filepath.Match
+	matched, err := filepath.Match(p.pattern, file)
+	return err == nil && matched
+}
+
+// matchRecursive handles patterns with ** (recursive directory matching)
+func (p compiledPattern) matchRecursive(file string) bool {
 	// Check if file starts with prefix
-	if prefix != "" {
-		if !strings.HasPrefix(file, prefix) {
+	if p.prefix != "" {
+		if !strings.HasPrefix(file, p.prefix) {
 			return false
 		}
 		// Remove prefix from file for further matching
-		if len(file) > len(prefix) && file[len(prefix)] == '/' {
-			file = file[len(prefix)+1:]
-		} else if len(file) == len(prefix) {
+		if len(file) > len(p.prefix) && file[len(p.prefix)] == '/' {
+			file = file[len(p.prefix)+1:]
+		} else if len(file) == len(p.prefix) {
 			file = ""
 		} else {
 			return false
 		}
 	}
 
-	// Get suffix (after last **)
-	suffix := parts[len(parts)-1]
-	suffix = strings.TrimPrefix(suffix, "/")
-
 	// If no suffix, pattern ends with **, match everything
-	if suffix == "" {
+	if p.suffix == "" {
 		return true
 	}
 
-	// Try to match suffix against the remaining path or any sub-path
+	// Try to match suffix against the remaining path and every sub-path
 	// This allows ** to match zero or more directory levels
-	matched, err := filepath.Match(suffix, file)
-	if err == nil && matched {
-		return true
-	}
-
-	// Check all sub-paths
-	pathParts := strings.Split(file, "/")
-	for i := range pathParts {
-		subPath := strings.Join(pathParts[i:], "/")
-		matched, err := filepath.Match(suffix, subPath)
+	for {
+		matched, err := filepath.Match(p.suffix, file)
 		if err == nil && matched {
 			return true
 		}
+		slash := strings.IndexByte(file, '/')
+		if slash == -1 {
+			return false
+		}
+		file = file[slash+1:]
 	}
+}
 
-	return false
+// patternSet is a list of compiled patterns, with literal paths looked up in a map.
+// With ignoreCase, the patterns are compiled lowercased and matched against lowercased paths.
+type patternSet struct {
+	literals   map[string]string // Literal path, to the pattern as given
+	globs      []compiledPattern
+	given      []string // Globs as given, reported by matchingPattern
+	ignoreCase bool
+}
+
+// compilePatternSet compiles patterns for repeated matching, case-insensitively with ignoreCase
+func compilePatternSet(patterns []string, ignoreCase bool) patternSet {
+	set := patternSet{literals: make(map[string]string), ignoreCase: ignoreCase}
+	for _, pattern := range patterns {
+		p := compilePattern(set.fold(pattern))
+		if p.literal {
+			set.literals[p.pattern] = pattern
+		} else {
+			set.globs = append(set.globs, p)
+			set.given = append(set.given, pattern)
+		}
+	}
+	return set
+}
+
+// fold lowercases a pattern or path when the set ignores case
+func (s patternSet) fold(text string) string {
+	if s.ignoreCase {
+		return strings.ToLower(text)
+	}
+	return text
+}
+
+// matches checks if a file path matches any pattern of the set
+func (s patternSet) matches(file string) bool {
+	_, matched := s.matchingPattern(file)
+	return matched
+}
+
+// matchingPattern returns the first pattern of the set matching a file path, as given
+func (s patternSet) matchingPattern(file string) (string, bool) {
+	file = s.fold(file)
+	if pattern, ok := s.literals[file]; ok {
+		return pattern, true
+	}
+	for i, p := range s.globs {
+		if p.match(file) {
+			return s.given[i], true
+		}
+	}
+	return "", false
+}
+
+// isEmpty reports whether the set holds no pattern
+func (s patternSet) isEmpty() bool {
+	return len(s.literals) == 0 && len(s.globs) == 0
+}
+
+// fileCandidate is a path selected by the patterns, waiting to be enriched with file information
+type fileCandidate struct {
+	path           string
+	isForced       bool
+	matchedPattern string
+	autoIncluded   bool
 }
 
 // filterAndEnrichFiles applies include, exclude, and force include patterns to the file list
 // Note: Patterns support glob matching including ** for recursive directory matching
 func filterAndEnrichFiles(files []string, config Config) ([]FileInfo, error) {
+	defer config.Timings.addFiltering(time.Now())
+	candidates := selectFiles(files, config)
+
+	// Files marked generated in .gitattributes are an implicit exclude source
+	if !config.IncludeGenerated {
+		path := config.GitAttributesFile
+		if path == "" {
+			path = DefaultGitAttributesFile
+		}
+		rules, err := readGeneratedRules(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		candidates = dropGenerated(candidates, rules, config)
+	}
+
+	return enrichConcurrently(len(candidates), config, func(i int, config Config) (FileInfo, bool) {
+		return enrichFile(candidates[i], config)
+	}), nil
+}
+
+// enrichConcurrently classifies n candidates with enrich concurrently: this is dominated by
+// I/O and possibly by 'file' or 'git' command invocations, while the order must be preserved.
+// Each call gets config with its own report buffer. Only the candidates enrich keeps are returned.
+func enrichConcurrently(n int, config Config, enrich func(i int, config Config) (FileInfo, bool)) []FileInfo {
+	infos := make([]FileInfo, n)
+	kept := make([]bool, n)
+	reports := make([]bytes.Buffer, n)
+
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fileConfig := config
+				fileConfig.report = &reports[i]
+				infos[i], kept[i] = enrich(i, fileConfig)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Print the messages in file order rather than in completion order
+	for i := range reports {
+		os.Stderr.Write(reports[i].Bytes())
+	}
+
 	var result []FileInfo
+	for i, info := range infos {
+		if kept[i] {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// selectFiles applies the include, exclude, and force include patterns to the file list
+func selectFiles(files []string, config Config) []fileCandidate {
+	var candidates []fileCandidate
+
+	// Patterns are compiled once for the whole list
+	includes := compilePatternSet(config.IncludePatterns, config.IgnoreCase)
+	includeIgnored := compilePatternSet(config.IncludeIgnoredPatterns, config.IgnoreCase)
+	forceIncludes := compilePatternSet(config.ForceIncludePatterns, config.IgnoreCase)
+
+	// Normalize exclusion patterns by removing any trailing slash for consistent matching
+	normalizedExcludes := make([]string, 0, len(config.ExcludePatterns))
+	excludedDirs := make([]string, 0, len(config.ExcludePatterns))
+	for _, excludePattern := range config.ExcludePatterns {
+		normalizedPattern := strings.TrimSuffix(excludePattern, "/")
+		normalizedExcludes = append(normalizedExcludes, normalizedPattern)
+		excludedDirs = append(excludedDirs, normalizedPattern+"/")
+	}
+	excludes := compilePatternSet(normalizedExcludes, config.IgnoreCase)
+	excludeDirs := compileDirNames(config.ExcludeDirs, config.IgnoreCase)
 
-	// This is the new, correct filtering logic
-	hasIncludeFilters := len(config.IncludePatterns) > 0
-	hasForceIncludeFilters := len(config.ForceIncludePatterns) > 0
+	var tests testPatternSet
+	if config.ExcludeTests {
+		testPatterns := config.TestPatterns
+		if testPatterns == nil {
+			testPatterns = DefaultTestPatterns
+		}
+		tests = compileTestPatterns(testPatterns)
+	}
+
+	hasIncludeFilters := !includes.isEmpty()
+	hasForceIncludeFilters := !forceIncludes.isEmpty()
 
 	for _, file := range files {
 		// A file is included if:
 		// 1. It's force included, OR
 		// 2. It matches an include pattern (if include patterns exist), OR
-		// 3. No include patterns AND no force include patterns exist (default include all)
-		isIncluded := false
-		isForced := false
-
-		// Check force include patterns first
-		for _, pattern := range config.ForceIncludePatterns {
-			if matchesPattern(file, pattern) {
-				isForced = true
-				isIncluded = true
-				break
-			}
-		}
+		// 3. No include patterns AND no force include patterns exist (default include all), OR
+		// 4. It matches an include ignored pattern
+		matchedPattern, isForced := forceIncludes.matchingPattern(file)
+		isIncluded := isForced
 
 		if !isForced {
 			if hasIncludeFilters {
 				// If -i flags exist, a file must match one of them.
-				for _, pattern := range config.IncludePatterns {
-					if matchesPattern(file, pattern) {
-						isIncluded = true
-						break
-					}
-				}
-			} else if !hasIncludeFilters && !hasForceIncludeFilters {
+				matchedPattern, isIncluded = includes.matchingPattern(file)
+			} else if !hasForceIncludeFilters {
 				// If NO -i and NO -f flags are given, include everything by default.
 				isIncluded = true
 			}
 		}
 
+		// Files matching --include-ignored patterns are included as well
+		if !isIncluded {
+			matchedPattern, isIncluded = includeIgnored.matchingPattern(file)
+		}
+
+		// The README and docs are added as context, whatever the patterns
+		if !isIncluded && isAutoContext(file, config) {
+			candidates = append(candidates, fileCandidate{path: file, autoIncluded: true})
+			continue
+		}
+
 		// If not included, skip this file
 		if !isIncluded {
+			if hasIncludeFilters {
+				explainSkip(config, file, "no include pattern matched")
+			} else {
+				explainSkip(config, file, "no force include pattern matched")
+			}
 			continue
 		}
 
 		// Check for exclusion (but not if force included)
 		if !isForced {
-			excluded := false
-			for _, excludePattern := range config.ExcludePatterns {
-				// Normalize pattern by removing any trailing slash for consistent matching
-				normalizedPattern := strings.TrimSuffix(excludePattern, "/")
-				// Check for exact match, glob match, OR if the file is within an excluded directory
-				if matchesPattern(file, normalizedPattern) || strings.HasPrefix(file, normalizedPattern+"/") {
-					excluded = true
-					break // An exclusion match was found
-				}
+			if pattern, excluded := excludingPattern(file, excludes, excludedDirs); excluded {
+				explainSkip(config, file, "excluded by pattern '%s'", pattern)
+				continue
 			}
+			if dir, excluded := excludeDirs.matchingDir(file); excluded {
+				explainSkip(config, file, "under excluded directory '%s'", dir)
+				continue
+			}
+		}
 
-			if excluded {
+		// Check for test files (but not if force included)
+		if !isForced && config.ExcludeTests {
+			if pattern, isTest := tests.matchingPattern(file); isTest {
+				explainSkip(config, file, "test file matching '%s'", pattern)
 				continue
 			}
 		}
 
-		// Get file info
-		fileInfo, err := os.Stat(file)
-		if err != nil {
-			// Skip files that can't be stat'd
-			fmt.Fprintf(os.Stderr, "Warning: Cannot stat file '%s': %v. Skipping.\n", file, err)
-			continue
+		// Check for hidden files and directories (but not if force included)
+		if !isForced && config.ExcludeHidden {
+			if component, hidden := hiddenComponent(file); hidden {
+				explainSkip(config, file, "hidden path component '%s' (--no-hidden)", component)
+				continue
+			}
 		}
 
-		// Create FileInfo struct
-		info := FileInfo{
-			Path:      file,
-			IsForced:  isForced,
-			Size:      fileInfo.Size(),
-			IsRegular: fileInfo.Mode().IsRegular(),
+		// Check the directory depth (but not if force included)
+		if !isForced && config.LimitDepth {
+			if depth := strings.Count(file, "/"); depth > config.MaxDepth {
+				explainSkip(config, file, "%d directories deep, deeper than %d", depth, config.MaxDepth)
+				continue
+			}
 		}
 
-		// Only check if it's a text file if it's not force included
-		if !isForced {
-			info.IsText = IsTextFile(file)
-			// Skip non-text files unless forced
-			if !info.IsText {
+		candidates = append(candidates, fileCandidate{path: file, isForced: isForced, matchedPattern: matchedPattern})
+	}
+
+	return candidates
+}
+
+// hiddenComponent returns the first component of a path starting with a dot, if any
+func hiddenComponent(file string) (string, bool) {
+	for _, component := range strings.Split(file, "/") {
+		if strings.HasPrefix(component, ".") && component != "." && component != ".." {
+			return component, true
+		}
+	}
+	return "", false
+}
+
+// isAutoContext reports whether a file is added as context by AutoReadme (a top-level
+// README, with or without extension) or AutoDocs (a Markdown file directly under docs/)
+func isAutoContext(file string, config Config) bool {
+	if config.AutoReadme && !strings.Contains(file, "/") {
+		name := strings.ToUpper(file)
+		if name == "README" || strings.HasPrefix(name, "README.") {
+			return true
+		}
+	}
+	if doc, ok := strings.CutPrefix(file, "docs/"); ok && config.AutoDocs {
+		return !strings.Contains(doc, "/") && strings.HasSuffix(doc, ".md")
+	}
+	return false
+}
+
+// dropGenerated removes the non-forced candidates marked generated by the rules
+func dropGenerated(candidates []fileCandidate, rules generatedRules, config Config) []fileCandidate {
+	if len(rules) == 0 {
+		return candidates
+	}
+	kept := candidates[:0]
+	for _, candidate := range candidates {
+		if !candidate.isForced {
+			if pattern, generated := rules.matchingPattern(candidate.path); generated {
+				explainSkip(config, candidate.path, "generated file matching '%s' in .gitattributes", pattern)
 				continue
 			}
+		}
+		kept = append(kept, candidate)
+	}
+	return kept
+}
+
+// excludingPattern checks for an exact match, a glob match, OR if the file is within an excluded
+// directory, and returns the exclusion pattern responsible
+func excludingPattern(file string, excludes patternSet, excludedDirs []string) (string, bool) {
+	if pattern, matched := excludes.matchingPattern(file); matched {
+		return pattern, true
+	}
+	for _, dir := range excludedDirs {
+		if strings.HasPrefix(excludes.fold(file), excludes.fold(dir)) {
+			return strings.TrimSuffix(dir, "/"), true
+		}
+	}
+	return "", false
+}
+
+// dirNameSet holds directory name globs, matched against every directory of a path
+type dirNameSet struct {
+	names      []string
+	ignoreCase bool
+}
+
+// compileDirNames checks directory name globs, lowercased with ignoreCase.
+// Invalid globs and globs spanning several directories are reported once.
+func compileDirNames(names []string, ignoreCase bool) dirNameSet {
+	set := dirNameSet{ignoreCase: ignoreCase}
+	for _, name := range names {
+		name = strings.Trim(name, "/")
+		if strings.Contains(name, "/") {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid directory name %q: use -e for paths\n", name)
+			continue
+		}
+		if _, err := filepath.Match(name, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid directory name %q: %v\n", name, err)
+			continue
+		}
+		if ignoreCase {
+			name = strings.ToLower(name)
+		}
+		set.names = append(set.names, name)
+	}
+	return set
+}
+
+// matchingDir returns the name of the first directory of a path matching a glob of the set
+func (s dirNameSet) matchingDir(file string) (string, bool) {
+	if len(s.names) == 0 {
+		return "", false
+	}
+	parts := strings.Split(file, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		folded := dir
+		if s.ignoreCase {
+			folded = strings.ToLower(dir)
+		}
+		for _, name := range s.names {
+			if matched, _ := filepath.Match(name, folded); matched {
+				return dir, true
+			}
+		}
+	}
+	return "", false
+}
+
+// testPatternSet holds test patterns split into file name globs and directory name globs
+type testPatternSet struct {
+	names []string
+	dirs  []string
+}
+
+// compileTestPatterns splits test patterns into file name and directory patterns.
+// Invalid patterns are reported once.
+func compileTestPatterns(patterns []string) testPatternSet {
+	var set testPatternSet
+	for _, pattern := range patterns {
+		dir := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Invalid test pattern %q: %v\n", pattern, err)
+			continue
+		}
+		if dir {
+			set.dirs = append(set.dirs, pattern)
 		} else {
-			// Force included files are always considered "text" for processing
-			info.IsText = true
+			set.names = append(set.names, pattern)
 		}
+	}
+	return set
+}
 
-		result = append(result, info)
+// matchingPattern checks if the file name or one of its parent directories matches a test
+// pattern, and returns that pattern
+func (s testPatternSet) matchingPattern(file string) (string, bool) {
+	parts := strings.Split(file, "/")
+	for _, pattern := range s.names {
+		if matched, _ := filepath.Match(pattern, parts[len(parts)-1]); matched {
+			return pattern, true
+		}
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		for _, pattern := range s.dirs {
+			if matched, _ := filepath.Match(pattern, dir); matched {
+				return pattern + "/", true
+			}
+		}
 	}
+	return "", false
+}
 
-	return result, nil
+// explainSkip reports why a file is skipped when Config.Explain is set
+func explainSkip(config Config, file, reason string, args ...interface{}) {
+	if config.Explain {
+		config.reportf("Explain: Skipping '%s': %s\n", file, fmt.Sprintf(reason, args...))
+	}
+}
+
+// enrichFile stats and classifies a selected file. The boolean is false when the file must be skipped.
+func enrichFile(candidate fileCandidate, config Config) (FileInfo, bool) {
+	file := candidate.path
+
+	// Get file info without following symlinks, which may point outside the repository or loop
+	fileInfo, err := os.Lstat(file)
+	if err != nil {
+		// Skip files that can't be stat'd
+		config.reportf("Warning: Cannot stat file '%s': %v. Skipping.\n", file, err)
+		return FileInfo{}, false
+	}
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		if !config.FollowSymlinks {
+			explainSkip(config, file, "symlink (use --follow-symlinks to include it)")
+			return FileInfo{}, false
+		}
+		// Stat fails on dangling and circular links
+		fileInfo, err = os.Stat(file)
+		if err != nil {
+			config.reportf("Warning: Cannot resolve symlink '%s': %v. Skipping.\n", file, err)
+			return FileInfo{}, false
+		}
+	}
+
+	// Create FileInfo struct
+	info := FileInfo{
+		Path:      file,
+		IsForced:  candidate.isForced,
+		Size:      fileInfo.Size(),
+		ModTime:   fileInfo.ModTime(),
+		IsRegular: fileInfo.Mode().IsRegular(),
+		Language:  detectLanguage(file),
+
+		MatchedPattern: candidate.matchedPattern,
+		AutoIncluded:   candidate.autoIncluded,
+	}
+
+	// Force included files are always considered "text" for processing
+	if candidate.isForced {
+		info.IsText = true
+		return info, true
+	}
+
+	if skipLanguage(config, file, info.Language) {
+		return FileInfo{}, false
+	}
+
+	// Drop files above the size threshold from the candidates entirely
+	if config.ExcludeLargerThan > 0 && info.Size > config.ExcludeLargerThan {
+		explainSkip(config, file, "larger than %d bytes (%d bytes)", config.ExcludeLargerThan, info.Size)
+		return FileInfo{}, false
+	}
+
+	// Only check if it's a text file if it's not force included
+	info.IsText = hasTextExtension(file, config.TextExtensions) || IsTextFile(file)
+	if !info.IsText {
+		explainSkip(config, file, "binary (non-text MIME type)")
+		return FileInfo{}, false
+	}
+	if config.StrictText && !SniffText(file) {
+		explainSkip(config, file, "binary content (--strict-text)")
+		return FileInfo{}, false
+	}
+
+	// Skip minified files if requested
+	if config.SkipMinified {
+		threshold := config.MinifiedLineLength
+		if threshold <= 0 {
+			threshold = DefaultMinifiedLineLength
+		}
+		if IsMinified(file, threshold) {
+			explainSkip(config, file, "looks minified")
+			return FileInfo{}, false
+		}
+	}
+
+	// Scan for conflict markers if requested
+	if config.OnlyConflicts || config.WarnConflicts {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			config.reportf("Warning: Cannot read file '%s': %v. Skipping.\n", file, err)
+			return FileInfo{}, false
+		}
+		conflicted := hasConflictMarkers(string(content))
+		if conflicted && config.WarnConflicts {
+			config.reportf("Warning: File '%s' contains git conflict markers.\n", file)
+		}
+		if !conflicted && config.OnlyConflicts {
+			explainSkip(config, file, "no git conflict markers (--only-conflicts)")
+			return FileInfo{}, false
+		}
+	}
+
+	return info, true
+}
+
+// hasConflictMarkers reports whether content contains a complete git conflict block:
+// a "<<<<<<<" line, followed by a "=======" line, followed by a ">>>>>>>" line
+func hasConflictMarkers(content string) bool {
+	hasStart, hasSeparator := false, false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case isConflictMarker(line, "<<<<<<<"):
+			hasStart = true
+		case hasStart && line == "=======":
+			hasSeparator = true
+		case hasSeparator && isConflictMarker(line, ">>>>>>>"):
+			return true
+		}
+	}
+	return false
+}
+
+// isConflictMarker checks if line is the given marker, optionally followed by a label
+func isConflictMarker(line, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+// hasTextExtension reports whether a file has one of the given extensions, ignoring case
+func hasTextExtension(filePath string, extensions []string) bool {
+	ext := filepath.Ext(filePath)
+	for _, textExt := range extensions {
+		if ext != "" && strings.EqualFold(ext, textExt) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileMimeTypes memoizes the MIME types reported by the 'file' command, by lowercase extension
+var fileMimeTypes sync.Map
+
+// fileCommandAvailable reports whether the 'file' command is installed, looking it up once
+var fileCommandAvailable = sync.OnceValue(func() bool {
+	_, err := exec.LookPath("file")
+	return err == nil
+})
+
+// fileCommandMimeType returns the MIME type of a file according to the 'file' command ("" on
+// failure). Results are reused for files with the same extension; files without an extension
+// are always inspected, as nothing else tells them apart, and inode types such as empty
+// files say nothing about the extension. A cached text type is only reused when the file's
+// own content looks like text, so a binary file never inherits it.
+func fileCommandMimeType(filePath, ext string) string {
+	key := strings.ToLower(ext)
+	if key != "" {
+		if cached, ok := fileMimeTypes.Load(key); ok {
+			mimeType := cached.(string)
+			if !isTextMimeType(mimeType) || SniffText(filePath) {
+				return mimeType
+			}
+		}
+	}
+
+	cmd := exec.Command("file", "-b", "--mime-type", filePath)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	mimeType := strings.TrimSpace(out.String())
+	if key != "" && !strings.HasPrefix(mimeType, "inode/") {
+		fileMimeTypes.LoadOrStore(key, mimeType)
+	}
+	return mimeType
+}
+
+// knownTextExtensions lists text extensions recognized when the MIME type is unknown
+var knownTextExtensions = map[string]bool{
+	".txt": true, ".md": true, ".go": true, ".py": true, ".js": true,
+	".html": true, ".css": true, ".json": true, ".xml": true, ".yaml": true,
+	".yml": true, ".toml": true, ".sh": true, ".bash": true, ".c": true,
+	".cpp": true, ".h": true, ".hpp": true, ".java": true, ".rb": true,
+	".php": true, ".ts": true, ".jsx": true, ".tsx": true, ".vue": true,
+	".rs": true, ".swift": true, ".kt": true, ".scala": true, ".clj": true,
+	".ex": true, ".exs": true, ".erl": true, ".hs": true, ".lua": true,
+	".pl": true, ".pm": true, ".r": true, ".dart": true, ".gradle": true,
+	".ini": true, ".cfg": true, ".conf": true, ".properties": true,
+	".gitignore": true, ".dockerignore": true, ".env": true, ".mod": true,
+	".sum": true, ".lock": true,
 }
 
 // IsTextFile checks if a file is a text file based on its MIME type
@@ -284,35 +1029,12 @@ func IsTextFile(filePath string) bool {
 	if mimeType == "" {
 		// Check if 'file' command is available and not disabled
 		fileDisabled := os.Getenv("MPP_NO_FILE") == "1"
-		if !fileDisabled {
-			_, err := exec.LookPath("file")
-			if err == nil {
-				cmd := exec.Command("file", "-b", "--mime-type", filePath)
-				var out bytes.Buffer
-				cmd.Stdout = &out
-				err := cmd.Run()
-				if err == nil {
-					mimeType = strings.TrimSpace(out.String())
-				}
-			}
+		if !fileDisabled && fileCommandAvailable() {
+			mimeType = fileCommandMimeType(filePath, ext)
 		}
 
 		// If 'file' command is not available or disabled, or if it failed, make a best guess based on extension
 		if mimeType == "" {
-			knownTextExtensions := map[string]bool{
-				".txt": true, ".md": true, ".go": true, ".py": true, ".js": true,
-				".html": true, ".css": true, ".json": true, ".xml": true, ".yaml": true,
-				".yml": true, ".toml": true, ".sh": true, ".bash": true, ".c": true,
-				".cpp": true, ".h": true, ".hpp": true, ".java": true, ".rb": true,
-				".php": true, ".ts": true, ".jsx": true, ".tsx": true, ".vue": true,
-				".rs": true, ".swift": true, ".kt": true, ".scala": true, ".clj": true,
-				".ex": true, ".exs": true, ".erl": true, ".hs": true, ".lua": true,
-				".pl": true, ".pm": true, ".r": true, ".dart": true, ".gradle": true,
-				".ini": true, ".cfg": true, ".conf": true, ".properties": true,
-				".gitignore": true, ".dockerignore": true, ".env": true, ".mod": true,
-				".sum": true, ".lock": true,
-			}
-
 			if knownTextExtensions[strings.ToLower(ext)] {
 				return true
 			}
@@ -333,6 +1055,10 @@ func IsTextFile(filePath string) bool {
 				buf := make([]byte, 512)
 				n, err := f.Read(buf)
 				if err == nil && n > 0 {
+					// UTF-16 text with a byte order mark is transcoded to UTF-8 when embedded
+					if hasUTF16BOM(buf[:n]) {
+						return true
+					}
 					// Check if the content appears to be text (no null bytes)
 					for i := 0; i < n; i++ {
 						if buf[i] == 0 {
@@ -345,7 +1071,11 @@ func IsTextFile(filePath string) bool {
 		}
 	}
 
-	// Check if it's a text file
+	return isTextMimeType(mimeType)
+}
+
+// isTextMimeType reports whether a MIME type denotes text or a common text-based format
+func isTextMimeType(mimeType string) bool {
 	if strings.HasPrefix(mimeType, "text/") {
 		return true
 	}
@@ -373,28 +1103,306 @@ func IsTextFile(filePath string) bool {
 	return false
 }
 
-// GetProjectTree returns the output of the tree command
+// SniffText reads the start of a file and reports whether its content looks like text:
+// no null bytes and only a small share of non-printable control characters
+func SniffText(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, textSniffSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		// An empty file has nothing binary in it
+		return err == io.EOF
+	}
+
+	return sniffTextContent(buf[:n])
+}
+
+// sniffTextContent reports whether the start of some content looks like text, as SniffText
+func sniffTextContent(sample []byte) bool {
+	if len(sample) > textSniffSize {
+		sample = sample[:textSniffSize]
+	}
+
+	// UTF-16 text with a byte order mark is transcoded to UTF-8 when embedded
+	if hasUTF16BOM(sample) {
+		return true
+	}
+
+	nonPrintable := 0
+	for _, b := range sample {
+		switch {
+		case b == 0:
+			return false
+		case b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\v' || b == '\b' || b == 0x1b:
+			// Common whitespace and escape characters found in text files
+		case b < 0x20 || b == 0x7f:
+			nonPrintable++
+		}
+	}
+
+	return float64(nonPrintable) <= float64(len(sample))*maxNonPrintableRatio
+}
+
+// Byte order marks recognized by DecodeText
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// hasUTF16BOM reports whether content starts with a UTF-16 byte order mark
+func hasUTF16BOM(content []byte) bool {
+	return bytes.HasPrefix(content, bomUTF16LE) || bytes.HasPrefix(content, bomUTF16BE)
+}
+
+// DecodeText strips a byte order mark from content, transcoding UTF-16LE/BE content to UTF-8.
+// Content without a byte order mark is returned unchanged.
+func DecodeText(content []byte) []byte {
+	switch {
+	case bytes.HasPrefix(content, bomUTF8):
+		return content[len(bomUTF8):]
+	case bytes.HasPrefix(content, bomUTF16LE):
+		return decodeUTF16(content[len(bomUTF16LE):], binary.LittleEndian)
+	case bytes.HasPrefix(content, bomUTF16BE):
+		return decodeUTF16(content[len(bomUTF16BE):], binary.BigEndian)
+	}
+	return content
+}
+
+// decodeUTF16 transcodes UTF-16 content in the given byte order to UTF-8.
+// A trailing odd byte is dropped.
+func decodeUTF16(content []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// IsMinified reports whether a file looks minified, i.e. the average line length
+// of a sample from the start of the file exceeds threshold characters
+func IsMinified(filePath string, threshold int) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, minifiedSampleSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+	return isMinifiedContent(buf[:n], threshold)
+}
+
+// isMinifiedContent reports whether content looks minified, as IsMinified
+func isMinifiedContent(content []byte, threshold int) bool {
+	if len(content) > minifiedSampleSize {
+		content = content[:minifiedSampleSize]
+	}
+	sample := bytes.TrimRight(content, "\n")
+	if len(sample) == 0 {
+		return false
+	}
+
+	lines := bytes.Count(sample, []byte("\n")) + 1
+	return len(sample)/lines > threshold
+}
+
+// treeIgnoredDirs lists directories left out of the project tree
+var treeIgnoredDirs = []string{".git", "node_modules", "vendor", "dist", "build"}
+
+// GetProjectTree returns a tree of the Git-listed files, rendered natively
 func GetProjectTree() (string, error) {
-	// Check if tree command is available
-	_, err := exec.LookPath("tree")
+	return GetProjectTreeWithDepth(0)
+}
+
+// GetProjectTreeWithDepth returns a tree of the Git-listed files, rendered natively and
+// descending at most depth directory levels (0 = unlimited)
+func GetProjectTreeWithDepth(depth int) (string, error) {
+	paths, err := listGitPaths()
 	if err != nil {
-		// Tree command not available, return a fallback message with a simple tree structure
-		return ".\n├── docs\n│   ├── CONTRIBUTING.md\n│   └── README.md\n├── src\n│   ├── main\n│   │   ├── app.go\n│   │   └── utils.go\n│   └── test\n│       └── app_test.go\n", nil
+		return "", err
+	}
+
+	return RenderTree(".", LimitTreeDepth(withoutIgnoredTreeDirs(paths), depth)), nil
+}
+
+// GetRepoProjectTree returns a tree of the Git-listed files of the repository in dir, rendered
+// natively with label as the top-level entry and at most depth levels (0 = unlimited)
+func GetRepoProjectTree(dir, label string, depth int) (string, error) {
+	paths, err := runGitLsFilesIn(dir, "-co", "--exclude-standard")
+	if err != nil {
+		return "", err
+	}
+
+	return RenderTree(label, LimitTreeDepth(withoutIgnoredTreeDirs(paths), depth)), nil
+}
+
+// GetExternalProjectTree returns the output of the tree command, descending at most
+// depth directory levels (0 = unlimited)
+func GetExternalProjectTree(depth int) (string, error) {
+	// Check if tree command is available
+	if _, err := exec.LookPath("tree"); err != nil {
+		return "", fmt.Errorf("tree command not found: %w", err)
 	}
 
 	// Directories to ignore in tree output
-	ignorePattern := ".git|node_modules|vendor|dist|build"
+	ignorePattern := strings.Join(treeIgnoredDirs, "|")
 
 	// Use --charset=utf-8 to ensure Unicode characters are used for the tree structure
-	cmd := exec.Command("tree", "-I", ignorePattern, "--charset=utf-8")
+	args := []string{"-I", ignorePattern, "--charset=utf-8"}
+	if depth > 0 {
+		args = append(args, "-L", strconv.Itoa(depth))
+	}
+	cmd := exec.Command("tree", args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	err = cmd.Run()
-	if err != nil {
-		// Tree command failed, return a fallback message with a simple tree structure
-		return ".\n├── docs\n│   ├── CONTRIBUTING.md\n│   └── README.md\n├── src\n│   ├── main\n│   │   ├── app.go\n│   │   └── utils.go\n│   └── test\n│       └── app_test.go\n", nil
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("failed to run tree: %s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return "", fmt.Errorf("failed to run tree: %w", err)
 	}
 
 	return stdout.String(), nil
 }
+
+// GetScopedProjectTree returns a tree of the Git-listed files located under root,
+// rendered natively with root as the top-level entry and at most depth levels (0 = unlimited)
+func GetScopedProjectTree(root string, depth int) (string, error) {
+	paths, err := listGitPaths()
+	if err != nil {
+		return "", err
+	}
+
+	root = NormalizeTreeRoot(root)
+	scoped := FilterTreePaths(withoutIgnoredTreeDirs(paths), root)
+	if len(scoped) == 0 {
+		return "", fmt.Errorf("no files found under tree root '%s'", root)
+	}
+
+	return RenderTree(root, LimitTreeDepth(scoped, depth)), nil
+}
+
+// withoutIgnoredTreeDirs returns the paths not located in a directory left out of the tree
+func withoutIgnoredTreeDirs(paths []string) []string {
+	var kept []string
+	for _, path := range paths {
+		if !inIgnoredTreeDir(path) {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// inIgnoredTreeDir checks if a path is located in one of the directories left out of the tree
+func inIgnoredTreeDir(path string) bool {
+	parts := strings.Split(path, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		for _, ignored := range treeIgnoredDirs {
+			if dir == ignored {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NormalizeTreeRoot cleans a tree root so it can be compared with Git paths
+func NormalizeTreeRoot(root string) string {
+	root = filepath.ToSlash(filepath.Clean(root))
+	if root == "" {
+		return "."
+	}
+	return root
+}
+
+// FilterTreePaths keeps the paths located under root and returns them relative to root
+func FilterTreePaths(paths []string, root string) []string {
+	if root == "." {
+		return paths
+	}
+
+	var scoped []string
+	prefix := root + "/"
+	for _, path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			scoped = append(scoped, strings.TrimPrefix(path, prefix))
+		}
+	}
+	return scoped
+}
+
+// LimitTreeDepth truncates slash-separated paths to their first depth components (0 = unlimited)
+func LimitTreeDepth(paths []string, depth int) []string {
+	if depth <= 0 {
+		return paths
+	}
+
+	limited := make([]string, 0, len(paths))
+	for _, path := range paths {
+		parts := strings.Split(path, "/")
+		if len(parts) > depth {
+			parts = parts[:depth]
+		}
+		limited = append(limited, strings.Join(parts, "/"))
+	}
+	return limited
+}
+
+// treeNode is a directory or file in a natively rendered tree
+type treeNode struct {
+	children map[string]*treeNode
+}
+
+// RenderTree renders slash-separated paths as a tree in the style of the 'tree' command,
+// with label as the top-level entry. Entries are sorted by byte order and drawn with UTF-8
+// box characters, so the output depends neither on the locale nor on an installed 'tree'.
+func RenderTree(label string, paths []string) string {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for _, path := range paths {
+		node := root
+		for _, part := range strings.Split(path, "/") {
+			if part == "" {
+				continue
+			}
+			child, exists := node.children[part]
+			if !exists {
+				child = &treeNode{children: make(map[string]*treeNode)}
+				node.children[part] = child
+			}
+			node = child
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(label + "\n")
+	writeTreeNode(&builder, root, "")
+	return builder.String()
+}
+
+// writeTreeNode writes the children of node, sorted by name, using prefix for indentation
+func writeTreeNode(builder *strings.Builder, node *treeNode, prefix string) {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(names)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+		builder.WriteString(prefix + connector + name + "\n")
+		writeTreeNode(builder, node.children[name], prefix+childPrefix)
+	}
+}
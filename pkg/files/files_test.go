@@ -1,9 +1,11 @@
 package files
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -149,18 +151,129 @@ func TestListGitFiles_Hermetic(t *testing.T) {
 				if info.IsForced != isForced {
 					t.Errorf("File %s: expected IsForced=%v, got %v", info.Path, isForced, info.IsForced)
 				}
+
+				// The modification time is captured from the same stat call as the size
+				if info.ModTime.IsZero() {
+					t.Errorf("File %s: expected ModTime to be set", info.Path)
+				}
 			}
 		})
 	}
 }
 
-func TestGetProjectTree(t *testing.T) {
-	// Skip this test if the tree command is not available
-	_, err := exec.LookPath("tree")
+func TestListGitFiles_TrackedAndUntracked(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer func() {
+		if err := os.RemoveAll(repoPath); err != nil {
+			t.Logf("Warning: Failed to remove test repo: %v", err)
+		}
+	}()
+
+	originalWD, err := os.Getwd()
 	if err != nil {
-		t.Skip("Skipping test: tree command not available")
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change directory to test repo: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(originalWD); err != nil {
+			t.Logf("Warning: Failed to change back to original directory: %v", err)
+		}
+	}()
+
+	if err := os.WriteFile(filepath.Join("src", "main", "new.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create an untracked file: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		config   Config
+		expected string // Sorted paths, comma-separated
+	}{
+		{"All files", Config{IncludePatterns: []string{"src/main/*"}}, "src/main/app.go,src/main/new.go,src/main/utils.go"},
+		{"Tracked only", Config{IncludePatterns: []string{"src/main/*"}, TrackedOnly: true}, "src/main/app.go,src/main/utils.go"},
+		{"Untracked only", Config{IncludePatterns: []string{"src/main/*"}, UntrackedOnly: true}, "src/main/new.go"},
+		{"Forced files are kept", Config{IncludePatterns: []string{"src/main/*"}, UntrackedOnly: true, ForceIncludePatterns: []string{"docs/README.md"}}, "docs/README.md,src/main/new.go"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			infos, err := ListGitFiles(tc.config)
+			if err != nil {
+				t.Fatalf("ListGitFiles failed: %v", err)
+			}
+			var paths []string
+			for _, info := range infos {
+				paths = append(paths, info.Path)
+			}
+			sort.Strings(paths)
+			if got := strings.Join(paths, ","); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestListGitFilesAt(t *testing.T) {
+	repoPath := setupTestRepo(t)
+	defer func() {
+		if err := os.RemoveAll(repoPath); err != nil {
+			t.Logf("Warning: Failed to remove test repo: %v", err)
+		}
+	}()
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	if err := os.Chdir(repoPath); err != nil {
+		t.Fatalf("Failed to change directory to test repo: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(originalWD); err != nil {
+			t.Logf("Warning: Failed to change back to original directory: %v", err)
+		}
+	}()
+
+	// Commit a new version of app.go and a new file on top of the initial commit
+	if err := os.WriteFile("src/main/app.go", []byte("package main // v2\n"), 0644); err != nil {
+		t.Fatalf("Failed to update app.go: %v", err)
+	}
+	if err := os.WriteFile("src/main/new.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create new.go: %v", err)
+	}
+	for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", "v2"}} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	result, err := ListGitFilesAt("HEAD~1", Config{IncludePatterns: []string{"src/main/*"}})
+	if err != nil {
+		t.Fatalf("ListGitFilesAt returned error: %v", err)
+	}
+	var paths []string
+	for _, info := range result {
+		paths = append(paths, info.Path)
+	}
+	if strings.Join(paths, ",") != "src/main/app.go,src/main/utils.go" {
+		t.Errorf("Expected the files of the first commit, got %v", paths)
+	}
+
+	content, err := ReadGitBlob("HEAD~1", "src/main/app.go")
+	if err != nil {
+		t.Fatalf("ReadGitBlob returned error: %v", err)
+	}
+	if !strings.Contains(string(content), "Hello, world!") {
+		t.Errorf("Expected the committed content of app.go, got %q", content)
 	}
 
+	if _, err := ListGitFilesAt("no-such-ref", Config{}); err == nil || !strings.Contains(err.Error(), "unknown revision") {
+		t.Errorf("Expected an unknown revision error, got %v", err)
+	}
+}
+
+func TestGetProjectTree(t *testing.T) {
 	// Get the project tree
 	tree, err := GetProjectTree()
 	if err != nil {
@@ -184,6 +297,26 @@ func TestGetProjectTree(t *testing.T) {
 			t.Errorf("Expected project tree to contain %q, but it doesn't", element)
 		}
 	}
+
+	// The tree is built from the files listed by Git in the current directory
+	if !strings.Contains(tree, "files.go") {
+		t.Errorf("Expected project tree to list files.go, got:\n%s", tree)
+	}
+}
+
+func TestGetExternalProjectTree(t *testing.T) {
+	// Skip this test if the tree command is not available
+	if _, err := exec.LookPath("tree"); err != nil {
+		t.Skip("Skipping test: tree command not available")
+	}
+
+	tree, err := GetExternalProjectTree(0)
+	if err != nil {
+		t.Fatalf("GetExternalProjectTree failed: %v", err)
+	}
+	if !strings.Contains(tree, "├──") {
+		t.Errorf("Expected tree output, got:\n%s", tree)
+	}
 }
 
 func TestIsTextFile(t *testing.T) {
@@ -263,3 +396,287 @@ func TestIsTextFile(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderTree(t *testing.T) {
+	paths := []string{
+		"docs/README.md",
+		"src/main/app.go",
+		"src/main/utils.go",
+		"src/test/app_test.go",
+	}
+
+	t.Run("Whole project", func(t *testing.T) {
+		expected := `.
+├── docs
+│   └── README.md
+└── src
+    ├── main
+    │   ├── app.go
+    │   └── utils.go
+    └── test
+        └── app_test.go
+`
+		if tree := RenderTree(".", paths); tree != expected {
+			t.Errorf("Unexpected tree:\n%s\nExpected:\n%s", tree, expected)
+		}
+	})
+
+	t.Run("Scoped to a subdirectory", func(t *testing.T) {
+		expected := `src
+├── main
+│   ├── app.go
+│   └── utils.go
+└── test
+    └── app_test.go
+`
+		scoped := FilterTreePaths(paths, NormalizeTreeRoot("./src/"))
+		if tree := RenderTree("src", scoped); tree != expected {
+			t.Errorf("Unexpected tree:\n%s\nExpected:\n%s", tree, expected)
+		}
+	})
+
+	t.Run("Sorted by byte order whatever the input order", func(t *testing.T) {
+		expected := `.
+├── Makefile
+├── _build.sh
+├── café.md
+└── main.go
+`
+		unsorted := []string{"main.go", "café.md", "_build.sh", "Makefile"}
+		if tree := RenderTree(".", unsorted); tree != expected {
+			t.Errorf("Unexpected tree:\n%s\nExpected:\n%s", tree, expected)
+		}
+	})
+}
+
+func TestFileCommandMimeType_CachedByExtension(t *testing.T) {
+	// A stand-in for the 'file' command, classifying by the presence of NUL bytes and
+	// logging each file it inspects
+	binDir := t.TempDir()
+	callsPath := filepath.Join(binDir, "calls")
+	stub := "#!/bin/sh\necho \"$3\" >> " + callsPath + "\nif [ \"$(tr -d '\\000' < \"$3\" | wc -c)\" -eq \"$(wc -c < \"$3\")\" ]; then echo text/plain; else echo application/octet-stream; fi\n"
+	if err := os.WriteFile(filepath.Join(binDir, "file"), []byte(stub), 0755); err != nil {
+		t.Fatalf("Failed to create the file command stub: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("MPP_NO_FILE", "")
+	originalAvailable := fileCommandAvailable
+	defer func() { fileCommandAvailable = originalAvailable }()
+	fileCommandAvailable = func() bool { return true }
+
+	tempDir := t.TempDir()
+	textPath := filepath.Join(tempDir, "notes.mppcache")
+	otherTextPath := filepath.Join(tempDir, "more.mppcache")
+	binaryPath := filepath.Join(tempDir, "data.mppcache")
+	if err := os.WriteFile(textPath, []byte("plain text\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(otherTextPath, []byte("more text\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(binaryPath, []byte{0x00, 0x01, 0x02, 0xff}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	first := fileCommandMimeType(textPath, ".mppcache")
+	if !strings.HasPrefix(first, "text/") {
+		t.Fatalf("Expected a text MIME type, got %q", first)
+	}
+	// Another text file is not inspected: its extension was already classified
+	if second := fileCommandMimeType(otherTextPath, ".MPPCACHE"); second != first {
+		t.Errorf("Expected the cached MIME type %q, got %q", first, second)
+	}
+	if calls, _ := os.ReadFile(callsPath); strings.Contains(string(calls), otherTextPath) {
+		t.Error("Expected the cached MIME type to be reused without running the file command")
+	}
+	// The cached text type does not apply to a binary file with the same extension
+	if third := fileCommandMimeType(binaryPath, ".MPPCACHE"); isTextMimeType(third) {
+		t.Errorf("Expected the binary file not to get the cached text type, got %q", third)
+	}
+	if IsTextFile(binaryPath) {
+		t.Error("Expected the binary file to be rejected")
+	}
+	if !IsTextFile(textPath) {
+		t.Error("Expected the text file to be accepted")
+	}
+}
+
+func TestSniffText(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := []struct {
+		name     string
+		content  []byte
+		expected bool
+	}{
+		{
+			name:     "Plain text",
+			content:  []byte("Hello, world!\n\tIndented line\n"),
+			expected: true,
+		},
+		{
+			name:     "UTF-8 text",
+			content:  []byte("Héllo, wörld! 日本語\n"),
+			expected: true,
+		},
+		{
+			name:     "Empty file",
+			content:  []byte{},
+			expected: true,
+		},
+		{
+			name:     "UTF-16LE text without BOM contains null bytes",
+			content:  []byte{'H', 0, 'i', 0, '\n', 0},
+			expected: false,
+		},
+		{
+			name:     "UTF-16LE text with BOM",
+			content:  []byte{0xFF, 0xFE, 'H', 0, 'i', 0, '\n', 0},
+			expected: true,
+		},
+		{
+			name:     "Many control characters",
+			content:  []byte("ab\x01\x02\x03\x04\x05\x06cd"),
+			expected: false,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(tempDir, fmt.Sprintf("sniff%d.txt", i))
+			if err := os.WriteFile(filePath, tc.content, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			if result := SniffText(filePath); result != tc.expected {
+				t.Errorf("Expected SniffText to return %v, got %v", tc.expected, result)
+			}
+		})
+	}
+
+	t.Run("Strict mode rejects UTF-16 .txt files without BOM", func(t *testing.T) {
+		filePath := filepath.Join(tempDir, "utf16.txt")
+		if err := os.WriteFile(filePath, []byte{'H', 0, 'i', 0}, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		infos, err := filterAndEnrichFiles([]string{filePath}, Config{})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+		if len(infos) != 1 {
+			t.Errorf("Expected the .txt file to be trusted by extension without strict mode, got %v", infos)
+		}
+
+		infos, err = filterAndEnrichFiles([]string{filePath}, Config{StrictText: true})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+		if len(infos) != 0 {
+			t.Errorf("Expected the UTF-16 file to be rejected in strict mode, got %v", infos)
+		}
+	})
+}
+
+func TestDecodeText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  []byte
+		expected string
+	}{
+		{
+			name:     "No BOM is unchanged",
+			content:  []byte("héllo\n"),
+			expected: "héllo\n",
+		},
+		{
+			name:     "UTF-8 BOM is stripped",
+			content:  append([]byte{0xEF, 0xBB, 0xBF}, "héllo\n"...),
+			expected: "héllo\n",
+		},
+		{
+			name:     "UTF-16LE is transcoded",
+			content:  []byte{0xFF, 0xFE, 'h', 0, 0xE9, 0, 'l', 0, 'l', 0, 'o', 0, '\n', 0},
+			expected: "héllo\n",
+		},
+		{
+			name:     "UTF-16BE is transcoded",
+			content:  []byte{0xFE, 0xFF, 0, 'h', 0, 0xE9, 0, 'l', 0, 'l', 0, 'o', 0, '\n'},
+			expected: "héllo\n",
+		},
+		{
+			name:     "UTF-16LE surrogate pair is transcoded",
+			content:  []byte{0xFF, 0xFE, 0x3D, 0xD8, 0x00, 0xDE},
+			expected: "😀",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := string(DecodeText(tc.content)); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestLimitTreeDepth(t *testing.T) {
+	paths := []string{"README.md", "src/main/app.go", "src/test/app_test.go"}
+
+	tree := RenderTree(".", LimitTreeDepth(paths, 2))
+	expected := `.
+├── README.md
+└── src
+    ├── main
+    └── test
+`
+	if tree != expected {
+		t.Errorf("Unexpected tree:\n%s\nExpected:\n%s", tree, expected)
+	}
+
+	if unlimited := LimitTreeDepth(paths, 0); len(unlimited) != len(paths) || unlimited[1] != "src/main/app.go" {
+		t.Errorf("Expected depth 0 to keep paths unchanged, got %v", unlimited)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tempDir := t.TempDir()
+
+	scripts := map[string]string{
+		"deploy":    "#!/bin/bash\necho deploy\n",
+		"manage":    "#!/usr/bin/env python3\nprint('hi')\n",
+		"run":       "#!/usr/bin/env -S node --no-warnings\nconsole.log('hi')\n",
+		"README":    "Just some notes\n",
+		"empty_bin": "",
+	}
+	for name, content := range scripts {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"src/app.go", "go"},
+		{"web/index.TSX", "tsx"},
+		{"docs/README.md", "markdown"},
+		{"Makefile", "makefile"},
+		{"data/file.unknownext", ""},
+		{".gitignore", ""},
+		{filepath.Join(tempDir, "deploy"), "bash"},
+		{filepath.Join(tempDir, "manage"), "python"},
+		{filepath.Join(tempDir, "run"), "javascript"},
+		{filepath.Join(tempDir, "README"), ""},
+		{filepath.Join(tempDir, "empty_bin"), ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if result := detectLanguage(tc.path); result != tc.expected {
+				t.Errorf("Expected detectLanguage(%q) to return %q, got %q", tc.path, tc.expected, result)
+			}
+		})
+	}
+}
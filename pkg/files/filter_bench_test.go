@@ -0,0 +1,86 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupBenchmarkFiles creates a synthetic project with count files and returns their paths.
+// The working directory is changed to the project root for the duration of the benchmark.
+func setupBenchmarkFiles(b *testing.B, count int) []string {
+	b.Helper()
+	root := b.TempDir()
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		b.Fatalf("Failed to get current working directory: %v", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		b.Fatalf("Failed to change directory: %v", err)
+	}
+	b.Cleanup(func() {
+		if err := os.Chdir(originalWD); err != nil {
+			b.Logf("Warning: Failed to change back to original directory: %v", err)
+		}
+	})
+
+	extensions := []string{".go", ".md", ".js", ".txt", ".json"}
+	paths := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		dir := fmt.Sprintf("pkg%d/sub%d", i%50, i%7)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatalf("Failed to create directory: %v", err)
+		}
+		path := filepath.ToSlash(filepath.Join(dir, fmt.Sprintf("file%d%s", i, extensions[i%len(extensions)])))
+		if err := os.WriteFile(path, []byte("content\n"), 0644); err != nil {
+			b.Fatalf("Failed to create file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func BenchmarkFilterAndEnrichFiles(b *testing.B) {
+	paths := setupBenchmarkFiles(b, 10000)
+
+	config := Config{
+		IncludePatterns: []string{"pkg1*/**/*.go", "**/*.md", "pkg2/sub3/*", "*.json", "**/*.js"},
+		ExcludePatterns: []string{"pkg10", "pkg11/", "**/file9*.md", "pkg4*/sub6/*"},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := filterAndEnrichFiles(paths, config); err != nil {
+			b.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkIsTextFile_UnknownExtensions classifies files whose extensions have no registered
+// MIME type, which requires the 'file' command
+func BenchmarkIsTextFile_UnknownExtensions(b *testing.B) {
+	root := b.TempDir()
+	paths := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file%d.mppx%d", i, i%10))
+		if err := os.WriteFile(path, []byte("content\n"), 0644); err != nil {
+			b.Fatalf("Failed to create file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Start each run with an empty cache
+		fileMimeTypes.Range(func(key, _ interface{}) bool {
+			fileMimeTypes.Delete(key)
+			return true
+		})
+		for _, path := range paths {
+			IsTextFile(path)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package files
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -205,3 +210,716 @@ func TestFilterAndEnrichFiles_Unit(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterAndEnrichFiles_SkipMinified(t *testing.T) {
+	tempDir := t.TempDir()
+
+	minifiedPath := filepath.Join(tempDir, "bundle.min.js")
+	minified := "var a=1;" + strings.Repeat("function f(){return a+1};", 100) + "\n"
+	if err := os.WriteFile(minifiedPath, []byte(minified), 0644); err != nil {
+		t.Fatalf("Failed to create minified file: %v", err)
+	}
+
+	normalPath := filepath.Join(tempDir, "app.js")
+	normal := strings.Repeat("function f() {\n  return 1;\n}\n", 50)
+	if err := os.WriteFile(normalPath, []byte(normal), 0644); err != nil {
+		t.Fatalf("Failed to create normal file: %v", err)
+	}
+
+	paths := []string{minifiedPath, normalPath}
+
+	t.Run("Minified file is excluded and normal file kept", func(t *testing.T) {
+		infos, err := filterAndEnrichFiles(paths, Config{SkipMinified: true})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+		if len(infos) != 1 || infos[0].Path != normalPath {
+			t.Errorf("Expected only %s, got %v", normalPath, infos)
+		}
+	})
+
+	t.Run("Threshold is configurable", func(t *testing.T) {
+		infos, err := filterAndEnrichFiles(paths, Config{SkipMinified: true, MinifiedLineLength: 5000})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+		if len(infos) != 2 {
+			t.Errorf("Expected both files with a high threshold, got %v", infos)
+		}
+	})
+
+	t.Run("Forced minified file is kept", func(t *testing.T) {
+		infos, err := filterAndEnrichFiles(paths, Config{SkipMinified: true, ForceIncludePatterns: []string{minifiedPath}})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles failed: %v", err)
+		}
+		if len(infos) != 1 || infos[0].Path != minifiedPath {
+			t.Errorf("Expected only the forced file %s, got %v", minifiedPath, infos)
+		}
+	})
+}
+
+func TestCompiledPatternMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		file     string
+		expected bool
+	}{
+		{"main.go", "main.go", true},
+		{"main.go", "cmd/main.go", false},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"src/*", "src/app.go", true},
+		{"src/*", "src/main/app.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "src/main/app.go", true},
+		{"src/**/*.go", "src/main/app.go", true},
+		{"src/**/*.go", "src/app.go", true},
+		{"src/**/*.go", "srcx/app.go", false},
+		{"src/**/*.go", "docs/app.go", false},
+		{"src/**", "src/main/app.go", true},
+		{"src/**", "src", true},
+		{"**/test/*.go", "src/test/app_test.go", true},
+		{"**/test/*.go", "src/main/app.go", false},
+		{"[", "[", true},
+		{"[", "a", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.file, func(t *testing.T) {
+			if result := compilePattern(tc.pattern).match(tc.file); result != tc.expected {
+				t.Errorf("Expected %q to match %q: %v, got %v", tc.pattern, tc.file, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFilterAndEnrichFiles_WarningsInFileOrder(t *testing.T) {
+	var paths, expected []string
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("missing_%02d.go", i)
+		paths = append(paths, path)
+		expected = append(expected, fmt.Sprintf("Warning: Cannot stat file '%s'", path))
+	}
+
+	// Capture stderr, where the warnings are printed once all files are enriched
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = writer
+	infos, err := filterAndEnrichFiles(paths, Config{})
+	os.Stderr = stderr
+	writer.Close()
+	output, readErr := io.ReadAll(reader)
+	if readErr != nil {
+		t.Fatalf("Failed to read stderr: %v", readErr)
+	}
+
+	if err != nil {
+		t.Fatalf("filterAndEnrichFiles failed: %v", err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("Expected missing files to be skipped, got %v", infos)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d warnings, got:\n%s", len(expected), output)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("Expected warning %d to start with %q, got %q", i, expected[i], line)
+		}
+	}
+}
+
+func TestHasConflictMarkers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{
+			name:     "Complete conflict block",
+			content:  "a\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\nb\n",
+			expected: true,
+		},
+		{
+			name:     "Conflict block with CRLF line endings",
+			content:  "<<<<<<< HEAD\r\nours\r\n=======\r\ntheirs\r\n>>>>>>> feature\r\n",
+			expected: true,
+		},
+		{
+			name:     "Clean file",
+			content:  "package main\n\nfunc main() {}\n",
+			expected: false,
+		},
+		{
+			name:     "Separator only (e.g. markdown heading underline)",
+			content:  "Title\n=======\n",
+			expected: false,
+		},
+		{
+			name:     "Markers out of order",
+			content:  ">>>>>>> feature\n=======\n<<<<<<< HEAD\n",
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := hasConflictMarkers(tc.content); result != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestFilterAndEnrichFiles_OnlyConflicts(t *testing.T) {
+	tempDir := t.TempDir()
+
+	conflictedPath := filepath.Join(tempDir, "conflicted.go")
+	conflicted := "package main\n<<<<<<< HEAD\nvar x = 1\n=======\nvar x = 2\n>>>>>>> feature\n"
+	if err := os.WriteFile(conflictedPath, []byte(conflicted), 0644); err != nil {
+		t.Fatalf("Failed to create conflicted file: %v", err)
+	}
+
+	cleanPath := filepath.Join(tempDir, "clean.go")
+	if err := os.WriteFile(cleanPath, []byte("package main\nvar x = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create clean file: %v", err)
+	}
+
+	paths := []string{conflictedPath, cleanPath}
+
+	infos, err := filterAndEnrichFiles(paths, Config{OnlyConflicts: true})
+	if err != nil {
+		t.Fatalf("filterAndEnrichFiles failed: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Path != conflictedPath {
+		t.Errorf("Expected only %s, got %v", conflictedPath, infos)
+	}
+
+	infos, err = filterAndEnrichFiles(paths, Config{WarnConflicts: true})
+	if err != nil {
+		t.Fatalf("filterAndEnrichFiles failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Errorf("Expected --warn-conflicts to keep both files, got %v", infos)
+	}
+}
+
+// selectedPaths returns the paths selectFiles keeps from paths with config
+func selectedPaths(paths []string, config Config) []string {
+	var result []string
+	for _, candidate := range selectFiles(paths, config) {
+		result = append(result, candidate.path)
+	}
+	return result
+}
+
+func TestSelectFiles_ExcludeTests(t *testing.T) {
+	paths := []string{
+		"src/app.go",
+		"src/app_test.go",
+		"web/foo.js",
+		"web/foo.spec.js",
+		"web/__tests__/helpers.js",
+		"tests/fixtures.json",
+		"docs/testing.md",
+	}
+
+	t.Run("Default test patterns", func(t *testing.T) {
+		result := selectedPaths(paths, Config{ExcludeTests: true})
+		expected := []string{"src/app.go", "web/foo.js", "docs/testing.md"}
+		if strings.Join(result, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("Composes with include patterns", func(t *testing.T) {
+		result := selectedPaths(paths, Config{ExcludeTests: true, IncludePatterns: []string{"src/*"}})
+		if len(result) != 1 || result[0] != "src/app.go" {
+			t.Errorf("Expected only src/app.go, got %v", result)
+		}
+	})
+
+	t.Run("Forced test file is kept", func(t *testing.T) {
+		result := selectedPaths(paths, Config{ExcludeTests: true, ForceIncludePatterns: []string{"src/app_test.go"}})
+		if len(result) != 1 || result[0] != "src/app_test.go" {
+			t.Errorf("Expected only the forced src/app_test.go, got %v", result)
+		}
+	})
+
+	t.Run("Custom test patterns", func(t *testing.T) {
+		result := selectedPaths(paths, Config{ExcludeTests: true, TestPatterns: []string{"*.spec.js", "__tests__/"}})
+		expected := []string{"src/app.go", "src/app_test.go", "web/foo.js", "tests/fixtures.json", "docs/testing.md"}
+		if strings.Join(result, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+}
+
+func TestSelectFiles_MaxDepth(t *testing.T) {
+	paths := []string{"README.md", "src/app.go", "src/db/store.go", "src/db/sql/query.go"}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"No limit", Config{}, paths},
+		{"Top level only", Config{LimitDepth: true}, []string{"README.md"}},
+		{"One level down", Config{LimitDepth: true, MaxDepth: 1}, []string{"README.md", "src/app.go"}},
+		{"Whatever the include patterns", Config{LimitDepth: true, MaxDepth: 1, IncludePatterns: []string{"src/**"}}, []string{"src/app.go"}},
+		{"Forced files are kept", Config{LimitDepth: true, ForceIncludePatterns: []string{"src/db/sql/query.go"}}, []string{"src/db/sql/query.go"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := selectedPaths(paths, tc.config); strings.Join(result, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSelectFiles_NoHidden(t *testing.T) {
+	paths := []string{".env", ".github/workflows/ci.yml", "src/.hidden/config.go", "src/app.go", "docs/intro.md"}
+
+	if result := strings.Join(selectedPaths(paths, Config{}), ","); result != strings.Join(paths, ",") {
+		t.Errorf("Expected hidden files to be included by default, got %s", result)
+	}
+	if result := strings.Join(selectedPaths(paths, Config{ExcludeHidden: true}), ","); result != "src/app.go,docs/intro.md" {
+		t.Errorf("Expected only the visible files, got %s", result)
+	}
+	if result := strings.Join(selectedPaths(paths, Config{ExcludeHidden: true, ForceIncludePatterns: []string{".env"}, IncludePatterns: []string{"src/**"}}), ","); result != ".env,src/app.go" {
+		t.Errorf("Expected the forced .env to be kept, got %s", result)
+	}
+}
+
+func TestSelectFiles_AutoReadme(t *testing.T) {
+	paths := []string{
+		"README.md",
+		"readme",
+		"src/README.md",
+		"docs/design.md",
+		"docs/api/endpoints.md",
+		"docs/diagram.png",
+		"src/app.go",
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+		auto     []string
+	}{
+		{
+			"Top-level README regardless of the patterns",
+			Config{AutoReadme: true, IncludePatterns: []string{"src/*.go"}, ExcludePatterns: []string{"*.md"}},
+			[]string{"README.md", "readme", "src/app.go"},
+			[]string{"README.md", "readme"},
+		},
+		{
+			"Docs directly under docs/",
+			Config{AutoReadme: true, AutoDocs: true, IncludePatterns: []string{"src/*.go"}},
+			[]string{"README.md", "readme", "docs/design.md", "src/app.go"},
+			[]string{"README.md", "readme", "docs/design.md"},
+		},
+		{
+			"A README matched by the patterns is not auto-included",
+			Config{AutoReadme: true, IncludePatterns: []string{"*.md"}},
+			[]string{"README.md", "readme"},
+			[]string{"readme"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := selectedPaths(paths, tc.config); strings.Join(result, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+			var auto []string
+			for _, candidate := range selectFiles(paths, tc.config) {
+				if candidate.autoIncluded {
+					auto = append(auto, candidate.path)
+				}
+			}
+			if strings.Join(auto, ",") != strings.Join(tc.auto, ",") {
+				t.Errorf("Expected %v to be auto-included, got %v", tc.auto, auto)
+			}
+		})
+	}
+}
+
+func TestSelectFiles_IgnoreCase(t *testing.T) {
+	paths := []string{"README.md", "docs/NOTES.MD", "Build/out.txt", "src/App.go"}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"Case-sensitive by default", Config{IncludePatterns: []string{"**/*.MD"}}, []string{"docs/NOTES.MD=**/*.MD"}},
+		{"Glob", Config{IgnoreCase: true, IncludePatterns: []string{"**/*.MD"}}, []string{"README.md=**/*.MD", "docs/NOTES.MD=**/*.MD"}},
+		{"Literal", Config{IgnoreCase: true, IncludePatterns: []string{"src/app.go"}}, []string{"src/App.go=src/app.go"}},
+		{"Excluded directory", Config{IgnoreCase: true, ExcludePatterns: []string{"build/", "**/*.md"}}, []string{"src/App.go="}},
+		{"Force include", Config{IgnoreCase: true, ForceIncludePatterns: []string{"readme.*"}}, []string{"README.md=readme.*"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var result []string
+			for _, candidate := range selectFiles(paths, tc.config) {
+				result = append(result, candidate.path+"="+candidate.matchedPattern)
+			}
+			if strings.Join(result, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSelectFiles_ExcludeDirs(t *testing.T) {
+	paths := []string{"src", "src/app.py", "src/__pycache__/app.pyc", "web/node_modules/lib/index.js", "lib.egg-info/PKG-INFO"}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"Nested directories", Config{ExcludeDirs: []string{"__pycache__", "node_modules/"}}, []string{"src", "src/app.py", "lib.egg-info/PKG-INFO"}},
+		{"Files are not matched", Config{ExcludeDirs: []string{"src"}}, []string{"src", "web/node_modules/lib/index.js", "lib.egg-info/PKG-INFO"}},
+		{"Glob", Config{ExcludeDirs: []string{"*.egg-info"}}, []string{"src", "src/app.py", "src/__pycache__/app.pyc", "web/node_modules/lib/index.js"}},
+		{"Forced files are kept", Config{ExcludeDirs: []string{"__pycache__"}, ForceIncludePatterns: []string{"src/__pycache__/app.pyc"}}, []string{"src/__pycache__/app.pyc"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := selectedPaths(paths, tc.config); strings.Join(result, ",") != strings.Join(tc.expected, ",") {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestSelectFiles_MatchedPattern(t *testing.T) {
+	paths := []string{"src/app.go", "docs/guide.md", "README.md"}
+
+	matched := func(config Config) map[string]string {
+		result := make(map[string]string)
+		for _, candidate := range selectFiles(paths, config) {
+			result[candidate.path] = candidate.matchedPattern
+		}
+		return result
+	}
+
+	result := matched(Config{
+		IncludePatterns:      []string{"src/*", "docs/*"},
+		ForceIncludePatterns: []string{"README.md"},
+	})
+	expected := map[string]string{"src/app.go": "src/*", "docs/guide.md": "docs/*", "README.md": "README.md"}
+	for path, pattern := range expected {
+		if result[path] != pattern {
+			t.Errorf("Expected %s to be matched by %q, got %q", path, pattern, result[path])
+		}
+	}
+
+	for path, pattern := range matched(Config{}) {
+		if pattern != "" {
+			t.Errorf("Expected no matched pattern for %s without patterns, got %q", path, pattern)
+		}
+	}
+}
+
+func TestFilterAndEnrichFiles_Symlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	target := filepath.Join(tempDir, "target.txt")
+	if err := os.WriteFile(target, []byte("shared settings\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	link := filepath.Join(tempDir, "link.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+	loop := filepath.Join(tempDir, "loop.txt")
+	if err := os.Symlink(loop, loop); err != nil {
+		t.Fatalf("Failed to create circular symlink: %v", err)
+	}
+
+	t.Run("Symlinks are skipped by default", func(t *testing.T) {
+		result, err := filterAndEnrichFiles([]string{target, link, loop}, Config{})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+		}
+		if len(result) != 1 || result[0].Path != target {
+			t.Errorf("Expected only %s, got %v", target, result)
+		}
+	})
+
+	t.Run("FollowSymlinks reads the target", func(t *testing.T) {
+		result, err := filterAndEnrichFiles([]string{link, loop}, Config{FollowSymlinks: true})
+		if err != nil {
+			t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+		}
+		if len(result) != 1 || result[0].Path != link {
+			t.Fatalf("Expected only %s, got %v", link, result)
+		}
+		if !result[0].IsRegular || result[0].Size != int64(len("shared settings\n")) {
+			t.Errorf("Expected the target's regular file info, got %+v", result[0])
+		}
+	})
+}
+
+func TestParseGeneratedAttributes(t *testing.T) {
+	content := `# Generated code
+*.pb.go linguist-generated
+/dist/** linguist-generated=true
+docs/** text eol=lf
+dist/keep.js -linguist-generated
+`
+	rules, err := parseGeneratedAttributes(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("parseGeneratedAttributes returned error: %v", err)
+	}
+
+	tests := []struct {
+		file      string
+		generated bool
+	}{
+		{"api.pb.go", true},
+		{"api/v1/service.pb.go", true},
+		{"dist/bundle.js", true},
+		{"dist/keep.js", false},
+		{"docs/index.md", false},
+		{"src/dist/bundle.js", false},
+	}
+	for _, tc := range tests {
+		if _, generated := rules.matchingPattern(tc.file); generated != tc.generated {
+			t.Errorf("matchingPattern(%q) = %v, expected %v", tc.file, generated, tc.generated)
+		}
+	}
+}
+
+func TestFilterAndEnrichFiles_Generated(t *testing.T) {
+	tempDir := t.TempDir()
+	attributes := filepath.Join(tempDir, ".gitattributes")
+	if err := os.WriteFile(attributes, []byte("*.gen.go linguist-generated\n"), 0644); err != nil {
+		t.Fatalf("Failed to create .gitattributes: %v", err)
+	}
+	generatedPath := filepath.ToSlash(filepath.Join(tempDir, "api.gen.go"))
+	mainPath := filepath.ToSlash(filepath.Join(tempDir, "main.go"))
+	for _, path := range []string{generatedPath, mainPath} {
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"Generated files are excluded", Config{GitAttributesFile: attributes}, []string{mainPath}},
+		{"IncludeGenerated keeps them", Config{GitAttributesFile: attributes, IncludeGenerated: true}, []string{generatedPath, mainPath}},
+		{"Force included files are kept", Config{GitAttributesFile: attributes, ForceIncludePatterns: []string{generatedPath}}, []string{generatedPath}},
+		{"A missing .gitattributes excludes nothing", Config{GitAttributesFile: filepath.Join(tempDir, "missing")}, []string{generatedPath, mainPath}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := filterAndEnrichFiles([]string{generatedPath, mainPath}, tc.config)
+			if err != nil {
+				t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+			}
+			var paths []string
+			for _, info := range result {
+				paths = append(paths, info.Path)
+			}
+			if !reflect.DeepEqual(paths, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, paths)
+			}
+		})
+	}
+}
+
+func TestFilterAndEnrichFiles_Languages(t *testing.T) {
+	tempDir := t.TempDir()
+	contents := map[string]string{
+		"main.go":   "package main\n",
+		"types.pyi": "x: int\n",
+		"manage":    "#!/usr/bin/env python3\nprint('hi')\n",
+		"notes.md":  "# Notes\n",
+	}
+	var paths []string
+	for _, name := range []string{"main.go", "types.pyi", "manage", "notes.md"} {
+		path := filepath.ToSlash(filepath.Join(tempDir, name))
+		if err := os.WriteFile(path, []byte(contents[name]), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{"Any language by default", Config{}, paths},
+		{"Extensions and shebangs", Config{Languages: []string{"python"}}, []string{paths[1], paths[2]}},
+		{"Several languages", Config{Languages: []string{"go", "markdown"}}, []string{paths[0], paths[3]}},
+		{"Composes with excludes", Config{Languages: []string{"python"}, ExcludePatterns: []string{paths[2]}}, []string{paths[1]}},
+		{"Force included files are kept", Config{Languages: []string{"go"}, ForceIncludePatterns: []string{paths[3]}}, []string{paths[3]}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := filterAndEnrichFiles(paths, tc.config)
+			if err != nil {
+				t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+			}
+			var included []string
+			for _, info := range result {
+				included = append(included, info.Path)
+			}
+			if !reflect.DeepEqual(included, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, included)
+			}
+		})
+	}
+
+	if !IsKnownLanguage("python") || IsKnownLanguage("cobol") {
+		t.Errorf("Expected python to be a known language and cobol not to be")
+	}
+}
+
+func TestFilterAndEnrichFiles_IncludedAndForced(t *testing.T) {
+	tempDir := t.TempDir()
+	appPath := filepath.ToSlash(filepath.Join(tempDir, "app.go"))
+	if err := os.WriteFile(appPath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	config := Config{
+		IncludePatterns:      []string{tempDir + "/*.go"},
+		ForceIncludePatterns: []string{appPath},
+	}
+	result, err := filterAndEnrichFiles([]string{appPath}, config)
+	if err != nil {
+		t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("Expected the file once, got %v", result)
+	}
+	if !result[0].IsForced {
+		t.Error("Expected force include to take precedence over include")
+	}
+	if result[0].MatchedPattern != appPath {
+		t.Errorf("Expected the force include pattern as matched pattern, got %q", result[0].MatchedPattern)
+	}
+}
+
+func TestFilterAndEnrichFiles_TextExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	customPath := filepath.ToSlash(filepath.Join(tempDir, "rules.foo"))
+	// A null byte makes the content sniffing reject the file
+	if err := os.WriteFile(customPath, []byte("rule\x00one\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	t.Setenv("MPP_NO_FILE", "1")
+
+	if result, _ := filterAndEnrichFiles([]string{customPath}, Config{}); len(result) != 0 {
+		t.Fatalf("Expected the file to be rejected as binary, got %v", result)
+	}
+
+	result, err := filterAndEnrichFiles([]string{customPath}, Config{TextExtensions: []string{".FOO"}})
+	if err != nil {
+		t.Fatalf("filterAndEnrichFiles returned error: %v", err)
+	}
+	if len(result) != 1 || !result[0].IsText || result[0].IsForced {
+		t.Fatalf("Expected the file to be kept as text without being forced, got %v", result)
+	}
+
+	result, _ = filterAndEnrichFiles([]string{customPath}, Config{TextExtensions: []string{".foo"}, ExcludeLargerThan: 4})
+	if len(result) != 0 {
+		t.Errorf("Expected the size limit to still apply, got %v", result)
+	}
+}
+
+func TestIsTextContent(t *testing.T) {
+	tests := []struct {
+		path     string
+		content  string
+		expected bool
+	}{
+		{"main.go", "package main\n", true},
+		{"image.png", "package main\n", false},
+		{"notes.unknownext", "plain text\n", true},
+		{"data.unknownext", "bin\x00ary", false},
+		{"empty.unknownext", "", false},
+		{"go.sum", "", true},
+	}
+	for _, tc := range tests {
+		if got := IsTextContent(tc.path, []byte(tc.content)); got != tc.expected {
+			t.Errorf("IsTextContent(%q) = %v, expected %v", tc.path, got, tc.expected)
+		}
+	}
+}
+
+func TestReadNulPathList(t *testing.T) {
+	paths, err := ReadNulPathList(strings.NewReader("a.go\x00my file.txt\x00\x00odd\nname.md\x00"))
+	if err != nil {
+		t.Fatalf("ReadNulPathList returned error: %v", err)
+	}
+	expected := []string{"a.go", "my file.txt", "odd\nname.md"}
+	if strings.Join(paths, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, paths)
+	}
+}
+
+func TestListExplicitFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	aPath := filepath.ToSlash(filepath.Join(tempDir, "a.go"))
+	bPath := filepath.ToSlash(filepath.Join(tempDir, "b.go"))
+
+	t.Run("Lists the given files once", func(t *testing.T) {
+		result, err := ListExplicitFiles([]string{aPath, bPath, aPath}, Config{IncludePatterns: []string{"*.md"}})
+		if err != nil {
+			t.Fatalf("ListExplicitFiles returned error: %v", err)
+		}
+		if len(result) != 2 || result[0].Path != aPath || result[1].Path != bPath {
+			t.Errorf("Expected %s and %s, got %v", aPath, bPath, result)
+		}
+	})
+
+	t.Run("Exclude patterns apply", func(t *testing.T) {
+		result, err := ListExplicitFiles([]string{aPath, bPath}, Config{ExcludePatterns: []string{bPath}})
+		if err != nil {
+			t.Fatalf("ListExplicitFiles returned error: %v", err)
+		}
+		if len(result) != 1 || result[0].Path != aPath {
+			t.Errorf("Expected only %s, got %v", aPath, result)
+		}
+	})
+
+	t.Run("Missing files and directories are rejected", func(t *testing.T) {
+		if _, err := ListExplicitFiles([]string{filepath.Join(tempDir, "missing.go")}, Config{}); err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+		if _, err := ListExplicitFiles([]string{tempDir}, Config{}); err == nil {
+			t.Error("Expected an error for a directory")
+		}
+	})
+}
+
+func TestReadPathList(t *testing.T) {
+	paths, err := ReadPathList(strings.NewReader("a.go\r\n\nmy file.txt\n# not a comment\n"))
+	if err != nil {
+		t.Fatalf("ReadPathList returned error: %v", err)
+	}
+	expected := []string{"a.go", "my file.txt", "# not a comment"}
+	if strings.Join(paths, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,97 @@
+package files
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGitAttributesFile is the .gitattributes file read for generated files
+const DefaultGitAttributesFile = ".gitattributes"
+
+// generatedAttribute is the .gitattributes attribute marking generated files
+const generatedAttribute = "linguist-generated"
+
+// generatedRule is a .gitattributes line setting or unsetting the generated attribute
+type generatedRule struct {
+	pattern   compiledPattern
+	nameOnly  bool // Pattern without a slash: matched against the file name at any depth
+	generated bool
+}
+
+// generatedRules are the generated attribute rules of a .gitattributes file, in file order
+type generatedRules []generatedRule
+
+// parseGeneratedAttributes reads the linguist-generated rules of .gitattributes content.
+// Lines not mentioning the attribute are ignored.
+func parseGeneratedAttributes(r io.Reader) (generatedRules, error) {
+	var rules generatedRules
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attribute := range fields[1:] {
+			generated, ok := parseGeneratedAttribute(attribute)
+			if !ok {
+				continue
+			}
+			pattern := strings.TrimPrefix(fields[0], "/")
+			rules = append(rules, generatedRule{
+				pattern:   compilePattern(pattern),
+				nameOnly:  !strings.Contains(fields[0], "/"),
+				generated: generated,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// parseGeneratedAttribute reports whether an attribute token sets (true) or unsets (false)
+// the generated attribute. The boolean is false for other attributes.
+func parseGeneratedAttribute(attribute string) (bool, bool) {
+	switch attribute {
+	case generatedAttribute, generatedAttribute + "=true":
+		return true, true
+	case "-" + generatedAttribute, "!" + generatedAttribute, generatedAttribute + "=false":
+		return false, true
+	}
+	return false, false
+}
+
+// readGeneratedRules reads the generated attribute rules of a .gitattributes file.
+// A missing file has no rules.
+func readGeneratedRules(path string) (generatedRules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	return parseGeneratedAttributes(file)
+}
+
+// matchingPattern reports whether a file is marked generated, and returns the pattern
+// responsible. As in Git, the last matching line wins.
+func (rules generatedRules) matchingPattern(file string) (string, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		target := file
+		if rule.nameOnly {
+			target = filepath.Base(file)
+		}
+		if rule.pattern.match(target) {
+			return rule.pattern.pattern, rule.generated
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,244 @@
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// treeEntry is a file listed by git ls-tree
+type treeEntry struct {
+	mode    string
+	objType string // "blob", or "commit" for submodules
+	size    int64
+}
+
+// ListGitFilesAt returns the files of a Git revision (commit, branch or tag) selected by the
+// patterns of config. Files are classified from their blob content, not the working tree,
+// and have the commit date of the revision as modification time.
+func ListGitFilesAt(ref string, config Config) ([]FileInfo, error) {
+	start := time.Now()
+	modTime, err := commitTime(ref)
+	if err != nil {
+		return nil, err
+	}
+	entries, paths, err := listTreeEntries(ref)
+	if err != nil {
+		return nil, err
+	}
+	config.Timings.addListing(start)
+	defer config.Timings.addFiltering(time.Now())
+
+	candidates := selectFiles(paths, config)
+
+	// Files marked generated in the revision's .gitattributes are an implicit exclude source
+	if !config.IncludeGenerated {
+		var rules generatedRules
+		if _, ok := entries[DefaultGitAttributesFile]; ok {
+			content, err := ReadGitBlob(ref, DefaultGitAttributesFile)
+			if err != nil {
+				return nil, err
+			}
+			if rules, err = parseGeneratedAttributes(bytes.NewReader(content)); err != nil {
+				return nil, fmt.Errorf("failed to read %s at %s: %w", DefaultGitAttributesFile, ref, err)
+			}
+		}
+		candidates = dropGenerated(candidates, rules, config)
+	}
+
+	return enrichConcurrently(len(candidates), config, func(i int, config Config) (FileInfo, bool) {
+		candidate := candidates[i]
+		return enrichBlob(ref, candidate, entries[candidate.path], modTime, config)
+	}), nil
+}
+
+// ReadGitBlob returns the content of a file at a Git revision. The path is relative to the
+// working directory, as listed by ListGitFilesAt.
+func ReadGitBlob(ref, path string) ([]byte, error) {
+	output, err := runGit("cat-file", "blob", ref+":./"+path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s' at %s: %w", path, ref, err)
+	}
+	return output, nil
+}
+
+// GetProjectTreeAt returns a tree of the files of a Git revision, rendered natively,
+// scoped to root ("" = whole project) and descending at most depth levels (0 = unlimited)
+func GetProjectTreeAt(ref, root string, depth int) (string, error) {
+	_, paths, err := listTreeEntries(ref)
+	if err != nil {
+		return "", err
+	}
+
+	if root != "" {
+		root = NormalizeTreeRoot(root)
+		scoped := FilterTreePaths(withoutIgnoredTreeDirs(paths), root)
+		if len(scoped) == 0 {
+			return "", fmt.Errorf("no files found under tree root '%s' at %s", root, ref)
+		}
+		return RenderTree(root, LimitTreeDepth(scoped, depth)), nil
+	}
+
+	return RenderTree(".", LimitTreeDepth(withoutIgnoredTreeDirs(paths), depth)), nil
+}
+
+// enrichBlob classifies a selected file of a Git revision from its tree entry and blob content.
+// The boolean is false when the file must be skipped.
+func enrichBlob(ref string, candidate fileCandidate, entry treeEntry, modTime time.Time, config Config) (FileInfo, bool) {
+	file := candidate.path
+
+	if entry.objType != "blob" {
+		explainSkip(config, file, "not a file at %s (%s)", ref, entry.objType)
+		return FileInfo{}, false
+	}
+	if entry.mode == "120000" {
+		explainSkip(config, file, "symlink at %s", ref)
+		return FileInfo{}, false
+	}
+
+	info := FileInfo{
+		Path:      file,
+		IsForced:  candidate.isForced,
+		Size:      entry.size,
+		ModTime:   modTime,
+		IsRegular: true,
+		Language:  detectLanguage(file),
+
+		MatchedPattern: candidate.matchedPattern,
+		AutoIncluded:   candidate.autoIncluded,
+	}
+
+	// Force included files are always considered "text" for processing
+	if candidate.isForced {
+		info.IsText = true
+		return info, true
+	}
+
+	if skipLanguage(config, file, info.Language) {
+		return FileInfo{}, false
+	}
+
+	if config.ExcludeLargerThan > 0 && info.Size > config.ExcludeLargerThan {
+		explainSkip(config, file, "larger than %d bytes (%d bytes)", config.ExcludeLargerThan, info.Size)
+		return FileInfo{}, false
+	}
+
+	content, err := ReadGitBlob(ref, file)
+	if err != nil {
+		config.reportf("Warning: %v. Skipping.\n", err)
+		return FileInfo{}, false
+	}
+
+	info.IsText = hasTextExtension(file, config.TextExtensions) || IsTextContent(file, content)
+	if !info.IsText {
+		explainSkip(config, file, "binary (non-text MIME type)")
+		return FileInfo{}, false
+	}
+	if config.StrictText && !sniffTextContent(content) {
+		explainSkip(config, file, "binary content (--strict-text)")
+		return FileInfo{}, false
+	}
+
+	if config.SkipMinified {
+		threshold := config.MinifiedLineLength
+		if threshold <= 0 {
+			threshold = DefaultMinifiedLineLength
+		}
+		if isMinifiedContent(content, threshold) {
+			explainSkip(config, file, "looks minified")
+			return FileInfo{}, false
+		}
+	}
+
+	if config.OnlyConflicts || config.WarnConflicts {
+		conflicted := hasConflictMarkers(string(content))
+		if conflicted && config.WarnConflicts {
+			config.reportf("Warning: File '%s' contains git conflict markers.\n", file)
+		}
+		if !conflicted && config.OnlyConflicts {
+			explainSkip(config, file, "no git conflict markers (--only-conflicts)")
+			return FileInfo{}, false
+		}
+	}
+
+	// Keep the content, so that the blob is not read again when writing the prompt
+	info.Content = content
+	return info, true
+}
+
+// IsTextContent checks if the content of a file is text, as IsTextFile does for files on disk,
+// sniffing the content itself when the extension does not tell
+func IsTextContent(filePath string, content []byte) bool {
+	if filepath.Base(filePath) == "go.mod" || filepath.Base(filePath) == "go.sum" {
+		return true
+	}
+
+	ext := filepath.Ext(filePath)
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return isTextMimeType(mimeType)
+	}
+	if knownTextExtensions[strings.ToLower(ext)] {
+		return true
+	}
+	return len(content) > 0 && sniffTextContent(content)
+}
+
+// listTreeEntries lists the files of a Git revision located under the working directory,
+// by path and in listing order
+func listTreeEntries(ref string) (map[string]treeEntry, []string, error) {
+	output, err := runGit("ls-tree", "-r", "-l", "-z", ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list files at %s: %w", ref, err)
+	}
+
+	entries := make(map[string]treeEntry)
+	var paths []string
+	for _, record := range strings.Split(string(output), "\x00") {
+		// Each record is "<mode> <type> <object> <size>\t<path>"
+		meta, path, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 4 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[3], 10, 64) // "-" for submodules
+		entries[path] = treeEntry{mode: fields[0], objType: fields[1], size: size}
+		paths = append(paths, path)
+	}
+	return entries, paths, nil
+}
+
+// commitTime returns the committer date of a Git revision
+func commitTime(ref string) (time.Time, error) {
+	if _, err := runGit("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return time.Time{}, fmt.Errorf("unknown revision '%s'", ref)
+	}
+	output, err := runGit("show", "-s", "--format=%ct", ref+"^{commit}")
+	if err != nil {
+		return time.Time{}, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected commit date for %s: %q", ref, output)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// runGit runs a git command and returns its standard output
+func runGit(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,198 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// languageByExtension maps lowercase file extensions to language names
+var languageByExtension = map[string]string{
+	".go":     "go",
+	".py":     "python",
+	".pyi":    "python",
+	".pyw":    "python",
+	".js":     "javascript",
+	".mjs":    "javascript",
+	".cjs":    "javascript",
+	".jsx":    "jsx",
+	".ts":     "typescript",
+	".tsx":    "tsx",
+	".java":   "java",
+	".kt":     "kotlin",
+	".scala":  "scala",
+	".c":      "c",
+	".h":      "c",
+	".cc":     "cpp",
+	".cpp":    "cpp",
+	".cxx":    "cpp",
+	".hpp":    "cpp",
+	".cs":     "csharp",
+	".rs":     "rust",
+	".rb":     "ruby",
+	".php":    "php",
+	".swift":  "swift",
+	".m":      "objective-c",
+	".lua":    "lua",
+	".pl":     "perl",
+	".r":      "r",
+	".sh":     "bash",
+	".bash":   "bash",
+	".zsh":    "zsh",
+	".fish":   "fish",
+	".ps1":    "powershell",
+	".sql":    "sql",
+	".html":   "html",
+	".htm":    "html",
+	".css":    "css",
+	".scss":   "scss",
+	".sass":   "sass",
+	".less":   "less",
+	".vue":    "vue",
+	".svelte": "svelte",
+	".json":   "json",
+	".yaml":   "yaml",
+	".yml":    "yaml",
+	".toml":   "toml",
+	".xml":    "xml",
+	".ini":    "ini",
+	".md":     "markdown",
+	".rst":    "rst",
+	".tex":    "latex",
+	".proto":  "protobuf",
+	".tf":     "terraform",
+	".dart":   "dart",
+	".ex":     "elixir",
+	".exs":    "elixir",
+	".erl":    "erlang",
+	".hs":     "haskell",
+	".ml":     "ocaml",
+	".clj":    "clojure",
+	".zig":    "zig",
+	".nim":    "nim",
+}
+
+// languageByFileName maps well-known extensionless file names to language names
+var languageByFileName = map[string]string{
+	"Makefile":    "makefile",
+	"GNUmakefile": "makefile",
+	"Dockerfile":  "dockerfile",
+	"Rakefile":    "ruby",
+	"Gemfile":     "ruby",
+}
+
+// languageByInterpreter maps shebang interpreters to language names
+var languageByInterpreter = map[string]string{
+	"sh":      "bash",
+	"bash":    "bash",
+	"zsh":     "zsh",
+	"fish":    "fish",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"Rscript": "r",
+}
+
+// Languages returns the sorted names of the languages files can be detected as
+func Languages() []string {
+	seen := make(map[string]bool)
+	for _, languages := range []map[string]string{languageByExtension, languageByFileName, languageByInterpreter} {
+		for _, language := range languages {
+			seen[language] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for language := range seen {
+		names = append(names, language)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsKnownLanguage reports whether files can be detected as the named language
+func IsKnownLanguage(name string) bool {
+	for _, language := range Languages() {
+		if language == name {
+			return true
+		}
+	}
+	return false
+}
+
+// skipLanguage reports whether Config.Languages leaves out a file of the given language
+func skipLanguage(config Config, file, language string) bool {
+	if len(config.Languages) == 0 {
+		return false
+	}
+	for _, selected := range config.Languages {
+		if language == selected {
+			return false
+		}
+	}
+	if language == "" {
+		explainSkip(config, file, "unknown language, not in --lang")
+	} else {
+		explainSkip(config, file, "language '%s' not in --lang", language)
+	}
+	return true
+}
+
+// detectLanguage returns the language of the file at path, based on its extension or,
+// for extensionless files, its shebang line. It returns "" if the language is unknown.
+func detectLanguage(path string) string {
+	base := filepath.Base(path)
+	if lang, ok := languageByFileName[base]; ok {
+		return lang
+	}
+
+	ext := strings.ToLower(filepath.Ext(base))
+	if ext != "" && ext != base {
+		return languageByExtension[ext]
+	}
+
+	return detectShebangLanguage(path)
+}
+
+// detectShebangLanguage reads only the first line of the file and maps its shebang
+// interpreter (e.g. "#!/usr/bin/env python3") to a language name
+func detectShebangLanguage(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as "-S" to reach the interpreter
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	return languageByInterpreter[interpreter]
+}
@@ -0,0 +1,28 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/base64"
+)
+
+// base64LineLength is the length of the lines of base64-encoded content, as in MIME
+const base64LineLength = 76
+
+// binaryNote opens the content of a binary file embedded base64-encoded
+const binaryNote = "[Binary file, base64-encoded]\n"
+
+// encodeBinary returns binary content base64-encoded in lines of base64LineLength characters,
+// after a line noting the encoding
+func encodeBinary(content []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	var result bytes.Buffer
+	result.Grow(len(binaryNote) + len(encoded) + len(encoded)/base64LineLength + 1)
+	result.WriteString(binaryNote)
+	for len(encoded) > base64LineLength {
+		result.WriteString(encoded[:base64LineLength] + "\n")
+		encoded = encoded[base64LineLength:]
+	}
+	result.WriteString(encoded + "\n")
+	return result.Bytes()
+}
@@ -0,0 +1,63 @@
+package prompt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+func TestEncodeBinary(t *testing.T) {
+	content := make([]byte, 100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	encoded := string(encodeBinary(content))
+	if !strings.HasPrefix(encoded, binaryNote) {
+		t.Fatalf("Expected the encoding note first, got %q", encoded)
+	}
+	lines := strings.Split(strings.TrimSuffix(strings.TrimPrefix(encoded, binaryNote), "\n"), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		if len(line) != base64LineLength {
+			t.Errorf("Expected lines of %d characters, got %d", base64LineLength, len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil {
+		t.Fatalf("Decoding failed: %v", err)
+	}
+	if string(decoded) != string(content) {
+		t.Errorf("Expected the content to round-trip, got %v", decoded)
+	}
+}
+
+func TestGenerator_BinaryBase64(t *testing.T) {
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	fileInfos := []files.FileInfo{
+		{Path: "logo.png", IsText: true, IsForced: true, Size: int64(len(binary)), IsRegular: true},
+		{Path: "notes.txt", IsText: true, IsForced: true, Size: 6, IsRegular: true},
+	}
+	reader := memoryReader{
+		"logo.png":  binary,
+		"notes.txt": "notes\n",
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+	generator.Reader = reader
+	generator.BinaryBase64 = true
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if !strings.Contains(promptText, binaryNote+base64.StdEncoding.EncodeToString([]byte(binary))+"\n") {
+		t.Errorf("Expected the PNG to be base64-encoded, got:\n%s", promptText)
+	}
+	// Forced text files are kept as is
+	if !strings.Contains(promptText, "\nnotes\n") || strings.Count(promptText, binaryNote) != 1 {
+		t.Errorf("Expected the text file to be kept as is, got:\n%s", promptText)
+	}
+}
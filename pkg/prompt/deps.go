@@ -0,0 +1,185 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// dependencyManifest is a dependency manifest summarized by IncludeDeps, with its parser.
+// A parser returns a note on the project (such as the module path) and the dependencies.
+type dependencyManifest struct {
+	name  string
+	parse func(content []byte) (string, []string, error)
+}
+
+var dependencyManifests = []dependencyManifest{
+	{"go.mod", parseGoMod},
+	{"package.json", parsePackageJSON},
+	{"requirements.txt", parseRequirements},
+}
+
+// dependencySummary returns the names of the manifests found at the root of the project (or
+// of each repository) and their parsed summary, "" when there is none
+func (g *Generator) dependencySummary() ([]string, string) {
+	prefixes := []string{""}
+	if len(g.Repos) > 0 {
+		prefixes = nil
+		for _, repo := range g.Repos {
+			prefixes = append(prefixes, repo.Label+"/")
+		}
+	}
+
+	var names []string
+	var summary strings.Builder
+	for _, prefix := range prefixes {
+		for _, manifest := range dependencyManifests {
+			path := prefix + manifest.name
+			content, err := g.contentReader().ReadContent(path)
+			if err != nil {
+				continue
+			}
+			note, deps, err := manifest.parse(content)
+			if err != nil {
+				if g.Verbosity >= VerbosityNormal {
+					fmt.Fprintf(os.Stderr, "Warning: Failed to parse '%s' for --deps: %v\n", path, err)
+				}
+				continue
+			}
+
+			if summary.Len() > 0 {
+				summary.WriteString("\n")
+			}
+			names = append(names, path)
+			summary.WriteString(path)
+			if note != "" {
+				summary.WriteString(" (" + note + ")")
+			}
+			summary.WriteString(":\n")
+			if len(deps) == 0 {
+				summary.WriteString("(no dependencies)\n")
+			}
+			for _, dep := range deps {
+				summary.WriteString("- " + dep + "\n")
+			}
+		}
+	}
+	return names, summary.String()
+}
+
+// parseGoMod lists the required modules of a go.mod file, marking the indirect ones
+func parseGoMod(content []byte) (string, []string, error) {
+	var module, goVersion string
+	var deps []string
+	inRequire := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line, comment, _ := strings.Cut(line, "//")
+		line = strings.TrimSpace(line)
+		indirect := strings.TrimSpace(comment) == "indirect"
+
+		switch {
+		case inRequire && line == ")":
+			inRequire = false
+			continue
+		case inRequire:
+		case line == "require (":
+			inRequire = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		case strings.HasPrefix(line, "module "):
+			module = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+			continue
+		case strings.HasPrefix(line, "go "):
+			goVersion = strings.TrimSpace(strings.TrimPrefix(line, "go "))
+			continue
+		default:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		dep := fields[0] + " " + fields[1]
+		if indirect {
+			dep += " (indirect)"
+		}
+		deps = append(deps, dep)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+
+	var notes []string
+	if module != "" {
+		notes = append(notes, "module "+module)
+	}
+	if goVersion != "" {
+		notes = append(notes, "go "+goVersion)
+	}
+	return strings.Join(notes, ", "), deps, nil
+}
+
+// parsePackageJSON lists the dependencies of a package.json file, sorted by name, followed
+// by the development dependencies
+func parsePackageJSON(content []byte) (string, []string, error) {
+	var manifest struct {
+		Name            string            `json:"name"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return "", nil, err
+	}
+
+	var deps []string
+	for _, group := range []struct {
+		versions map[string]string
+		suffix   string
+	}{
+		{manifest.Dependencies, ""},
+		{manifest.DevDependencies, " (dev)"},
+	} {
+		names := make([]string, 0, len(group.versions))
+		for name := range group.versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, name+" "+group.versions[name]+group.suffix)
+		}
+	}
+
+	note := ""
+	if manifest.Name != "" {
+		note = "package " + manifest.Name
+	}
+	return note, deps, nil
+}
+
+// parseRequirements lists the requirements of a requirements.txt file, without comments
+// and pip options (such as -r or --index-url)
+func parseRequirements(content []byte) (string, []string, error) {
+	var deps []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		deps = append(deps, line)
+	}
+	return "", deps, scanner.Err()
+}
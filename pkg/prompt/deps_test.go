@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+func TestParseGoMod(t *testing.T) {
+	content := `module example.com/app
+
+go 1.21
+
+require github.com/atotto/clipboard v0.1.4
+
+require (
+	golang.org/x/sys v0.15.0 // indirect
+
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace example.com/old => ../old
+`
+	note, deps, err := parseGoMod([]byte(content))
+	if err != nil {
+		t.Fatalf("parseGoMod failed: %v", err)
+	}
+	if note != "module example.com/app, go 1.21" {
+		t.Errorf("Unexpected note: %q", note)
+	}
+	expected := "github.com/atotto/clipboard v0.1.4|golang.org/x/sys v0.15.0 (indirect)|gopkg.in/yaml.v3 v3.0.1"
+	if got := strings.Join(deps, "|"); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	content := `{"name": "web", "dependencies": {"react": "^18.2.0", "axios": "1.6.0"}, "devDependencies": {"jest": "^29.0.0"}}`
+	note, deps, err := parsePackageJSON([]byte(content))
+	if err != nil {
+		t.Fatalf("parsePackageJSON failed: %v", err)
+	}
+	expected := "axios 1.6.0|react ^18.2.0|jest ^29.0.0 (dev)"
+	if note != "package web" || strings.Join(deps, "|") != expected {
+		t.Errorf("Expected package web with %s, got %q with %s", expected, note, strings.Join(deps, "|"))
+	}
+
+	if _, _, err := parsePackageJSON([]byte("{")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	content := "# Runtime\nrequests>=2.31  # HTTP\n-r base.txt\n\nflask==3.0.0\n"
+	_, deps, err := parseRequirements([]byte(content))
+	if err != nil {
+		t.Fatalf("parseRequirements failed: %v", err)
+	}
+	if got := strings.Join(deps, "|"); got != "requests>=2.31|flask==3.0.0" {
+		t.Errorf("Unexpected requirements: %s", got)
+	}
+}
+
+func TestGenerator_IncludeDeps(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "main.go", IsText: true, Size: 13, IsRegular: true},
+	}
+	reader := memoryReader{
+		"main.go":          "package main\n",
+		"go.mod":           "module example.com/app\n\ngo 1.21\n\nrequire gopkg.in/yaml.v3 v3.0.1\n",
+		"requirements.txt": "flask==3.0.0\n",
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.Reader = reader
+	generator.IncludeTree = false
+	generator.IncludeDeps = true
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "--- DEPENDENCIES (parsed from go.mod, requirements.txt) ---\n" +
+		"go.mod (module example.com/app, go 1.21):\n- gopkg.in/yaml.v3 v3.0.1\n\n" +
+		"requirements.txt:\n- flask==3.0.0\n\n--- FILE CONTENT"
+	if !strings.Contains(promptText, expected) {
+		t.Errorf("Expected the dependencies section before the files, got:\n%s", promptText)
+	}
+}
@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// filterTimeout bounds the run time of the filter command on a single file
+const filterTimeout = 30 * time.Second
+
+// runFilter pipes content through a shell command and returns its standard output.
+// The path of the filtered file is available to the command as $MPP_FILE.
+func runFilter(command, path string, content []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), filterTimeout)
+	defer cancel()
+
+	output, err := pipeThrough(ctx, command, []string{"MPP_FILE=" + path}, content)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s", filterTimeout)
+	}
+	return output, err
+}
+
+// PostProcess pipes the generated prompt through a shell command and returns its standard
+// output. There is no time limit: the command may be slow, such as a translation.
+func PostProcess(command, promptText string) (string, error) {
+	output, err := pipeThrough(context.Background(), command, nil, []byte(promptText))
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// pipeThrough runs a shell command with content on its standard input and the extra
+// environment variables env, and returns its standard output. The error of a failed
+// command includes its standard error.
+func pipeThrough(ctx context.Context, command string, env []string, content []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = bytes.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
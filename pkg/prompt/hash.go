@@ -0,0 +1,41 @@
+package prompt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+// hashLength is the number of hex digits kept from SHA-256 digests
+const hashLength = 12
+
+// shortHash returns the abbreviated SHA-256 digest of content, e.g. "sha256:ab12cd34ef56"
+func shortHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// contentHasher computes the combined hash of all the files written to a prompt.
+// Paths are hashed along with content, so renaming a file changes the result.
+type contentHasher struct {
+	h hash.Hash
+}
+
+func newContentHasher() *contentHasher {
+	return &contentHasher{h: sha256.New()}
+}
+
+// add records a file written to the prompt
+func (c *contentHasher) add(file files.FileInfo, content []byte) {
+	c.h.Write([]byte(file.Path))
+	c.h.Write([]byte{0})
+	c.h.Write(content)
+	c.h.Write([]byte{0})
+}
+
+// sum returns the abbreviated combined digest
+func (c *contentHasher) sum() string {
+	return "sha256:" + hex.EncodeToString(c.h.Sum(nil))[:hashLength]
+}
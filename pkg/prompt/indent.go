@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"bytes"
+	"strings"
+)
+
+// whitespaceSensitiveLanguages are left untouched by IndentTabWidth: their indentation
+// carries meaning (Python, YAML, Markdown code blocks) or tabs have one (Makefile recipes)
+var whitespaceSensitiveLanguages = map[string]bool{
+	"python":   true,
+	"yaml":     true,
+	"makefile": true,
+	"haskell":  true,
+	"nim":      true,
+	"sass":     true,
+	"markdown": true,
+	"rst":      true,
+}
+
+// multiLineStringDelimiters are the delimiters of the strings spanning several lines,
+// by language ("" = no such strings). The indentation of the lines inside them is part of
+// the string. Languages missing here, such as Rust, C++ or C# with their raw and verbatim
+// strings or shells with their heredocs, are not reindented.
+var multiLineStringDelimiters = map[string]string{
+	"go":         "`",
+	"javascript": "`",
+	"jsx":        "`",
+	"typescript": "`",
+	"tsx":        "`",
+	"java":       `"""`,
+	"kotlin":     `"""`,
+	"scala":      `"""`,
+	"swift":      `"""`,
+	"c":          "",
+	"css":        "",
+	"scss":       "",
+	"less":       "",
+	"json":       "",
+	"protobuf":   "",
+}
+
+// canReindent reports whether the multi-line strings of a language are known, so that
+// its indentation can be changed without altering any string
+func canReindent(language string) bool {
+	_, ok := multiLineStringDelimiters[language]
+	return ok
+}
+
+// indentWithTabs replaces each run of width spaces in the leading indentation of the lines
+// of content with a tab. Lines starting inside a multi-line string of the language are kept.
+// The language must be one canReindent accepts.
+func indentWithTabs(content []byte, width int, language string) []byte {
+	delimiter := multiLineStringDelimiters[language]
+	inString := false
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	var result bytes.Buffer
+	result.Grow(len(content))
+	for _, line := range lines {
+		if inString {
+			result.Write(line)
+		} else {
+			result.Write(tabifyIndentation(line, width))
+		}
+		if delimiter != "" {
+			inString = scanMultiLineString(string(line), delimiter, inString)
+		}
+	}
+	return result.Bytes()
+}
+
+// tabifyIndentation replaces each run of width spaces in the leading indentation of a line
+// with a tab, keeping the spaces left over and the rest of the line as is
+func tabifyIndentation(line []byte, width int) []byte {
+	indent := 0
+	for indent < len(line) && (line[indent] == ' ' || line[indent] == '\t') {
+		indent++
+	}
+
+	var result []byte
+	spaces := 0
+	for _, ch := range line[:indent] {
+		if ch == '\t' {
+			result = append(result, bytes.Repeat([]byte(" "), spaces)...)
+			result = append(result, '\t')
+			spaces = 0
+			continue
+		}
+		spaces++
+		if spaces == width {
+			result = append(result, '\t')
+			spaces = 0
+		}
+	}
+	result = append(result, bytes.Repeat([]byte(" "), spaces)...)
+	return append(result, line[indent:]...)
+}
+
+// scanMultiLineString reports whether a multi-line string is still open at the end of a line,
+// given whether one was open at its start. Delimiters within single-line strings are ignored.
+func scanMultiLineString(line, delimiter string, inString bool) bool {
+	quote := byte(0) // Quote of the single-line string being scanned
+	for i := 0; i < len(line); i++ {
+		switch {
+		case inString:
+			if strings.HasPrefix(line[i:], delimiter) {
+				inString = false
+				i += len(delimiter) - 1
+			}
+		case quote != 0:
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == quote {
+				quote = 0
+			}
+		case strings.HasPrefix(line[i:], delimiter):
+			inString = true
+			i += len(delimiter) - 1
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		}
+	}
+	return inString
+}
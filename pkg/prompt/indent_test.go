@@ -0,0 +1,90 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+func TestIndentWithTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Leading runs of spaces",
+			language: "c",
+			input:    "int f() {\n    return 1;\n          x;\n}\n",
+			expected: "int f() {\n\treturn 1;\n\t\t  x;\n}\n",
+		},
+		{
+			name:     "Inner whitespace is kept",
+			language: "c",
+			input:    "    a    =    \"    \";\n",
+			expected: "\ta    =    \"    \";\n",
+		},
+		{
+			name:     "Existing tabs are kept",
+			language: "c",
+			input:    "\t    x\n  \ty\n",
+			expected: "\t\tx\n  \ty\n",
+		},
+		{
+			name:     "Go raw strings are kept",
+			language: "go",
+			input:    "    s := `\n    raw\n        text`\n    t := \"`\"\n    u\n",
+			expected: "\ts := `\n    raw\n        text`\n\tt := \"`\"\n\tu\n",
+		},
+		{
+			name:     "Text blocks are kept",
+			language: "java",
+			input:    "    String s = \"\"\"\n        block\n        \"\"\";\n    x;\n",
+			expected: "\tString s = \"\"\"\n        block\n        \"\"\";\n\tx;\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(indentWithTabs([]byte(tc.input), 4, tc.language)); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerator_IndentTabWidth(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "app.js", IsText: true, Size: 20, IsRegular: true, Language: "javascript"},
+		{Path: "app.py", IsText: true, Size: 20, IsRegular: true, Language: "python"},
+		{Path: "app.rs", IsText: true, Size: 30, IsRegular: true, Language: "rust"},
+	}
+	reader := memoryReader{
+		"app.js": "if (a) {\n  b();\n}\n",
+		"app.py": "if a:\n  b()\n",
+		"app.rs": "let s = r#\"\n  raw\n\"#;\n",
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+	generator.Reader = reader
+	generator.IndentTabWidth = 2
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if !strings.Contains(promptText, "if (a) {\n\tb();\n}\n") {
+		t.Errorf("Expected the JavaScript indentation to use tabs, got:\n%s", promptText)
+	}
+	// Python is whitespace-sensitive and kept as is
+	if !strings.Contains(promptText, "if a:\n  b()\n") {
+		t.Errorf("Expected the Python indentation to be kept, got:\n%s", promptText)
+	}
+	// Rust raw strings are not recognized, so its indentation is kept as well
+	if !strings.Contains(promptText, "let s = r#\"\n  raw\n\"#;\n") {
+		t.Errorf("Expected the Rust indentation to be kept, got:\n%s", promptText)
+	}
+}
@@ -0,0 +1,88 @@
+package prompt
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+)
+
+// outliners reduce the content of a file to its outline, by language. With Outline,
+// files of other languages are kept whole.
+var outliners = map[string]func(content []byte) ([]byte, error){
+	"go": outlineGo,
+}
+
+// outlineGo returns the package clause, imports, type declarations and function signatures
+// of Go source, with their doc comments; function bodies, variables and constants are dropped
+func outlineGo(content []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the doc comments of the kept declarations are printed: the others would
+	// be placed at their original position, among the remaining declarations
+	comments := []*ast.CommentGroup{file.Doc}
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			decl.Body = nil
+			comments = append(comments, decl.Doc)
+			decls = append(decls, decl)
+		case *ast.GenDecl:
+			if decl.Tok != token.IMPORT && decl.Tok != token.TYPE {
+				continue
+			}
+			comments = append(comments, decl.Doc)
+			if decl.Tok == token.TYPE {
+				comments = append(comments, typeComments(decl)...)
+			}
+			decls = append(decls, decl)
+		}
+	}
+	file.Decls = decls
+	file.Comments = nil
+	for _, group := range comments {
+		if group != nil {
+			file.Comments = append(file.Comments, group)
+		}
+	}
+
+	var outline bytes.Buffer
+	if err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&outline, fset, file); err != nil {
+		return nil, err
+	}
+	return outline.Bytes(), nil
+}
+
+// typeComments returns the doc and line comments of the types of a declaration and of
+// their struct fields and interface methods
+func typeComments(decl *ast.GenDecl) []*ast.CommentGroup {
+	var comments []*ast.CommentGroup
+	for _, spec := range decl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		comments = append(comments, typeSpec.Doc, typeSpec.Comment)
+
+		var fields *ast.FieldList
+		switch typ := typeSpec.Type.(type) {
+		case *ast.StructType:
+			fields = typ.Fields
+		case *ast.InterfaceType:
+			fields = typ.Methods
+		}
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			comments = append(comments, field.Doc, field.Comment)
+		}
+	}
+	return comments
+}
@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+const outlineSource = `// Package shapes computes areas.
+package shapes
+
+import "math"
+
+// Pi is kept in the outline only through the signatures using it
+const Pi = math.Pi
+
+// Shape has an area
+type Shape interface {
+	Area() float64 // In square units
+}
+
+// Circle is a round shape
+type Circle struct {
+	Radius float64 // Never negative
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64 {
+	// The body is left out
+	return Pi * c.Radius * c.Radius
+}
+`
+
+func TestOutlineGo(t *testing.T) {
+	outline, err := outlineGo([]byte(outlineSource))
+	if err != nil {
+		t.Fatalf("outlineGo failed: %v", err)
+	}
+
+	expected := `// Package shapes computes areas.
+package shapes
+
+import "math"
+
+// Shape has an area
+type Shape interface {
+	Area() float64 // In square units
+}
+
+// Circle is a round shape
+type Circle struct {
+	Radius float64 // Never negative
+}
+
+// Area returns the area of the circle
+func (c Circle) Area() float64
+`
+	if string(outline) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, outline)
+	}
+
+	if _, err := outlineGo([]byte("package broken\nfunc {")); err == nil {
+		t.Error("Expected an error for invalid Go source")
+	}
+}
+
+func TestGenerator_Outline(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "shapes.go", IsText: true, Size: int64(len(outlineSource)), IsRegular: true, Language: "go"},
+		{Path: "README.md", IsText: true, Size: 9, IsRegular: true, Language: "markdown"},
+		{Path: "broken.go", IsText: true, Size: 20, IsRegular: true, Language: "go"},
+	}
+	reader := memoryReader{
+		"shapes.go": outlineSource,
+		"README.md": "# Shapes\n",
+		"broken.go": "package broken\nfunc {",
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.Reader = reader
+	generator.IncludeTree = false
+	generator.Verbosity = VerbosityQuiet
+	generator.Outline = true
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if strings.Contains(promptText, "The body is left out") || !strings.Contains(promptText, "func (c Circle) Area() float64\n") {
+		t.Errorf("Expected the Go file to be outlined, got:\n%s", promptText)
+	}
+	// Unsupported languages and unparsable files are kept whole
+	if !strings.Contains(promptText, "# Shapes\n") || !strings.Contains(promptText, "package broken\nfunc {") {
+		t.Errorf("Expected the other files to be kept whole, got:\n%s", promptText)
+	}
+}
@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is the minimum delay between two progress updates
+const progressInterval = 100 * time.Millisecond
+
+// progress reports how many files have been read, throttled to one update per
+// progressInterval. It is safe for concurrent use.
+type progress struct {
+	out   io.Writer
+	total int
+	done  atomic.Int64
+
+	mu         sync.Mutex
+	lastUpdate time.Time
+	lineLength int // Length of the progress line shown (0 = none)
+}
+
+// newProgress creates a progress reporter for total files. Nothing is shown
+// before progressInterval has elapsed, so quick runs stay silent.
+func newProgress(out io.Writer, total int) *progress {
+	return &progress{out: out, total: total, lastUpdate: time.Now()}
+}
+
+// step records a file as read, updating the display when due
+func (p *progress) step() {
+	if p == nil {
+		return
+	}
+	count := p.done.Add(1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.lastUpdate) < progressInterval {
+		return
+	}
+	p.lastUpdate = time.Now()
+	line := fmt.Sprintf("Reading file %d/%d...", count, p.total)
+	fmt.Fprint(p.out, "\r"+line)
+	p.lineLength = len(line)
+}
+
+// finish clears the progress line if one was shown
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lineLength > 0 {
+		fmt.Fprint(p.out, "\r"+strings.Repeat(" ", p.lineLength)+"\r")
+		p.lineLength = 0
+	}
+}
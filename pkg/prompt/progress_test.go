@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	t.Run("Quick runs stay silent", func(t *testing.T) {
+		var out strings.Builder
+		p := newProgress(&out, 3)
+		p.step()
+		p.step()
+		p.finish()
+		if out.Len() != 0 {
+			t.Errorf("Expected no output, got %q", out.String())
+		}
+	})
+
+	t.Run("Reports the count when due and clears the line", func(t *testing.T) {
+		var out strings.Builder
+		p := newProgress(&out, 20)
+		p.lastUpdate = time.Now().Add(-time.Second)
+		p.step()
+		if !strings.Contains(out.String(), "Reading file 1/20...") {
+			t.Errorf("Expected a progress line, got %q", out.String())
+		}
+		p.finish()
+		if !strings.HasSuffix(out.String(), "\r") {
+			t.Errorf("Expected the progress line to be cleared, got %q", out.String())
+		}
+	})
+
+	t.Run("Concurrent steps are all counted", func(t *testing.T) {
+		var out strings.Builder
+		p := newProgress(&out, 100)
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.step()
+			}()
+		}
+		wg.Wait()
+		if got := p.done.Load(); got != 100 {
+			t.Errorf("Expected 100 steps, got %d", got)
+		}
+	})
+
+	t.Run("Nil progress is a no-op", func(t *testing.T) {
+		var p *progress
+		p.step()
+		p.finish()
+	})
+}
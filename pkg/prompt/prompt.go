@@ -3,17 +3,23 @@
 package prompt
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/briossant/make-project-prompt/pkg/files"
 )
 
 // ContentItem represents a piece of content to include in the prompt
 type ContentItem struct {
-	Type         string           // "question", "file_group"
-	Content      string           // The actual content for questions
+	Type         string           // "question", "file_group", "extra_context", "last_words"
+	Content      string           // The actual content for questions, extra context, and last words
 	Order        int              // Original position in args (for --raw mode)
 	FilePatterns []string         // For file_group type: the patterns to match
 	Files        []files.FileInfo // For file_group type: the matched files
@@ -21,17 +27,97 @@ type ContentItem struct {
 
 // Generator handles prompt generation
 type Generator struct {
-	Files        []files.FileInfo
-	Question     string // Deprecated: use Questions for new code
-	Questions    []ContentItem
-	ContentItems []ContentItem // Ordered list of all content for raw mode
-	MaxFileSize  int64
-	QuietMode    bool
-	RawMode      bool
-	IncludeTree  bool // Whether to include project tree
+	Files          []files.FileInfo
+	Question       string // Deprecated: use Questions for new code
+	Questions      []ContentItem
+	ContentItems   []ContentItem // Ordered list of all content for raw mode
+	MaxFileSize    int64
+	Verbosity      Verbosity // Diagnostics written on stderr
+	RawMode        bool
+	IncludeTree    bool   // Whether to include project tree
+	TreeOnly       bool   // Leave the file content out, keeping the tree and questions (default mode)
+	TreeRoot       string // Directory the project tree is rooted at ("" = whole project)
+	TreeDepth      int    // Maximum depth of the project tree (0 = unlimited)
+	TreeMatched    bool   // Build the project tree from the included files only
+	UseTreeCommand bool   // Render the project tree with the external 'tree' command
+	GitRef         string // Git revision the files and tree are read from ("" = working tree)
+	HeadLines      int    // Lines kept from the start of oversized files (0 = none)
+	TailLines      int    // Lines kept from the end of oversized files (0 = none)
+
+	QuestionPrefix string // Text prepended to every question
+	QuestionSuffix string // Text appended to every question
+
+	NumberQuestions bool           // Number the questions ("1. ...") in the default mode footer
+	QuestionsOrder  QuestionsOrder // Order of the questions in the default mode footer ("" = QuestionsAsGiven)
+	LabelQuestions  bool           // Open each raw mode question following files with "Regarding <files>:"
+
+	AnnotateLanguage bool // Add the detected language to file headers
+	GroupByPattern   bool // Group files under a header naming the pattern that matched them (default mode)
+
+	RelativeTo  string // Directory the displayed file paths are made relative to ("" = as listed)
+	StripPrefix string // Prefix removed from the displayed file paths
+
+	HashContent bool // Add a short content hash to file headers and a combined hash of all files at the end
+
+	FileMetadata bool // Add a compact size/modification date/language line after file headers
+
+	ShowProgress bool // Report the number of files read on stderr during long runs
+
+	DedupeBlankLines bool // Drop trailing blank lines from file content so files are separated by exactly one blank line
+
+	NoEndMarkers bool // Leave out the END FILE markers closing the files (default mode)
+	Compact      bool // Open files with a single "### path" line, without END FILE markers or blank lines between files (not in raw mode)
+
+	Reader ContentReader // Source of the file content (nil = GitRef's blobs or the filesystem)
+	Repos  []Repo        // Repositories the files are listed from, under their label (nil = the working directory)
+
+	IncludeDeps bool // Add a section summarizing the dependency manifests (go.mod, package.json, requirements.txt) found at the root
+
+	FilterCommand string // Shell command each file's content is piped through before inclusion ("" = none)
+
+	Outline bool // Reduce the files of supported languages (Go) to their declarations and signatures, without bodies
+
+	LongLineThreshold int // Warn about files holding lines longer than this many characters (0 = no warning)
+
+	IncludeEmpty bool // Keep empty files, force included ones included (skipped by default)
+
+	BinaryBase64 bool // Embed the content of force included binary files base64-encoded instead of raw
+
+	IndentTabWidth int // Replace each run of this many leading spaces with a tab, in the languages whose multi-line strings are known and not whitespace-sensitive (0 = off)
+
+	FileManifest bool // List the paths of the written files in a section closing the file content
+
+	MaxTotalBytes int64 // Stop adding files once the next one would bring the embedded content above this many bytes (0 = no limit)
+
+	MaxTokens int        // Estimated token budget of the prompt, warned about when exceeded (0 = no limit)
+	AutoTrim  bool       // Drop the least relevant files until the prompt fits MaxTokens
+	Ranker    FileRanker // Orders the files for AutoTrim, most relevant first (nil = RelevanceRanker(nil))
+
+	ScanSecrets    bool            // Look for secret-like substrings (keys, tokens) in the embedded content, reported by SecretFindings
+	Redact         bool            // Replace secret-like substrings with ***REDACTED*** in the embedded content
+	SecretPatterns []SecretPattern // Patterns scanned and redacted in addition to the built-in ones
+
+	hasher   *contentHasher // Combined hash of the files written so far (when HashContent is set)
+	progress *progress      // Progress of the files read (when ShowProgress is set)
+	written  []string       // Displayed paths of the files written so far (when FileManifest is set)
+	readTime time.Duration  // Time spent reading the files during the last Generate
+
+	totalBytes   int64 // Bytes of file content embedded so far (when MaxTotalBytes is set)
+	totalReached bool  // Whether a file was left out for MaxTotalBytes, leaving out all the following ones
+
+	secrets []SecretFinding // Secret-like substrings found so far (when ScanSecrets is set)
+
+	RoleMessage  string // Text placed at the very top of the prompt (e.g. "You are a Go expert")
+	ExtraContext string // Text placed after the file content
+	LastWords    string // Text placed at the very end of the prompt
+
+	BeginMarker string // Line placed before the whole prompt, for scripts extracting it ("" = none)
+	EndMarker   string // Line placed after the whole prompt ("" = none)
+
+	Template string // text/template source rendering the whole prompt from TemplateData ("" = built-in layout)
 }
 
-// NewGenerator creates a new prompt generator
+// NewGenerator creates a new prompt generator, with quiet or normal verbosity
 func NewGenerator(fileInfos []files.FileInfo, question string, quietMode bool) *Generator {
 	questions := []ContentItem{}
 	if question != "" && question != "[YOUR QUESTION HERE]" {
@@ -41,12 +127,16 @@ func NewGenerator(fileInfos []files.FileInfo, question string, quietMode bool) *
 			Order:   0,
 		})
 	}
+	verbosity := VerbosityNormal
+	if quietMode {
+		verbosity = VerbosityQuiet
+	}
 	return &Generator{
 		Files:       fileInfos,
 		Question:    question, // Keep for backward compatibility
 		Questions:   questions,
 		MaxFileSize: 1048576, // 1MB default max file size
-		QuietMode:   quietMode,
+		Verbosity:   verbosity,
 		IncludeTree: true,
 		RawMode:     false,
 	}
@@ -61,6 +151,46 @@ func (g *Generator) AddQuestion(content string, order int) {
 	})
 }
 
+// DedupeFileGroups removes files that already appeared in an earlier file_group item,
+// so each path is emitted only once (first occurrence wins). A file force included by a
+// later group stays at its first position but is marked forced, as force inclusion takes
+// precedence. It returns the updated items and the paths that were suppressed, in order
+// of suppression.
+func DedupeFileGroups(items []ContentItem) ([]ContentItem, []string) {
+	type position struct{ item, file int }
+	seen := make(map[string]position)
+	var suppressed []string
+	result := make([]ContentItem, 0, len(items))
+
+	for _, item := range items {
+		if item.Type != "file_group" {
+			result = append(result, item)
+			continue
+		}
+
+		unique := make([]files.FileInfo, 0, len(item.Files))
+		for _, file := range item.Files {
+			if first, ok := seen[file.Path]; ok {
+				if file.IsForced {
+					kept := unique // The first occurrence may be in the current group
+					if first.item < len(result) {
+						kept = result[first.item].Files
+					}
+					kept[first.file].IsForced = true
+				}
+				suppressed = append(suppressed, file.Path)
+				continue
+			}
+			seen[file.Path] = position{item: len(result), file: len(unique)}
+			unique = append(unique, file)
+		}
+		item.Files = unique
+		result = append(result, item)
+	}
+
+	return result, suppressed
+}
+
 // SetMaxFileSize sets the maximum file size for inclusion in the prompt
 func (g *Generator) SetMaxFileSize(size int64) {
 	g.MaxFileSize = size
@@ -68,10 +198,81 @@ func (g *Generator) SetMaxFileSize(size int64) {
 
 // Generate creates the prompt with file content and project structure
 func (g *Generator) Generate() (string, int, error) {
-	if g.RawMode {
-		return g.generateRawMode()
+	promptText, fileCount, err := g.generate()
+	if err != nil || g.MaxTokens <= 0 {
+		return promptText, fileCount, err
+	}
+	if tokens := EstimateTokens(promptText); tokens > g.MaxTokens {
+		if g.AutoTrim {
+			return g.autoTrim(promptText, fileCount)
+		}
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: The prompt is ~%d tokens, above --max-tokens %d (use --auto-trim to drop the least relevant files).\n", tokens, g.MaxTokens)
+		}
+	}
+	return promptText, fileCount, nil
+}
+
+// generate creates the prompt from the current files, once
+func (g *Generator) generate() (string, int, error) {
+	g.hasher = nil
+	if g.HashContent {
+		g.hasher = newContentHasher()
+	}
+	g.written = nil
+	g.readTime = 0
+	g.totalBytes = 0
+	g.totalReached = false
+	g.secrets = nil
+	g.progress = nil
+	if g.ShowProgress {
+		g.progress = newProgress(os.Stderr, g.fileTotal())
+		defer g.progress.finish()
+	}
+
+	var promptText string
+	var fileCount int
+	var err error
+	switch {
+	case g.Template != "":
+		promptText, fileCount, err = g.generateTemplateMode()
+	case g.RawMode:
+		promptText, fileCount, err = g.generateRawMode()
+	default:
+		promptText, fileCount, err = g.generateDefaultMode()
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return g.wrapMarkers(promptText), fileCount, nil
+}
+
+// wrapMarkers places BeginMarker and EndMarker on lines of their own around the prompt
+func (g *Generator) wrapMarkers(promptText string) string {
+	if g.BeginMarker != "" {
+		promptText = g.BeginMarker + "\n" + promptText
+	}
+	if g.EndMarker != "" {
+		if !strings.HasSuffix(promptText, "\n") {
+			promptText += "\n"
+		}
+		promptText += g.EndMarker + "\n"
 	}
-	return g.generateDefaultMode()
+	return promptText
+}
+
+// fileTotal returns the number of files the prompt may include
+func (g *Generator) fileTotal() int {
+	if g.RawMode && g.Template == "" {
+		total := 0
+		for _, item := range g.rawContentItems() {
+			if item.Type == "file_group" {
+				total += len(item.Files)
+			}
+		}
+		return total
+	}
+	return len(g.Files)
 }
 
 // generateDefaultMode creates the prompt in default mode (with pre-written messages)
@@ -79,116 +280,365 @@ func (g *Generator) generateDefaultMode() (string, int, error) {
 	var promptContent strings.Builder
 	fileCounter := 0
 
+	// Role message
+	if g.RoleMessage != "" {
+		promptContent.WriteString(g.RoleMessage + "\n\n")
+	}
+
 	// Introduction
 	promptContent.WriteString("Here is the context of my current project. Analyze the structure and content of the provided files to answer my question.\n\n")
 
 	// Project structure via 'tree'
 	if g.IncludeTree {
-		promptContent.WriteString("--- PROJECT STRUCTURE (based on 'tree', may differ slightly from included files) ---\n")
-		projectTree, err := files.GetProjectTree()
+		header, projectTree, err := g.projectTree()
+		promptContent.WriteString("--- PROJECT STRUCTURE (" + header + ") ---\n")
 		if err != nil {
-			if !g.QuietMode {
+			if g.Verbosity >= VerbosityNormal {
 				fmt.Fprintf(os.Stderr, "Warning: Failed to get project tree: %v\n", err)
 			}
-			promptContent.WriteString("Error running tree command.\n")
+			promptContent.WriteString("Error building project tree.\n")
 		} else {
 			promptContent.WriteString(projectTree)
 		}
 		promptContent.WriteString("\n")
 	}
 
+	// Dependencies parsed from the manifests
+	if g.IncludeDeps {
+		if names, summary := g.dependencySummary(); summary != "" {
+			promptContent.WriteString("--- DEPENDENCIES (parsed from " + strings.Join(names, ", ") + ") ---\n" + summary + "\n")
+		} else if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: No dependency manifest (go.mod, package.json, requirements.txt) found for --deps.\n")
+		}
+	}
+
+	// Files auto-included as context (README, docs), ahead of the files selected by the patterns
+	if autoIncluded := g.autoIncludedFiles(); len(autoIncluded) > 0 && !g.TreeOnly {
+		promptContent.WriteString("--- AUTO-INCLUDED CONTEXT (README and docs, added regardless of -i/-e/-f options) ---\n")
+		fileCounter += g.writeFileList(&promptContent, autoIncluded)
+		promptContent.WriteString("\n--- END OF AUTO-INCLUDED CONTEXT ---\n\n")
+	}
+
 	// Content of relevant files
-	promptContent.WriteString("--- FILE CONTENT (based on git ls-files, respecting .gitignore and -i/-e/-f options) ---\n")
+	if !g.TreeOnly {
+		if g.GitRef != "" {
+			promptContent.WriteString("--- FILE CONTENT (at " + g.GitRef + ", respecting -i/-e/-f options) ---\n")
+		} else {
+			promptContent.WriteString("--- FILE CONTENT (based on git ls-files, respecting .gitignore and -i/-e/-f options) ---\n")
+		}
+
+		fileCounter += g.writeFiles(&promptContent)
 
-	fileCounter = g.writeFiles(&promptContent)
+		promptContent.WriteString("\n--- END OF FILE CONTENT ---\n")
+		if g.HashContent {
+			promptContent.WriteString(g.contentHashLine() + "\n")
+		}
+		if g.FileManifest {
+			promptContent.WriteString("\n" + g.fileManifest())
+		}
+	}
 
-	promptContent.WriteString("\n--- END OF FILE CONTENT ---\n")
+	// Additional context
+	if g.ExtraContext != "" {
+		promptContent.WriteString("\n--- ADDITIONAL CONTEXT ---\n" + g.ExtraContext + "\n")
+	}
 
 	// Final question(s) - accumulate all questions
-	if len(g.Questions) > 0 {
-		promptContent.WriteString("\nBased on the context provided above, answer the following question:\n\n")
-		for _, q := range g.Questions {
-			promptContent.WriteString(q.Content + "\n")
+	questions := g.footerQuestions()
+	if len(questions) > 0 {
+		noun := "question"
+		if len(questions) > 1 {
+			noun = "questions"
+		}
+		promptContent.WriteString("\nBased on the context provided above, answer the following " + noun + ":\n\n")
+		for i, q := range questions {
+			if g.NumberQuestions {
+				promptContent.WriteString(strconv.Itoa(i+1) + ". ")
+			}
+			promptContent.WriteString(g.formatQuestion(q) + "\n")
 		}
-	} else if g.Question != "" && g.Question != "[YOUR QUESTION HERE]" {
-		// Backward compatibility: use old Question field if Questions is empty
-		promptContent.WriteString("\nBased on the context provided above, answer the following question:\n\n")
-		promptContent.WriteString(g.Question + "\n")
+	}
+
+	// Last words
+	if g.LastWords != "" {
+		promptContent.WriteString("\n" + g.LastWords + "\n")
 	}
 
 	return promptContent.String(), fileCounter, nil
 }
 
+// footerQuestions returns the questions closing a default mode prompt
+func (g *Generator) footerQuestions() []string {
+	var questions []string
+	for _, q := range g.orderedQuestions() {
+		questions = append(questions, q.Content)
+	}
+	if len(questions) == 0 && g.Question != "" && g.Question != "[YOUR QUESTION HERE]" {
+		// Backward compatibility: use old Question field if Questions is empty
+		questions = append(questions, g.Question)
+	}
+	return questions
+}
+
+// projectTree returns the project structure section, with a header describing how it was built
+func (g *Generator) projectTree() (string, string, error) {
+	if g.TreeMatched {
+		paths := make([]string, 0, len(g.Files))
+		for _, file := range g.Files {
+			paths = append(paths, filepath.ToSlash(file.Path))
+		}
+		label := "."
+		if g.TreeRoot != "" {
+			label = g.TreeRoot
+			paths = files.FilterTreePaths(paths, g.TreeRoot)
+		}
+		return "included files only", files.RenderTree(label, files.LimitTreeDepth(paths, g.TreeDepth)), nil
+	}
+
+	if g.GitRef != "" {
+		tree, err := files.GetProjectTreeAt(g.GitRef, g.TreeRoot, g.TreeDepth)
+		return "at " + g.GitRef + ", may differ slightly from included files", tree, err
+	}
+
+	if len(g.Repos) > 0 {
+		var trees []string
+		for _, repo := range g.Repos {
+			tree, err := files.GetRepoProjectTree(repo.Dir, repo.Label, g.TreeDepth)
+			if err != nil {
+				return "", "", fmt.Errorf("repository '%s': %w", repo.Label, err)
+			}
+			trees = append(trees, tree)
+		}
+		return "one tree per repository, based on git ls-files, may differ slightly from included files", strings.Join(trees, "\n"), nil
+	}
+
+	if g.TreeRoot != "" {
+		tree, err := files.GetScopedProjectTree(g.TreeRoot, g.TreeDepth)
+		return "rooted at '" + g.TreeRoot + "', may differ slightly from included files", tree, err
+	}
+
+	if g.UseTreeCommand {
+		tree, err := files.GetExternalProjectTree(g.TreeDepth)
+		return "based on 'tree', may differ slightly from included files", tree, err
+	}
+
+	tree, err := files.GetProjectTreeWithDepth(g.TreeDepth)
+	return "based on git ls-files, may differ slightly from included files", tree, err
+}
+
 // generateRawMode creates the prompt in raw mode (minimal formatting, position-aware)
 func (g *Generator) generateRawMode() (string, int, error) {
 	var promptContent strings.Builder
 	fileCounter := 0
 
+	if g.RoleMessage != "" {
+		promptContent.WriteString(g.RoleMessage + "\n\n")
+	}
+
 	// In raw mode: interleave questions and files based on ContentItems order
-	if len(g.ContentItems) > 0 {
-		// Process content items in order
-		for _, item := range g.ContentItems {
-			if item.Type == "question" {
-				promptContent.WriteString(item.Content + "\n\n")
-			} else if item.Type == "file_group" {
-				// Write files for this specific group
-				count := g.writeFileGroup(&promptContent, item.Files)
-				fileCounter += count
+	var regarding []string // Files written since the last question, for LabelQuestions
+	for _, item := range g.rawContentItems() {
+		switch item.Type {
+		case "question":
+			if g.LabelQuestions && len(regarding) > 0 {
+				promptContent.WriteString("Regarding " + strings.Join(regarding, ", ") + ":\n")
+				regarding = nil
+			}
+			promptContent.WriteString(g.formatQuestion(item.Content) + "\n\n")
+		case "extra_context", "last_words":
+			promptContent.WriteString(item.Content + "\n\n")
+		case "file_group":
+			// Write files for this specific group
+			count := g.writeFileGroup(&promptContent, item.Files)
+			fileCounter += count
+			for _, file := range item.Files {
+				regarding = append(regarding, g.displayPath(file.Path))
 			}
 		}
-	} else {
-		// Fallback: write all files, then all questions
-		fileCounter = g.writeFiles(&promptContent)
-		for _, q := range g.Questions {
-			promptContent.WriteString("\n" + q.Content + "\n")
-		}
+	}
+
+	if g.HashContent {
+		promptContent.WriteString(g.contentHashLine() + "\n")
+	}
+	if g.FileManifest {
+		promptContent.WriteString(g.fileManifest())
 	}
 
 	return promptContent.String(), fileCounter, nil
 }
 
-// writeFileGroup writes a specific group of files
-func (g *Generator) writeFileGroup(builder *strings.Builder, fileList []files.FileInfo) int {
-	fileCounter := 0
-
-	for _, file := range fileList {
-		// Skip if not a regular file
-		if !file.IsRegular {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Warning: File '%s' is not a regular file. Skipping.\n", file.Path)
-			}
-			continue
+// rawContentItems returns the content items of a raw mode prompt sorted by Order.
+// When ContentItems is empty, they are built from Files (as a single group) and Questions.
+// ExtraContext and LastWords are added at the end, unless ContentItems already positions them.
+func (g *Generator) rawContentItems() []ContentItem {
+	items := append([]ContentItem{}, g.ContentItems...)
+	if len(items) == 0 {
+		if len(g.Files) > 0 {
+			items = append(items, ContentItem{
+				Type:         "file_group",
+				FilePatterns: []string{"*"},
+				Files:        g.Files,
+				Order:        -1, // Files come before the questions
+			})
 		}
+		items = append(items, g.Questions...)
+	}
 
-		// Skip if file is too large (unless force included)
-		if !file.IsForced && file.Size > g.MaxFileSize {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' because it is too large (> 1MiB).\n", file.Path)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Order < items[j].Order
+	})
+
+	hasType := func(itemType string) bool {
+		for _, item := range items {
+			if item.Type == itemType {
+				return true
 			}
-			continue
 		}
+		return false
+	}
+	if g.ExtraContext != "" && !hasType("extra_context") {
+		items = append(items, ContentItem{Type: "extra_context", Content: g.ExtraContext})
+	}
+	if g.LastWords != "" && !hasType("last_words") {
+		items = append(items, ContentItem{Type: "last_words", Content: g.LastWords})
+	}
+	return items
+}
 
-		// Skip if not a text file (unless force included)
-		if !file.IsForced && !file.IsText {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' (non-text file).\n", file.Path)
-			}
-			continue
+// formatQuestion wraps a question with the configured prefix and suffix
+func (g *Generator) formatQuestion(question string) string {
+	return g.QuestionPrefix + question + g.QuestionSuffix
+}
+
+// compactHeaderPrefix opens the single line separating files in compact mode
+const compactHeaderPrefix = "### "
+
+// fileHeader returns the separator line opening a file, annotated with the file's
+// language when AnnotateLanguage is set and the language is known, and with the
+// content hash when HashContent is set. The metadata line follows when FileMetadata is set.
+func (g *Generator) fileHeader(file files.FileInfo, content []byte) string {
+	// Raw mode keeps its END FILE markers, which separate the files from the questions
+	compact := g.Compact && !g.RawMode
+	header := "--- FILE: " + g.displayPath(file.Path)
+	if compact {
+		header = compactHeaderPrefix + g.displayPath(file.Path)
+	}
+	if g.AnnotateLanguage && file.Language != "" {
+		header += " (" + file.Language + ")"
+	}
+	if g.HashContent {
+		header += " [" + shortHash(content) + "]"
+	}
+	if !compact {
+		header += " ---"
+	}
+	if g.FileMetadata {
+		header += "\n" + fileMetadata(file)
+	}
+	return header
+}
+
+// fileMetadata returns a compact line describing a file's size, modification date and language
+func fileMetadata(file files.FileInfo) string {
+	line := "size: " + formatSize(file.Size) + ", modified: " + file.ModTime.Format("2006-01-02")
+	if file.Language != "" {
+		line += ", language: " + file.Language
+	}
+	return line
+}
+
+// formatSize formats a byte count with a binary unit and one decimal (e.g. "1.2KB")
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return strconv.FormatInt(size, 10) + "B"
+	}
+	value := float64(size) / unit
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + suffixes[i]
+}
+
+// recordContent adds a file written to the prompt to the combined hash and the manifest
+func (g *Generator) recordContent(file files.FileInfo, content []byte) {
+	if g.hasher != nil {
+		g.hasher.add(file, content)
+	}
+	if g.FileManifest {
+		g.written = append(g.written, g.displayPath(file.Path))
+	}
+}
+
+// fileManifest returns the section listing the paths of all written files
+func (g *Generator) fileManifest() string {
+	var manifest strings.Builder
+	manifest.WriteString("--- INCLUDED FILES (" + strconv.Itoa(len(g.written)) + ") ---\n")
+	for _, path := range g.written {
+		manifest.WriteString("- " + path + "\n")
+	}
+	return manifest.String()
+}
+
+// contentHashLine returns the line holding the combined hash of all written files
+func (g *Generator) contentHashLine() string {
+	return "--- CONTENT HASH: " + g.hasher.sum() + " ---"
+}
+
+// fileFooter returns the separator line closing a file
+func (g *Generator) fileFooter(file files.FileInfo) string {
+	return "--- END FILE: " + g.displayPath(file.Path) + " ---"
+}
+
+// displayPath rewrites a file path for display according to RelativeTo and StripPrefix.
+// Files are always read from their real path.
+func (g *Generator) displayPath(path string) string {
+	if g.RelativeTo != "" {
+		if rel, err := relativePath(g.RelativeTo, path); err == nil {
+			path = rel
 		}
+	}
+	if g.StripPrefix != "" {
+		path = strings.TrimPrefix(path, g.StripPrefix)
+	}
+	return path
+}
 
-		// Read file content
-		content, err := os.ReadFile(file.Path)
-		if err != nil {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to read content of '%s': %v. Skipping.\n", file.Path, err)
-			}
+// relativePath returns path relative to dir, both being resolved from the working directory
+func relativePath(dir, path string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// writeFileGroup writes a specific group of files
+func (g *Generator) writeFileGroup(builder *strings.Builder, fileList []files.FileInfo) int {
+	fileCounter := 0
+
+	for _, file := range fileList {
+		content, ok := g.readFileContent(file)
+		if !ok {
 			continue
 		}
 
 		// Add file content to prompt
-		builder.WriteString("--- FILE: " + file.Path + " ---\n")
-		builder.Write(content)
-		builder.WriteString("\n--- END FILE: " + file.Path + " ---\n\n")
+		builder.WriteString(g.fileHeader(file, content) + "\n")
+		g.writeFileBody(builder, content)
+		builder.WriteString(g.fileFooter(file) + "\n\n")
+		g.recordContent(file, content)
 
 		fileCounter++
 	}
@@ -196,51 +646,328 @@ func (g *Generator) writeFileGroup(builder *strings.Builder, fileList []files.Fi
 	return fileCounter
 }
 
-// writeFiles writes file content to the builder and returns the count
+// writeFiles writes the content of the files selected by the patterns to the builder and returns the count
 func (g *Generator) writeFiles(builder *strings.Builder) int {
+	if !g.GroupByPattern {
+		return g.writeFileList(builder, g.selectedFiles())
+	}
+
 	fileCounter := 0
+	for _, group := range groupFilesByPattern(g.selectedFiles()) {
+		if group.pattern == "" {
+			builder.WriteString("\n=== Other files ===\n")
+		} else {
+			builder.WriteString("\n=== Files matching " + group.pattern + " ===\n")
+		}
+		fileCounter += g.writeFileList(builder, group.files)
+	}
+	return fileCounter
+}
 
+// autoIncludedFiles returns the files added as context regardless of the patterns
+func (g *Generator) autoIncludedFiles() []files.FileInfo {
+	var autoIncluded []files.FileInfo
 	for _, file := range g.Files {
-		// Skip if not a regular file
-		if !file.IsRegular {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Warning: File '%s' is not a regular file. Skipping.\n", file.Path)
-			}
-			continue
+		if file.AutoIncluded {
+			autoIncluded = append(autoIncluded, file)
 		}
+	}
+	return autoIncluded
+}
 
-		// Skip if file is too large (unless force included)
-		if !file.IsForced && file.Size > g.MaxFileSize {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' because it is too large (> 1MiB).\n", file.Path)
-			}
+// selectedFiles returns the files selected by the patterns, without the auto-included ones
+func (g *Generator) selectedFiles() []files.FileInfo {
+	var selected []files.FileInfo
+	for _, file := range g.Files {
+		if !file.AutoIncluded {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
+
+// patternGroup is a list of files selected by the same pattern
+type patternGroup struct {
+	pattern string
+	files   []files.FileInfo
+}
+
+// groupFilesByPattern groups files by their matched pattern, in order of first appearance
+func groupFilesByPattern(fileList []files.FileInfo) []patternGroup {
+	var groups []patternGroup
+	index := make(map[string]int)
+	for _, file := range fileList {
+		i, ok := index[file.MatchedPattern]
+		if !ok {
+			i = len(groups)
+			index[file.MatchedPattern] = i
+			groups = append(groups, patternGroup{pattern: file.MatchedPattern})
+		}
+		groups[i].files = append(groups[i].files, file)
+	}
+	return groups
+}
+
+// writeFileList writes the files of a list in default mode layout and returns the count
+func (g *Generator) writeFileList(builder *strings.Builder, fileList []files.FileInfo) int {
+	fileCounter := 0
+
+	for _, file := range fileList {
+		content, ok := g.readFileContent(file)
+		if !ok {
 			continue
 		}
 
-		// Skip if not a text file (unless force included)
-		if !file.IsForced && !file.IsText {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' (non-text file).\n", file.Path)
+		// Add file content to prompt; in compact mode, the next header closes the file
+		if g.Compact {
+			builder.WriteString(g.fileHeader(file, content) + "\n")
+			g.writeFileBody(builder, content)
+		} else {
+			builder.WriteString("\n" + g.fileHeader(file, content) + "\n")
+			g.writeFileBody(builder, content)
+			if !g.NoEndMarkers {
+				builder.WriteString(g.fileFooter(file) + "\n")
 			}
-			continue
 		}
+		g.recordContent(file, content)
+
+		fileCounter++
+	}
+
+	return fileCounter
+}
+
+// writeFileBody writes the content of a file so that its END FILE marker starts a new line:
+// a line break is added only when the content does not already end with one.
+// With DedupeBlankLines, trailing line breaks are dropped first, keeping any significant
+// trailing spaces of the last line.
+func (g *Generator) writeFileBody(builder *strings.Builder, content []byte) {
+	if g.DedupeBlankLines {
+		content = bytes.TrimRight(content, "\r\n")
+	}
+	builder.Write(content)
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		builder.WriteString("\n")
+	}
+}
+
+// readFileContent applies the inclusion checks to a file and returns the content to embed.
+// The boolean is false when the file must be skipped.
+// Once a file would bring the embedded content above MaxTotalBytes, it and all the following
+// files are skipped, so the prompt keeps whole files in their listing order.
+func (g *Generator) readFileContent(file files.FileInfo) ([]byte, bool) {
+	if g.totalReached {
+		g.progress.step()
+		g.warnTotalBytes(file)
+		return nil, false
+	}
+
+	content, ok := g.loadFileContent(file)
+	if !ok {
+		return nil, false
+	}
+	if g.Redact {
+		var count int
+		content, count = redactSecrets(content, g.secretPatterns())
+		if count > 0 && g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Info: Redacted %d secret-like substring(s) in '%s'.\n", count, file.Path)
+		}
+	}
+	if g.MaxTotalBytes > 0 {
+		if g.totalBytes+int64(len(content)) > g.MaxTotalBytes {
+			g.totalReached = true
+			g.warnTotalBytes(file)
+			return nil, false
+		}
+		g.totalBytes += int64(len(content))
+	}
+	if g.ScanSecrets {
+		g.secrets = append(g.secrets, findSecrets(file.Path, content, g.secretPatterns())...)
+	}
+	return content, true
+}
+
+// secretPatterns returns the built-in secret patterns followed by SecretPatterns
+func (g *Generator) secretPatterns() []SecretPattern {
+	return append(secretDetectors[:len(secretDetectors):len(secretDetectors)], g.SecretPatterns...)
+}
+
+// SecretFindings returns the secret-like substrings found in the files embedded by the last
+// Generate, when ScanSecrets is set
+func (g *Generator) SecretFindings() []SecretFinding {
+	return g.secrets
+}
+
+// warnTotalBytes reports a file left out because of MaxTotalBytes
+func (g *Generator) warnTotalBytes(file files.FileInfo) {
+	if g.Verbosity >= VerbosityNormal {
+		fmt.Fprintf(os.Stderr, "Warning: Skipping file '%s' (the file content would exceed --max-total-bytes %d).\n", file.Path, g.MaxTotalBytes)
+	}
+}
+
+// loadFileContent reads a file and applies the per-file checks and transforms to its content
+func (g *Generator) loadFileContent(file files.FileInfo) ([]byte, bool) {
+	defer g.progress.step()
+	defer g.trackReadTime(time.Now())
+
+	// Skip if not a regular file
+	if !file.IsRegular {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: File '%s' is not a regular file. Skipping.\n", file.Path)
+		}
+		return nil, false
+	}
+
+	// Skip if file is too large (unless force included or truncation is enabled)
+	tooLarge := !file.IsForced && file.Size > g.MaxFileSize
+	truncate := g.HeadLines > 0 || g.TailLines > 0
+	if tooLarge && !truncate {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' because it is too large (> 1MiB).\n", file.Path)
+		}
+		return nil, false
+	}
+
+	// Skip if not a text file (unless force included)
+	if !file.IsForced && !file.IsText {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' (non-text file).\n", file.Path)
+		}
+		return nil, false
+	}
+
+	// Read file content, unless it was kept when classifying the file
+	content := file.Content
+	var err error
+	if content == nil {
+		content, err = g.contentReader().ReadContent(file.Path)
+	}
+	if err != nil {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to read content of '%s': %v. Skipping.\n", file.Path, err)
+		}
+		return nil, false
+	}
+
+	// Skip empty files, even force included ones, which would only add a pair of separators
+	if len(content) == 0 && !g.IncludeEmpty {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Info: Skipping file '%s' (empty file, use --include-empty to keep it).\n", file.Path)
+		}
+		return nil, false
+	}
+
+	// Force included binary files are embedded base64-encoded, so the prompt stays text
+	if g.BinaryBase64 && file.IsForced && !files.IsTextContent(file.Path, content) {
+		if g.Verbosity >= VerbosityVerbose {
+			fmt.Fprintf(os.Stderr, "Verbose: Including '%s' (%d bytes, base64-encoded).\n", file.Path, len(content))
+		}
+		return encodeBinary(content), true
+	}
+
+	// Strip byte order marks and transcode UTF-16 content to UTF-8
+	content = files.DecodeText(content)
+
+	// Force included files skip the type check: warn when one holds binary data
+	if file.IsForced && !files.IsTextContent(file.Path, content) && g.Verbosity >= VerbosityNormal {
+		fmt.Fprintf(os.Stderr, "Warning: Forced file '%s' appears to be binary; output may be corrupted (consider --binary-base64).\n", file.Path)
+	}
 
-		// Read file content
-		content, err := os.ReadFile(file.Path)
+	// Pipe the content through the filter command, keeping the raw content if it fails
+	if g.FilterCommand != "" {
+		filtered, err := runFilter(g.FilterCommand, file.Path, content)
 		if err != nil {
-			if !g.QuietMode {
-				fmt.Fprintf(os.Stderr, "Warning: Failed to read content of '%s': %v. Skipping.\n", file.Path, err)
+			if g.Verbosity >= VerbosityNormal {
+				fmt.Fprintf(os.Stderr, "Warning: Filter command failed on '%s': %v. Using the raw content.\n", file.Path, err)
 			}
-			continue
+		} else {
+			content = filtered
 		}
+	}
 
-		// Add file content to prompt
-		builder.WriteString("\n--- FILE: " + file.Path + " ---\n")
-		builder.Write(content)
-		builder.WriteString("\n--- END FILE: " + file.Path + " ---\n")
+	// Reduce the files of supported languages to their outline, keeping the others whole
+	if g.Outline {
+		if outline, ok := outliners[file.Language]; ok {
+			reduced, err := outline(content)
+			if err != nil {
+				if g.Verbosity >= VerbosityNormal {
+					fmt.Fprintf(os.Stderr, "Warning: Failed to outline '%s': %v. Using the full content.\n", file.Path, err)
+				}
+			} else {
+				content = reduced
+			}
+		}
+	}
 
-		fileCounter++
+	if g.IndentTabWidth > 0 {
+		if whitespaceSensitiveLanguages[file.Language] {
+			if g.Verbosity >= VerbosityNormal {
+				fmt.Fprintf(os.Stderr, "Warning: Keeping the indentation of '%s' as is: %s is whitespace-sensitive.\n", file.Path, file.Language)
+			}
+		} else if !canReindent(file.Language) {
+			if g.Verbosity >= VerbosityNormal {
+				fmt.Fprintf(os.Stderr, "Warning: Keeping the indentation of '%s' as is: its multi-line strings are not recognized.\n", file.Path)
+			}
+		} else {
+			content = indentWithTabs(content, g.IndentTabWidth, file.Language)
+		}
 	}
 
-	return fileCounter
+	if g.LongLineThreshold > 0 && g.Verbosity >= VerbosityNormal {
+		if longest := longestLine(content); longest > g.LongLineThreshold {
+			fmt.Fprintf(os.Stderr, "Warning: File '%s' has lines longer than %d characters (longest: %d).\n", file.Path, g.LongLineThreshold, longest)
+		}
+	}
+
+	if tooLarge {
+		if g.Verbosity >= VerbosityNormal {
+			fmt.Fprintf(os.Stderr, "Info: Truncating file '%s' because it is too large (> 1MiB).\n", file.Path)
+		}
+		content = truncateLines(content, g.HeadLines, g.TailLines)
+	}
+
+	if g.Verbosity >= VerbosityVerbose {
+		fmt.Fprintf(os.Stderr, "Verbose: Including '%s' (%d bytes).\n", file.Path, len(content))
+	}
+
+	return content, true
+}
+
+// trackReadTime adds the time elapsed since start to the time spent reading files
+func (g *Generator) trackReadTime(start time.Time) {
+	g.readTime += time.Since(start)
+}
+
+// ReadTime returns the time spent reading, decoding and filtering the files during the last Generate
+func (g *Generator) ReadTime() time.Duration {
+	return g.readTime
+}
+
+// longestLine returns the length, in characters, of the longest line of content
+func longestLine(content []byte) int {
+	longest := 0
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if n := utf8.RuneCount(bytes.TrimSuffix(line, []byte("\r"))); n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+// truncateLines keeps the first head and last tail lines of content, replacing the rest
+// with a marker stating how many lines were omitted
+func truncateLines(content []byte, head, tail int) []byte {
+	text := strings.TrimSuffix(string(content), "\n")
+	lines := strings.Split(text, "\n")
+	if head+tail >= len(lines) {
+		return content
+	}
+
+	omitted := len(lines) - head - tail
+	kept := make([]string, 0, head+tail+1)
+	kept = append(kept, lines[:head]...)
+	kept = append(kept, fmt.Sprintf("... [%d lines omitted] ...", omitted))
+	kept = append(kept, lines[len(lines)-tail:]...)
+
+	return []byte(strings.Join(kept, "\n") + "\n")
 }
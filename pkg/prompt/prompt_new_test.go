@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/briossant/make-project-prompt/pkg/files"
 )
@@ -334,4 +335,1158 @@ func TestGenerator_RawModeInterleaving(t *testing.T) {
 				headerIdx, file1Idx, middleIdx, file2Idx, footerIdx)
 		}
 	})
+
+	t.Run("Raw mode sorts content items by order", func(t *testing.T) {
+		generator := NewGenerator([]files.FileInfo{}, "", false)
+		generator.RawMode = true
+		generator.ContentItems = []ContentItem{
+			{Type: "question", Content: "Footer text", Order: 4},
+			{Type: "file_group", Files: fileInfos2, Order: 3},
+			{Type: "question", Content: "Header text", Order: 0},
+			{Type: "file_group", Files: fileInfos1, Order: 1},
+		}
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		headerIdx := strings.Index(promptText, "Header text")
+		file1Idx := strings.Index(promptText, "Content of file 1")
+		file2Idx := strings.Index(promptText, "Content of file 2")
+		footerIdx := strings.Index(promptText, "Footer text")
+		if !(headerIdx < file1Idx && file1Idx < file2Idx && file2Idx < footerIdx) {
+			t.Errorf("Content items are not sorted by order:\n%s", promptText)
+		}
+	})
+
+	t.Run("Raw mode without content items uses files then questions", func(t *testing.T) {
+		generator := NewGenerator(append(fileInfos1, fileInfos2...), "", false)
+		generator.RawMode = true
+		generator.AddQuestion("First question", 0)
+		generator.AddQuestion("Second question", 1)
+
+		promptText, fileCount, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if fileCount != 2 {
+			t.Errorf("Expected 2 files in prompt, got %d", fileCount)
+		}
+
+		file1Idx := strings.Index(promptText, "Content of file 1")
+		file2Idx := strings.Index(promptText, "Content of file 2")
+		firstIdx := strings.Index(promptText, "First question")
+		secondIdx := strings.Index(promptText, "Second question")
+		if !(file1Idx < file2Idx && file2Idx < firstIdx && firstIdx < secondIdx) {
+			t.Errorf("Expected files followed by questions in order:\n%s", promptText)
+		}
+	})
+}
+
+func TestTruncateLines(t *testing.T) {
+	content := []byte("line1\nline2\nline3\nline4\nline5\nline6\n")
+
+	tests := []struct {
+		name     string
+		head     int
+		tail     int
+		expected string
+	}{
+		{
+			name:     "Head only",
+			head:     2,
+			expected: "line1\nline2\n... [4 lines omitted] ...\n",
+		},
+		{
+			name:     "Tail only",
+			tail:     2,
+			expected: "... [4 lines omitted] ...\nline5\nline6\n",
+		},
+		{
+			name:     "Head and tail elide the middle",
+			head:     1,
+			tail:     1,
+			expected: "line1\n... [4 lines omitted] ...\nline6\n",
+		},
+		{
+			name:     "Nothing to omit",
+			head:     3,
+			tail:     3,
+			expected: string(content),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(truncateLines(content, tt.head, tt.tail))
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGenerator_HeadTailTruncation(t *testing.T) {
+	tempDir := t.TempDir()
+
+	content := "first\nmiddle1\nmiddle2\nmiddle3\nlast\n"
+	largeFile := filepath.Join(tempDir, "large.log")
+	if err := os.WriteFile(largeFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: largeFile, IsText: true, Size: int64(len(content)), IsRegular: true},
+	}
+
+	t.Run("Oversized file is skipped without head or tail", func(t *testing.T) {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.SetMaxFileSize(10)
+
+		_, fileCount, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if fileCount != 0 {
+			t.Errorf("Expected oversized file to be skipped, got %d files", fileCount)
+		}
+	})
+
+	t.Run("Oversized file is truncated with head and tail", func(t *testing.T) {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.SetMaxFileSize(10)
+		generator.HeadLines = 1
+		generator.TailLines = 1
+
+		promptText, fileCount, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if fileCount != 1 {
+			t.Fatalf("Expected truncated file to be included, got %d files", fileCount)
+		}
+		if !strings.Contains(promptText, "first\n... [3 lines omitted] ...\nlast") {
+			t.Errorf("Expected truncated content in prompt, got:\n%s", promptText)
+		}
+		if strings.Contains(promptText, "middle2") {
+			t.Error("Expected middle lines to be omitted")
+		}
+	})
+
+	t.Run("Forced file is included in full", func(t *testing.T) {
+		forced := []files.FileInfo{
+			{Path: largeFile, IsText: true, IsForced: true, Size: int64(len(content)), IsRegular: true},
+		}
+		generator := NewGenerator(forced, "", true)
+		generator.SetMaxFileSize(10)
+		generator.HeadLines = 1
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if !strings.Contains(promptText, content) {
+			t.Error("Expected forced file to be included in full")
+		}
+	})
+}
+
+func TestDedupeFileGroups(t *testing.T) {
+	app := files.FileInfo{Path: "src/main/app.go", IsText: true, IsRegular: true}
+	utils := files.FileInfo{Path: "src/main/utils.go", IsText: true, IsRegular: true}
+
+	items := []ContentItem{
+		{Type: "file_group", FilePatterns: []string{"src/*"}, Files: []files.FileInfo{app, utils}, Order: 0},
+		{Type: "question", Content: "Between", Order: 1},
+		{Type: "file_group", FilePatterns: []string{"src/main/*"}, Files: []files.FileInfo{utils, app}, Order: 2},
+	}
+
+	result, suppressed := DedupeFileGroups(items)
+
+	if len(result) != len(items) {
+		t.Fatalf("Expected %d items, got %d", len(items), len(result))
+	}
+	if len(result[0].Files) != 2 {
+		t.Errorf("Expected first group to keep both files, got %d", len(result[0].Files))
+	}
+	if result[1].Content != "Between" {
+		t.Errorf("Expected question to be preserved, got %q", result[1].Content)
+	}
+	if len(result[2].Files) != 0 {
+		t.Errorf("Expected second group to be emptied, got %v", result[2].Files)
+	}
+
+	expectedSuppressed := []string{"src/main/utils.go", "src/main/app.go"}
+	if strings.Join(suppressed, ",") != strings.Join(expectedSuppressed, ",") {
+		t.Errorf("Expected suppressed %v, got %v", expectedSuppressed, suppressed)
+	}
+}
+
+func TestDedupeFileGroups_ForceIncludeWins(t *testing.T) {
+	app := files.FileInfo{Path: "src/main/app.go", IsText: true, IsRegular: true, MatchedPattern: "src/*"}
+	forcedApp := files.FileInfo{Path: "src/main/app.go", IsText: true, IsForced: true, IsRegular: true, MatchedPattern: "src/main/app.go"}
+
+	items := []ContentItem{
+		{Type: "file_group", FilePatterns: []string{"src/*"}, Files: []files.FileInfo{app}, Order: 0},
+		{Type: "file_group", FilePatterns: []string{"src/main/app.go"}, Files: []files.FileInfo{forcedApp}, Order: 1},
+	}
+
+	result, suppressed := DedupeFileGroups(items)
+
+	if len(result[0].Files) != 1 || len(result[1].Files) != 0 {
+		t.Fatalf("Expected the file once, in the first group, got %v and %v", result[0].Files, result[1].Files)
+	}
+	if !result[0].Files[0].IsForced {
+		t.Error("Expected the kept file to be marked forced")
+	}
+	if len(suppressed) != 1 || suppressed[0] != "src/main/app.go" {
+		t.Errorf("Expected src/main/app.go to be suppressed, got %v", suppressed)
+	}
+	if items[0].Files[0].IsForced {
+		t.Error("Expected the input items to be left unchanged")
+	}
+}
+
+func TestGenerator_BOMTranscoding(t *testing.T) {
+	tempDir := t.TempDir()
+
+	fixtures := map[string][]byte{
+		"utf8_bom.txt": append([]byte{0xEF, 0xBB, 0xBF}, "UTF-8 content\n"...),
+		"utf16le.txt":  {0xFF, 0xFE, 'L', 0, 'E', 0, '\n', 0},
+		"utf16be.txt":  {0xFE, 0xFF, 0, 'B', 0, 'E', 0, '\n'},
+	}
+
+	var fileInfos []files.FileInfo
+	for name, content := range fixtures {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		fileInfos = append(fileInfos, files.FileInfo{Path: path, IsText: true, Size: int64(len(content)), IsRegular: true})
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if fileCount != len(fixtures) {
+		t.Errorf("Expected %d files, got %d", len(fixtures), fileCount)
+	}
+
+	for _, expected := range []string{"\nUTF-8 content\n", "\nLE\n", "\nBE\n"} {
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", expected, promptText)
+		}
+	}
+	if strings.ContainsAny(promptText, "\x00\uFEFF") {
+		t.Error("Expected null bytes and BOMs to be removed from the prompt")
+	}
+}
+
+func TestGenerator_TreeMatched(t *testing.T) {
+	tempDir := t.TempDir()
+
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(originalWD); err != nil {
+			t.Logf("Warning: Failed to change back to original directory: %v", err)
+		}
+	}()
+
+	if err := os.MkdirAll("src/main", 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile("src/main/app.go", []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: "src/main/app.go", IsText: true, Size: 12, IsRegular: true},
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.TreeMatched = true
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expectedTree := "--- PROJECT STRUCTURE (included files only) ---\n.\n└── src\n    └── main\n        └── app.go\n"
+	if !strings.Contains(promptText, expectedTree) {
+		t.Errorf("Expected prompt to contain the matched tree, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_QuestionPrefixSuffix(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("Test content"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: testFile, IsText: true, Size: int64(len("Test content")), IsRegular: true},
+	}
+
+	for _, rawMode := range []bool{false, true} {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.QuestionPrefix = "Please "
+		generator.QuestionSuffix = " Explain your reasoning."
+		generator.AddQuestion("review the code.", 0)
+		generator.AddQuestion("list the bugs.", 1)
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		for _, expected := range []string{
+			"Please review the code. Explain your reasoning.\n",
+			"Please list the bugs. Explain your reasoning.\n",
+		} {
+			if !strings.Contains(promptText, expected) {
+				t.Errorf("Expected prompt (raw mode: %v) to contain %q, got:\n%s", rawMode, expected, promptText)
+			}
+		}
+	}
+}
+
+func TestGenerator_AnnotateLanguage(t *testing.T) {
+	tempDir := t.TempDir()
+
+	goPath := filepath.Join(tempDir, "app.go")
+	notesPath := filepath.Join(tempDir, "notes")
+	for _, path := range []string{goPath, notesPath} {
+		if err := os.WriteFile(path, []byte("content\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: goPath, IsText: true, Size: 8, IsRegular: true, Language: "go"},
+		{Path: notesPath, IsText: true, Size: 8, IsRegular: true},
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if strings.Contains(promptText, "(go)") {
+		t.Error("Expected no language annotation without AnnotateLanguage")
+	}
+
+	generator.AnnotateLanguage = true
+	promptText, _, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.Contains(promptText, "--- FILE: "+goPath+" (go) ---") {
+		t.Errorf("Expected annotated header for the Go file, got:\n%s", promptText)
+	}
+	if !strings.Contains(promptText, "--- FILE: "+notesPath+" ---") {
+		t.Errorf("Expected plain header for the file with unknown language, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_RawModeExtraContextAndLastWords(t *testing.T) {
+	t.Run("Fields are appended when ContentItems does not position them", func(t *testing.T) {
+		generator := NewGenerator([]files.FileInfo{}, "", true)
+		generator.RawMode = true
+		generator.ExtraContext = "Extra"
+		generator.LastWords = "Last"
+		generator.ContentItems = []ContentItem{
+			{Type: "question", Content: "Question", Order: 0},
+		}
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if promptText != "Question\n\nExtra\n\nLast\n\n" {
+			t.Errorf("Unexpected raw prompt: %q", promptText)
+		}
+	})
+
+	t.Run("Positioned extra context is not repeated", func(t *testing.T) {
+		generator := NewGenerator([]files.FileInfo{}, "", true)
+		generator.RawMode = true
+		generator.ExtraContext = "Extra"
+		generator.LastWords = "Last"
+		generator.ContentItems = []ContentItem{
+			{Type: "question", Content: "Question", Order: 1},
+			{Type: "extra_context", Content: "Extra", Order: 0},
+		}
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if promptText != "Extra\n\nQuestion\n\nLast\n\n" {
+			t.Errorf("Unexpected raw prompt: %q", promptText)
+		}
+	})
+}
+
+func TestGenerator_Template(t *testing.T) {
+	tempDir := t.TempDir()
+
+	filePath := filepath.Join(tempDir, "app.go")
+	if err := os.WriteFile(filePath, []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	fileInfos := []files.FileInfo{{Path: filePath, IsText: true, Size: 12, IsRegular: true, Language: "go"}}
+
+	t.Run("Template renders the prompt", func(t *testing.T) {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.RoleMessage = "You are a Go expert"
+		generator.QuestionPrefix = "Q: "
+		generator.AddQuestion("Review this", 0)
+		generator.Template = "{{.RoleMessage}}\n{{range .Files}}<file path=\"{{.Path}}\" lang=\"{{.Language}}\">{{.Content}}</file>\n{{end}}{{range .Questions}}{{.}}\n{{end}}"
+
+		promptText, fileCount, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if fileCount != 1 {
+			t.Errorf("Expected 1 file, got %d", fileCount)
+		}
+
+		expected := "You are a Go expert\n<file path=\"" + filePath + "\" lang=\"go\">package main</file>\nQ: Review this\n"
+		if promptText != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, promptText)
+		}
+	})
+
+	t.Run("Invalid template returns an error", func(t *testing.T) {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.Template = "{{range .Files}}"
+
+		if _, _, err := generator.Generate(); err == nil {
+			t.Error("Expected an error for an invalid template")
+		}
+	})
+}
+
+func TestGenerator_GroupByPattern(t *testing.T) {
+	tempDir := t.TempDir()
+
+	srcPath := filepath.Join(tempDir, "app.go")
+	docPath := filepath.Join(tempDir, "guide.md")
+	otherPath := filepath.Join(tempDir, "util.go")
+	for _, path := range []string{srcPath, docPath, otherPath} {
+		if err := os.WriteFile(path, []byte("content\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: srcPath, IsText: true, Size: 8, IsRegular: true, MatchedPattern: "src/*"},
+		{Path: docPath, IsText: true, Size: 8, IsRegular: true, MatchedPattern: "docs/*"},
+		{Path: otherPath, IsText: true, Size: 8, IsRegular: true, MatchedPattern: "src/*"},
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if strings.Contains(promptText, "=== Files matching") {
+		t.Error("Expected no group headers without GroupByPattern")
+	}
+
+	generator.GroupByPattern = true
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if fileCount != 3 {
+		t.Errorf("Expected 3 files, got %d", fileCount)
+	}
+
+	srcHeader := strings.Index(promptText, "=== Files matching src/* ===")
+	docHeader := strings.Index(promptText, "=== Files matching docs/* ===")
+	if srcHeader == -1 || docHeader == -1 {
+		t.Fatalf("Expected a header per pattern, got:\n%s", promptText)
+	}
+	otherFile := strings.Index(promptText, "--- FILE: "+otherPath+" ---")
+	if !(srcHeader < otherFile && otherFile < docHeader) {
+		t.Errorf("Expected files matched by src/* to be grouped before docs/*, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_DisplayPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		relativeTo  string
+		stripPrefix string
+		path        string
+		expected    string
+	}{
+		{"Unchanged by default", "", "", "src/main/app.go", "src/main/app.go"},
+		{"Relative to a directory", "src", "", "src/main/app.go", "main/app.go"},
+		{"Relative to a sibling directory", "docs", "", "src/main/app.go", "../src/main/app.go"},
+		{"Strip a prefix", "", "src/main/", "src/main/app.go", "app.go"},
+		{"Prefix not matching", "", "lib/", "src/main/app.go", "src/main/app.go"},
+		{"Relative then strip", "src", "main/", "src/main/app.go", "app.go"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			generator := NewGenerator(nil, "", true)
+			generator.RelativeTo = tc.relativeTo
+			generator.StripPrefix = tc.stripPrefix
+			if result := generator.displayPath(tc.path); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestGenerator_StripPrefixKeepsRealPath(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "app.go")
+	if err := os.WriteFile(filePath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	generator := NewGenerator([]files.FileInfo{
+		{Path: filePath, IsText: true, Size: 13, IsRegular: true},
+	}, "", true)
+	generator.IncludeTree = false
+	generator.StripPrefix = tempDir + string(filepath.Separator)
+
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if fileCount != 1 || !strings.Contains(promptText, "package main") {
+		t.Errorf("Expected the file to be read from its real path, got:\n%s", promptText)
+	}
+	if !strings.Contains(promptText, "--- FILE: app.go ---") || !strings.Contains(promptText, "--- END FILE: app.go ---") {
+		t.Errorf("Expected the displayed path to be stripped, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_HashContent(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "app.go")
+	if err := os.WriteFile(filePath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	fileInfos := []files.FileInfo{{Path: filePath, IsText: true, Size: 13, IsRegular: true}}
+
+	generate := func(rawMode bool) string {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.HashContent = true
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return promptText
+	}
+
+	promptText := generate(false)
+	expectedHeader := "--- FILE: " + filePath + " [" + shortHash([]byte("package main\n")) + "] ---"
+	if !strings.Contains(promptText, expectedHeader) {
+		t.Errorf("Expected header %q, got:\n%s", expectedHeader, promptText)
+	}
+	if !strings.Contains(promptText, "--- CONTENT HASH: sha256:") {
+		t.Errorf("Expected a combined content hash, got:\n%s", promptText)
+	}
+	if generate(false) != promptText {
+		t.Error("Expected the hashes to be stable between runs")
+	}
+
+	if !strings.Contains(generate(true), "--- CONTENT HASH: sha256:") {
+		t.Error("Expected a combined content hash in raw mode")
+	}
+
+	if err := os.WriteFile(filePath, []byte("package other\n"), 0644); err != nil {
+		t.Fatalf("Failed to update test file: %v", err)
+	}
+	if generate(false) == promptText {
+		t.Error("Expected the hashes to change with the content")
+	}
+}
+
+func TestGenerator_FileMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "app.go")
+	if err := os.WriteFile(filePath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	modTime := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	fileInfos := []files.FileInfo{{Path: filePath, IsText: true, Size: 1229, ModTime: modTime, IsRegular: true, Language: "go"}}
+
+	for _, rawMode := range []bool{false, true} {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.FileMetadata = true
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		expected := "--- FILE: " + filePath + " ---\nsize: 1.2KB, modified: 2024-01-02, language: go\npackage main\n"
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("Expected metadata after the header (raw mode: %v), got:\n%s", rawMode, promptText)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:       "0B",
+		512:     "512B",
+		1229:    "1.2KB",
+		1048576: "1.0MB",
+	}
+	for size, expected := range tests {
+		if got := formatSize(size); got != expected {
+			t.Errorf("formatSize(%d) = %q, expected %q", size, got, expected)
+		}
+	}
+}
+
+func TestGenerator_FilterCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "data.txt")
+	if err := os.WriteFile(filePath, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	fileInfos := []files.FileInfo{{Path: filePath, IsText: true, Size: 6, IsRegular: true}}
+
+	generate := func(command string) string {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.FilterCommand = command
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return promptText
+	}
+
+	if promptText := generate("tr a-z A-Z"); !strings.Contains(promptText, "HELLO\n") {
+		t.Errorf("Expected the filtered content, got:\n%s", promptText)
+	}
+	if promptText := generate(`echo "$MPP_FILE"`); !strings.Contains(promptText, filePath+"\n") {
+		t.Errorf("Expected the file path in $MPP_FILE, got:\n%s", promptText)
+	}
+	if promptText := generate("exit 1"); !strings.Contains(promptText, "hello\n") {
+		t.Errorf("Expected the raw content when the filter fails, got:\n%s", promptText)
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	output, err := PostProcess("tr a-z A-Z", "prompt text\n")
+	if err != nil {
+		t.Fatalf("PostProcess failed: %v", err)
+	}
+	if output != "PROMPT TEXT\n" {
+		t.Errorf("Expected the transformed prompt, got %q", output)
+	}
+
+	_, err = PostProcess("echo 'translation failed' >&2; exit 3", "prompt text\n")
+	if err == nil {
+		t.Fatal("Expected an error when the command fails")
+	}
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "translation failed") {
+		t.Errorf("Expected the exit status and the command's stderr in the error, got: %v", err)
+	}
+}
+
+func TestLongestLine(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected int
+	}{
+		{"", 0},
+		{"short\nmuch longer line\n", 16},
+		{"héllo\r\n", 5},
+	}
+	for _, tc := range tests {
+		if got := longestLine([]byte(tc.content)); got != tc.expected {
+			t.Errorf("longestLine(%q) = %d, expected %d", tc.content, got, tc.expected)
+		}
+	}
+}
+
+func TestGenerator_FileManifest(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "missing.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "b.go", IsText: true, Size: 10, IsRegular: true},
+	}
+	reader := memoryReader{"a.go": "package a\n", "b.go": "package b\n"}
+
+	for _, rawMode := range []bool{false, true} {
+		generator := NewGenerator(fileInfos, "What do they do?", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.Reader = reader
+		generator.FileManifest = true
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		// Only the files actually written are listed
+		manifest := "--- INCLUDED FILES (2) ---\n- a.go\n- b.go\n"
+		index := strings.Index(promptText, manifest)
+		if index < 0 {
+			t.Errorf("Expected the manifest of the written files (raw mode: %v), got:\n%s", rawMode, promptText)
+			continue
+		}
+		if !rawMode && index > strings.Index(promptText, "What do they do?") {
+			t.Errorf("Expected the manifest before the question, got:\n%s", promptText)
+		}
+	}
+}
+
+func TestGenerator_IncludeEmpty(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "empty.go", IsText: true, IsRegular: true},
+		{Path: "forced.txt", IsForced: true, IsRegular: true},
+		{Path: "kept.go", IsText: true, Size: 10, IsRegular: true},
+	}
+	reader := memoryReader{"empty.go": "", "forced.txt": "", "kept.go": "package k\n"}
+
+	tests := []struct {
+		includeEmpty bool
+		expected     int
+	}{
+		{false, 1}, // Empty files are skipped, even force included ones
+		{true, 3},
+	}
+	for _, tc := range tests {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.Reader = reader
+		generator.IncludeEmpty = tc.includeEmpty
+		promptText, fileCount, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if fileCount != tc.expected {
+			t.Errorf("IncludeEmpty=%v: expected %d files, got %d:\n%s", tc.includeEmpty, tc.expected, fileCount, promptText)
+		}
+		if strings.Contains(promptText, "--- FILE: forced.txt ---") != tc.includeEmpty {
+			t.Errorf("IncludeEmpty=%v: unexpected presence of the forced empty file:\n%s", tc.includeEmpty, promptText)
+		}
+	}
+}
+
+func TestGenerator_MaxTotalBytes(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "b.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "c.go", IsText: true, Size: 5, IsRegular: true},
+	}
+	reader := memoryReader{"a.go": "package a\n", "b.go": "package b\n", "c.go": "// c\n"}
+
+	tests := []struct {
+		maxTotalBytes int64
+		expected      []string
+	}{
+		{0, []string{"a.go", "b.go", "c.go"}},
+		{25, []string{"a.go", "b.go", "c.go"}},
+		{20, []string{"a.go", "b.go"}},
+		// c.go would fit after b.go is left out, but files are only added in order
+		{15, []string{"a.go"}},
+		{5, nil},
+	}
+	for _, tc := range tests {
+		for _, rawMode := range []bool{false, true} {
+			generator := NewGenerator(fileInfos, "", true)
+			generator.IncludeTree = false
+			generator.RawMode = rawMode
+			generator.Reader = reader
+			generator.MaxTotalBytes = tc.maxTotalBytes
+			promptText, fileCount, err := generator.Generate()
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if fileCount != len(tc.expected) {
+				t.Errorf("MaxTotalBytes=%d, raw=%v: expected %d files, got %d:\n%s", tc.maxTotalBytes, rawMode, len(tc.expected), fileCount, promptText)
+			}
+			for _, path := range tc.expected {
+				if !strings.Contains(promptText, "--- FILE: "+path+" ---") {
+					t.Errorf("MaxTotalBytes=%d, raw=%v: expected %s to be included:\n%s", tc.maxTotalBytes, rawMode, path, promptText)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerator_Markers(t *testing.T) {
+	fileInfos := []files.FileInfo{{Path: "a.go", IsText: true, Size: 10, IsRegular: true}}
+	reader := memoryReader{"a.go": "package a\n"}
+
+	for _, rawMode := range []bool{false, true} {
+		generator := NewGenerator(fileInfos, "Why?", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.Reader = reader
+		generator.BeginMarker = "<<<PROMPT>>>"
+		generator.EndMarker = "<<<END>>>"
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if !strings.HasPrefix(promptText, "<<<PROMPT>>>\n") || !strings.HasSuffix(promptText, "\n<<<END>>>\n") {
+			t.Errorf("Raw=%v: expected the prompt to be wrapped in the markers, got:\n%s", rawMode, promptText)
+		}
+		if strings.Count(promptText, "<<<PROMPT>>>") != 1 || strings.Count(promptText, "<<<END>>>") != 1 {
+			t.Errorf("Raw=%v: expected each marker once, got:\n%s", rawMode, promptText)
+		}
+	}
+}
+
+func TestGenerator_Compact(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 10, IsRegular: true, Language: "Go"},
+		{Path: "b.go", IsText: true, Size: 10, IsRegular: true, Language: "Go"},
+	}
+	reader := memoryReader{"a.go": "package a\n", "b.go": "package b"}
+
+	generator := NewGenerator(fileInfos, "Why?", true)
+	generator.IncludeTree = false
+	generator.Reader = reader
+	generator.Compact = true
+	generator.AnnotateLanguage = true
+	promptText, count, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "### a.go (Go)\npackage a\n### b.go (Go)\npackage b\n\n--- END OF FILE CONTENT ---\n"
+	if count != 2 || !strings.Contains(promptText, expected) {
+		t.Errorf("Expected compact separators, got:\n%s", promptText)
+	}
+	if strings.Contains(promptText, "END FILE:") {
+		t.Errorf("Expected no END FILE markers, got:\n%s", promptText)
+	}
+	if stats := ComputeStats(promptText); stats.Files != 2 {
+		t.Errorf("Expected the compact headers to be counted, got %d files", stats.Files)
+	}
+}
+
+func TestGenerator_NoEndMarkers(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "b.go", IsText: true, Size: 10, IsRegular: true},
+	}
+	reader := memoryReader{"a.go": "package a\n", "b.go": "package b\n"}
+
+	generator := NewGenerator(fileInfos, "Why?", true)
+	generator.IncludeTree = false
+	generator.Reader = reader
+	generator.NoEndMarkers = true
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "\n--- FILE: a.go ---\npackage a\n\n--- FILE: b.go ---\npackage b\n\n--- END OF FILE CONTENT ---\n"
+	if !strings.Contains(promptText, expected) {
+		t.Errorf("Expected the opening markers only, got:\n%s", promptText)
+	}
+	if stats := ComputeStats(promptText); stats.Files != 2 {
+		t.Errorf("Expected the opening markers to be counted, got %d files", stats.Files)
+	}
+}
+
+func TestGenerator_AutoIncluded(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 10, IsRegular: true},
+		{Path: "README.md", IsText: true, Size: 8, IsRegular: true, AutoIncluded: true},
+	}
+	reader := memoryReader{"a.go": "package a\n", "README.md": "# Title\n"}
+
+	generator := NewGenerator(fileInfos, "Why?", true)
+	generator.IncludeTree = false
+	generator.Reader = reader
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if fileCount != 2 {
+		t.Errorf("Expected the auto-included file to be counted, got %d files", fileCount)
+	}
+	autoSection := strings.Index(promptText, "--- AUTO-INCLUDED CONTEXT")
+	readme := strings.Index(promptText, "--- FILE: README.md ---")
+	fileContent := strings.Index(promptText, "--- FILE CONTENT")
+	source := strings.Index(promptText, "--- FILE: a.go ---")
+	if autoSection < 0 || !(autoSection < readme && readme < fileContent && fileContent < source) {
+		t.Errorf("Expected README.md in a leading auto-included section, got:\n%s", promptText)
+	}
+
+	generator.TreeOnly = true
+	promptText, _, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if strings.Contains(promptText, "README.md") {
+		t.Errorf("Expected no auto-included content with TreeOnly, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_QuestionsOrder(t *testing.T) {
+	generator := NewGenerator(nil, "", true)
+	generator.IncludeTree = false
+	generator.TreeOnly = true
+	generator.AddQuestion("First?", 0)
+	generator.AddQuestion("Second?", 1)
+	generator.AddQuestion("Third?", 2)
+
+	tests := []struct {
+		order    QuestionsOrder
+		expected string
+	}{
+		{"", "First?\nSecond?\nThird?\n"},
+		{QuestionsAsGiven, "First?\nSecond?\nThird?\n"},
+		{QuestionsAsc, "First?\nSecond?\nThird?\n"},
+		{QuestionsDesc, "Third?\nSecond?\nFirst?\n"},
+	}
+	for _, tc := range tests {
+		generator.QuestionsOrder = tc.order
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if !strings.HasSuffix(promptText, "questions:\n\n"+tc.expected) {
+			t.Errorf("Expected the %q order to end the prompt with %q, got:\n%s", tc.order, tc.expected, promptText)
+		}
+	}
+}
+
+func TestGenerator_LabelQuestions(t *testing.T) {
+	tempDir := t.TempDir()
+	var fileInfos []files.FileInfo
+	for _, name := range []string{"a.go", "b.go"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		fileInfos = append(fileInfos, files.FileInfo{Path: path, IsText: true, Size: 13, IsRegular: true})
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.RawMode = true
+	generator.LabelQuestions = true
+	generator.RelativeTo = tempDir
+	generator.ContentItems = []ContentItem{
+		{Type: "question", Content: "General question?", Order: 0},
+		{Type: "file_group", Files: fileInfos[:1], Order: 1},
+		{Type: "question", Content: "What does a do?", Order: 2},
+		{Type: "file_group", Files: fileInfos[1:], Order: 3},
+		{Type: "question", Content: "What does b do?", Order: 4},
+		{Type: "question", Content: "Follow-up?", Order: 5},
+	}
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	for _, expected := range []string{
+		"General question?\n\n--- FILE: a.go ---",
+		"--- END FILE: a.go ---\n\nRegarding a.go:\nWhat does a do?\n\n",
+		"--- END FILE: b.go ---\n\nRegarding b.go:\nWhat does b do?\n\nFollow-up?\n\n",
+	} {
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", expected, promptText)
+		}
+	}
+}
+
+func TestShortHash(t *testing.T) {
+	// sha256("") = e3b0c44298fc1c149afbf4c8996fb924...
+	if result := shortHash(nil); result != "sha256:e3b0c44298fc" {
+		t.Errorf("Unexpected hash of empty content: %q", result)
+	}
+}
+
+func TestGenerator_DedupeBlankLines(t *testing.T) {
+	tempDir := t.TempDir()
+
+	noNewlinePath := filepath.Join(tempDir, "no_newline.txt")
+	blankLinesPath := filepath.Join(tempDir, "blank_lines.txt")
+	if err := os.WriteFile(noNewlinePath, []byte("last line"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(blankLinesPath, []byte("content  \n\n\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	fileInfos := []files.FileInfo{
+		{Path: noNewlinePath, IsText: true, Size: 9, IsRegular: true},
+		{Path: blankLinesPath, IsText: true, Size: 12, IsRegular: true},
+	}
+
+	for _, rawMode := range []bool{false, true} {
+		generator := NewGenerator(fileInfos, "", true)
+		generator.IncludeTree = false
+		generator.RawMode = rawMode
+		generator.DedupeBlankLines = true
+
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+
+		expected := "last line\n--- END FILE: " + noNewlinePath + " ---\n\n"
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("RawMode=%v: expected content without trailing newline to be closed on its own line, got:\n%q", rawMode, promptText)
+		}
+		expected = "content  \n--- END FILE: " + blankLinesPath + " ---\n"
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("RawMode=%v: expected trailing blank lines to be dropped and trailing spaces kept, got:\n%q", rawMode, promptText)
+		}
+		if strings.Contains(promptText, "\n\n\n") {
+			t.Errorf("RawMode=%v: expected at most one blank line between files, got:\n%q", rawMode, promptText)
+		}
+	}
+}
+
+func TestGenerator_EndMarkerOnItsOwnLine(t *testing.T) {
+	tempDir := t.TempDir()
+
+	withNewlinePath := filepath.Join(tempDir, "with_newline.go")
+	withoutNewlinePath := filepath.Join(tempDir, "without_newline.go")
+	if err := os.WriteFile(withNewlinePath, []byte("package a\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(withoutNewlinePath, []byte("package b"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	generator := NewGenerator([]files.FileInfo{
+		{Path: withNewlinePath, IsText: true, Size: 10, IsRegular: true},
+		{Path: withoutNewlinePath, IsText: true, Size: 9, IsRegular: true},
+	}, "", true)
+	generator.IncludeTree = false
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"package a\n--- END FILE: " + withNewlinePath + " ---\n",
+		"package b\n--- END FILE: " + withoutNewlinePath + " ---\n",
+	} {
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("Expected %q in the prompt, got:\n%q", expected, promptText)
+		}
+	}
+}
+
+func TestGenerator_NumberQuestions(t *testing.T) {
+	generate := func(number bool, questions ...string) string {
+		generator := NewGenerator([]files.FileInfo{}, "", true)
+		generator.IncludeTree = false
+		generator.NumberQuestions = number
+		for i, q := range questions {
+			generator.AddQuestion(q, i)
+		}
+		promptText, _, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		return promptText
+	}
+
+	promptText := generate(true, "Find the bugs.", "Suggest tests.")
+	expected := "answer the following questions:\n\n1. Find the bugs.\n2. Suggest tests.\n"
+	if !strings.Contains(promptText, expected) {
+		t.Errorf("Expected numbered questions %q, got:\n%s", expected, promptText)
+	}
+
+	promptText = generate(true, "Find the bugs.")
+	if !strings.Contains(promptText, "answer the following question:\n\n1. Find the bugs.\n") {
+		t.Errorf("Expected a singular header for one numbered question, got:\n%s", promptText)
+	}
+
+	promptText = generate(false, "Find the bugs.", "Suggest tests.")
+	if strings.Contains(promptText, "1. Find the bugs.") {
+		t.Errorf("Expected no numbering without NumberQuestions, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_QuestionPluralization(t *testing.T) {
+	generator := NewGenerator([]files.FileInfo{}, "", true)
+	generator.IncludeTree = false
+	generator.AddQuestion("First question", 0)
+
+	promptText, _, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.Contains(promptText, "answer the following question:\n") {
+		t.Errorf("Expected the singular form with one question, got:\n%s", promptText)
+	}
+
+	generator.AddQuestion("Second question", 1)
+	promptText, _, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.Contains(promptText, "answer the following questions:\n") {
+		t.Errorf("Expected the plural form with two questions, got:\n%s", promptText)
+	}
+}
+
+func TestGenerator_TreeOnly(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "app.go")
+	if err := os.WriteFile(filePath, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	generator := NewGenerator([]files.FileInfo{
+		{Path: filePath, IsText: true, Size: 13, IsRegular: true},
+	}, "", true)
+	generator.TreeMatched = true
+	generator.TreeOnly = true
+	generator.AddQuestion("Explain the architecture.", 0)
+
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if fileCount != 0 {
+		t.Errorf("Expected no file to be written, got %d", fileCount)
+	}
+	if strings.Contains(promptText, "--- FILE CONTENT") || strings.Contains(promptText, "package main") {
+		t.Errorf("Expected no file content, got:\n%s", promptText)
+	}
+	for _, expected := range []string{"--- PROJECT STRUCTURE", "app.go", "Explain the architecture."} {
+		if !strings.Contains(promptText, expected) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", expected, promptText)
+		}
+	}
 }
@@ -2,47 +2,35 @@ package prompt
 
 import (
 	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/briossant/make-project-prompt/pkg/files"
 )
 
-func TestGenerator_Generate(t *testing.T) {
-	// Create a temporary directory for test files
-	tempDir, err := os.MkdirTemp("", "prompt_test")
-	if err != nil {
-		t.Fatalf("Failed to create temp directory: %v", err)
-	}
-	defer func() {
-		if err := os.RemoveAll(tempDir); err != nil {
-			t.Logf("Warning: Failed to remove temp directory: %v", err)
-		}
-	}()
-
-	// Create test files
-	textFile := filepath.Join(tempDir, "test.txt")
-	if err := os.WriteFile(textFile, []byte("This is a text file"), 0644); err != nil {
-		t.Fatalf("Failed to create text file: %v", err)
-	}
+// memoryReader is a ContentReader serving file content from memory
+type memoryReader map[string]string
 
-	goFile := filepath.Join(tempDir, "test.go")
-	if err := os.WriteFile(goFile, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
-		t.Fatalf("Failed to create Go file: %v", err)
+func (r memoryReader) ReadContent(path string) ([]byte, error) {
+	content, ok := r[path]
+	if !ok {
+		return nil, os.ErrNotExist
 	}
+	return []byte(content), nil
+}
 
-	// Create a large file that exceeds the default max size
-	largeFile := filepath.Join(tempDir, "large.txt")
+func TestGenerator_Generate(t *testing.T) {
+	textFile := "test.txt"
+	goFile := "test.go"
+	largeFile := "large.txt"
+	forcedLargeFile := "large.txt.forced"
 	largeContent := strings.Repeat("Large file content\n", 100000) // More than 1MB
-	if err := os.WriteFile(largeFile, []byte(largeContent), 0644); err != nil {
-		t.Fatalf("Failed to create large file: %v", err)
-	}
 
-	// Create a forced included large file
-	forcedLargeFile := filepath.Join(tempDir, "large.txt.forced")
-	if err := os.WriteFile(forcedLargeFile, []byte(largeContent), 0644); err != nil {
-		t.Fatalf("Failed to create forced large file: %v", err)
+	reader := memoryReader{
+		textFile:        "This is a text file",
+		goFile:          "package main\n\nfunc main() {}\n",
+		largeFile:       largeContent,
+		forcedLargeFile: largeContent,
 	}
 
 	// Create file info objects
@@ -112,6 +100,7 @@ func TestGenerator_Generate(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			// Create generator
 			generator := NewGenerator(fileInfos, tc.question, false)
+			generator.Reader = reader
 
 			// Set custom max file size if specified
 			if tc.maxFileSize > 0 {
@@ -149,3 +138,22 @@ func TestGenerator_Generate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_ContentReader(t *testing.T) {
+	fileInfos := []files.FileInfo{
+		{Path: "kept.go", IsText: true, Size: 13, IsRegular: true},
+		{Path: "missing.go", IsText: true, Size: 13, IsRegular: true},
+	}
+
+	generator := NewGenerator(fileInfos, "", true)
+	generator.IncludeTree = false
+	generator.Reader = memoryReader{"kept.go": "package kept\n"}
+	promptText, fileCount, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if fileCount != 1 || !strings.Contains(promptText, "--- FILE: kept.go ---\npackage kept\n") {
+		t.Errorf("Expected only the content served by the reader, got %d files:\n%s", fileCount, promptText)
+	}
+}
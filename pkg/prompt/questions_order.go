@@ -0,0 +1,28 @@
+package prompt
+
+import "sort"
+
+// QuestionsOrder is the order in which the default mode footer writes the questions
+type QuestionsOrder string
+
+const (
+	QuestionsAsGiven QuestionsOrder = "as-given" // In the order they were added (default)
+	QuestionsAsc     QuestionsOrder = "asc"      // By ascending position (ContentItem.Order): the first one given first
+	QuestionsDesc    QuestionsOrder = "desc"     // By descending position: the first one given last, closest to the end of the prompt
+)
+
+// QuestionsOrders lists the valid question orders
+var QuestionsOrders = []QuestionsOrder{QuestionsAsGiven, QuestionsAsc, QuestionsDesc}
+
+// orderedQuestions returns the questions sorted by QuestionsOrder; questions at the same
+// position keep the order they were added in
+func (g *Generator) orderedQuestions() []ContentItem {
+	ordered := append([]ContentItem{}, g.Questions...)
+	switch g.QuestionsOrder {
+	case QuestionsAsc:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Order < ordered[j].Order })
+	case QuestionsDesc:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Order > ordered[j].Order })
+	}
+	return ordered
+}
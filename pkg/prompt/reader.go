@@ -0,0 +1,71 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+// ContentReader reads the content of the files included in the prompt
+type ContentReader interface {
+	ReadContent(path string) ([]byte, error)
+}
+
+// FileSystemReader reads files from the working tree
+type FileSystemReader struct{}
+
+// ReadContent returns the content of a file on disk
+func (FileSystemReader) ReadContent(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// GitBlobReader reads files from a Git revision
+type GitBlobReader struct {
+	Ref string // Commit, branch or tag
+}
+
+// ReadContent returns the content of a file at the revision
+func (r GitBlobReader) ReadContent(path string) ([]byte, error) {
+	return files.ReadGitBlob(r.Ref, path)
+}
+
+// Repo is a repository whose files are listed under a label, when several are included
+type Repo struct {
+	Label string // First component of the paths of the repository files (e.g. "api")
+	Dir   string // Directory of the repository
+}
+
+// RepoReader reads files listed under the label of their repository
+type RepoReader struct {
+	Repos []Repo
+}
+
+// ReadContent returns the content of a file on disk, in the repository named by the first
+// component of its path
+func (r RepoReader) ReadContent(path string) ([]byte, error) {
+	label, rest, _ := strings.Cut(filepath.ToSlash(path), "/")
+	for _, repo := range r.Repos {
+		if repo.Label == label {
+			return os.ReadFile(filepath.Join(repo.Dir, filepath.FromSlash(rest)))
+		}
+	}
+	return nil, fmt.Errorf("no repository labeled '%s'", label)
+}
+
+// contentReader returns the reader of the generator: Reader when set, else a Git blob
+// reader when GitRef is set, else the repositories when Repos is set, else the filesystem
+func (g *Generator) contentReader() ContentReader {
+	switch {
+	case g.Reader != nil:
+		return g.Reader
+	case g.GitRef != "":
+		return GitBlobReader{Ref: g.GitRef}
+	case len(g.Repos) > 0:
+		return RepoReader{Repos: g.Repos}
+	default:
+		return FileSystemReader{}
+	}
+}
@@ -0,0 +1,79 @@
+package prompt
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// redactedText replaces the secret-like substrings masked by Redact
+const redactedText = "***REDACTED***"
+
+// SecretPattern is a named regular expression matching secret-like substrings. When the
+// expression has a subexpression named "secret", only that part is masked by Redact.
+type SecretPattern struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+// secretDetectors are the built-in secret patterns. They are kept conservative: they match
+// well-known key formats, or long values assigned to names that clearly denote secrets.
+var secretDetectors = []SecretPattern{
+	{"AWS access key ID", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"secret assignment", regexp.MustCompile(`(?i)\b\w*(?:api[_-]?key|secret[_-]?key|client[_-]?secret|access[_-]?token|auth[_-]?token|private[_-]?key)\w*["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9_\-/+.=]{16,})`)},
+}
+
+// SecretFinding is a secret-like substring found in the content of an included file
+type SecretFinding struct {
+	Path string // Path of the file, as listed
+	Line int    // 1-based line of the match
+	Kind string // Kind of secret, such as "AWS access key ID"
+}
+
+// findSecrets returns the secret-like substrings of the content of a file, at most one per line
+func findSecrets(path string, content []byte, patterns []SecretPattern) []SecretFinding {
+	var findings []SecretFinding
+	for i, line := range bytes.Split(content, []byte("\n")) {
+		for _, detector := range patterns {
+			if detector.Pattern.Match(line) {
+				findings = append(findings, SecretFinding{Path: path, Line: i + 1, Kind: detector.Kind})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// redactSecrets replaces the substrings of content matched by patterns with redactedText,
+// and returns the number of replacements
+func redactSecrets(content []byte, patterns []SecretPattern) ([]byte, int) {
+	count := 0
+	for _, detector := range patterns {
+		group := detector.Pattern.SubexpIndex("secret")
+		matches := detector.Pattern.FindAllSubmatchIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var result bytes.Buffer
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if group >= 0 && match[2*group] >= 0 {
+				start, end = match[2*group], match[2*group+1]
+			}
+			if start == end {
+				continue
+			}
+			result.Write(content[last:start])
+			result.WriteString(redactedText)
+			last = end
+			count++
+		}
+		result.Write(content[last:])
+		content = result.Bytes()
+	}
+	return content, count
+}
@@ -0,0 +1,96 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bytesPerToken is the average number of bytes per token used to estimate token counts
+const bytesPerToken = 4
+
+// Stats summarizes the size of a generated prompt
+type Stats struct {
+	Files  int
+	Bytes  int
+	Tokens int // Estimated with EstimateTokens
+}
+
+// EstimateTokens returns a rough token count for text, assuming about four bytes per token
+func EstimateTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// ParseFilePaths returns the paths of the files embedded in a prompt generated by this tool,
+// in order, read from their "--- END FILE: path ---" markers (opening markers may carry
+// a language annotation). Without END FILE markers, the paths are read from the opening
+// markers, annotations included, or in compact prompts from the "### path" headers,
+// so Markdown headings in the files are counted as well.
+func ParseFilePaths(text string) []string {
+	var paths, openedPaths, compactPaths []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "--- END FILE: ") && strings.HasSuffix(line, " ---") {
+			paths = append(paths, strings.TrimSuffix(strings.TrimPrefix(line, "--- END FILE: "), " ---"))
+		} else if strings.HasPrefix(line, "--- FILE: ") && strings.HasSuffix(line, " ---") {
+			openedPaths = append(openedPaths, strings.TrimSuffix(strings.TrimPrefix(line, "--- FILE: "), " ---"))
+		} else if path, ok := strings.CutPrefix(line, compactHeaderPrefix); ok {
+			compactPaths = append(compactPaths, path)
+		}
+	}
+	switch {
+	case len(paths) > 0:
+		return paths
+	case len(openedPaths) > 0:
+		return openedPaths
+	}
+	return compactPaths
+}
+
+// ComputeStats computes the size statistics of a prompt generated by this tool
+func ComputeStats(text string) Stats {
+	return Stats{
+		Files:  len(ParseFilePaths(text)),
+		Bytes:  len(text),
+		Tokens: EstimateTokens(text),
+	}
+}
+
+// FormatSummary formats the one-line summary of a generated prompt, e.g.
+// "Generated prompt: 42 files, 128.0KB, ~31k tokens"
+func FormatSummary(stats Stats) string {
+	noun := "files"
+	if stats.Files == 1 {
+		noun = "file"
+	}
+	return fmt.Sprintf("Generated prompt: %d %s, %s, %s tokens", stats.Files, noun, formatSize(int64(stats.Bytes)), formatTokenCount(stats.Tokens))
+}
+
+// formatTokenCount formats an estimated token count compactly: "~950", "~3.4k", "~31k", "~1.2M"
+func formatTokenCount(tokens int) string {
+	switch {
+	case tokens < 1000:
+		return fmt.Sprintf("~%d", tokens)
+	case tokens < 10000:
+		return fmt.Sprintf("~%.1fk", float64(tokens)/1000)
+	case tokens < 1000000:
+		return fmt.Sprintf("~%dk", (tokens+500)/1000)
+	}
+	return fmt.Sprintf("~%.1fM", float64(tokens)/1000000)
+}
+
+// FormatComparison reports the difference between a previous prompt and the current one
+func FormatComparison(previous, current Stats) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("  Files:  %d -> %d (%s)\n", previous.Files, current.Files, formatDelta(previous.Files, current.Files)))
+	builder.WriteString(fmt.Sprintf("  Bytes:  %d -> %d (%s)\n", previous.Bytes, current.Bytes, formatDelta(previous.Bytes, current.Bytes)))
+	builder.WriteString(fmt.Sprintf("  Tokens: ~%d -> ~%d (%s)\n", previous.Tokens, current.Tokens, formatDelta(previous.Tokens, current.Tokens)))
+	return builder.String()
+}
+
+// formatDelta formats the signed difference between two values, with its percentage when defined
+func formatDelta(previous, current int) string {
+	delta := fmt.Sprintf("%+d", current-previous)
+	if previous == 0 {
+		return delta
+	}
+	return fmt.Sprintf("%s, %+.1f%%", delta, float64(current-previous)*100/float64(previous))
+}
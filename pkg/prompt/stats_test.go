@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseFilePaths(t *testing.T) {
+	text := "Project structure...\n" +
+		"--- FILE: src/app.go (go) ---\npackage main\n--- END FILE: src/app.go ---\n\n" +
+		"--- FILE: docs/my notes.md ---\n# Notes\n--- END FILE: docs/my notes.md ---\n"
+
+	paths := ParseFilePaths(text)
+	expected := []string{"src/app.go", "docs/my notes.md"}
+	if strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
+
+func TestCompareStats(t *testing.T) {
+	filesPrompt := func(n int, body string) string {
+		var builder strings.Builder
+		for i := 0; i < n; i++ {
+			builder.WriteString("--- FILE: file.go ---\n" + body + "\n--- END FILE: file.go ---\n")
+		}
+		return builder.String()
+	}
+
+	previous := ComputeStats(filesPrompt(4, strings.Repeat("x", 400)))
+	current := ComputeStats(filesPrompt(2, strings.Repeat("x", 100)))
+
+	if previous.Files != 4 || current.Files != 2 {
+		t.Fatalf("Expected 4 and 2 files, got %d and %d", previous.Files, current.Files)
+	}
+	if current.Tokens != EstimateTokens(filesPrompt(2, strings.Repeat("x", 100))) {
+		t.Errorf("Expected tokens to be estimated from the prompt text, got %d", current.Tokens)
+	}
+
+	report := FormatComparison(previous, current)
+	expected := []string{
+		"Files:  4 -> 2 (-2, -50.0%)",
+		"Bytes:  " + strconv.Itoa(previous.Bytes) + " -> " + strconv.Itoa(current.Bytes) + " (-",
+		"Tokens: ~" + strconv.Itoa(previous.Tokens) + " -> ~" + strconv.Itoa(current.Tokens) + " (-",
+	}
+	for _, line := range expected {
+		if !strings.Contains(report, line) {
+			t.Errorf("Expected report to contain %q, got:\n%s", line, report)
+		}
+	}
+
+	// Comparing to an empty previous prompt reports the raw delta
+	report = FormatComparison(Stats{}, current)
+	if !strings.Contains(report, "Files:  0 -> 2 (+2)\n") {
+		t.Errorf("Expected a positive delta without percentage, got:\n%s", report)
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	tests := []struct {
+		stats    Stats
+		expected string
+	}{
+		{Stats{Files: 1, Bytes: 512, Tokens: 128}, "Generated prompt: 1 file, 512B, ~128 tokens"},
+		{Stats{Files: 42, Bytes: 131072, Tokens: 31250}, "Generated prompt: 42 files, 128.0KB, ~31k tokens"},
+		{Stats{Files: 3, Bytes: 13824, Tokens: 3456}, "Generated prompt: 3 files, 13.5KB, ~3.5k tokens"},
+		{Stats{Files: 900, Bytes: 5 << 20, Tokens: 1310720}, "Generated prompt: 900 files, 5.0MB, ~1.3M tokens"},
+	}
+
+	for _, tc := range tests {
+		if got := FormatSummary(tc.stats); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the context available to prompt templates (see Generator.Template).
+//
+// Example template:
+//
+//	{{.RoleMessage}}
+//	{{if .Tree}}Project structure:
+//	{{.Tree}}{{end}}
+//	{{range .Files}}<file path="{{.Path}}">
+//	{{.Content}}
+//	</file>
+//	{{end}}
+//	{{range .Questions}}Q: {{.}}
+//	{{end}}
+type TemplateData struct {
+	RoleMessage  string         // --role-message text
+	Tree         string         // Project structure ("" when the tree is disabled)
+	TreeHeader   string         // Description of how the tree was built
+	Dependencies string         // Summary of the dependency manifests ("" unless --deps is set)
+	Files        []TemplateFile // Included files, after the size and text checks, auto-included ones first
+	Questions    []string       // Questions, with the question prefix and suffix applied
+	ExtraContext string         // --extra-context text
+	LastWords    string         // --last-words text
+	ContentHash  string         // Combined hash of the included files ("" unless --hash is set)
+}
+
+// TemplateFile is a file exposed to prompt templates
+type TemplateFile struct {
+	Path     string
+	Language string // Detected language ("" if unknown)
+	Hash     string // Short content hash ("" unless --hash is set)
+	Metadata string // Size, modification date and language line ("" unless --file-metadata is set)
+	Content  string
+
+	AutoIncluded bool // Added by --auto-readme or --auto-docs rather than selected by the patterns
+}
+
+// generateTemplateMode renders the prompt through the user-provided template
+func (g *Generator) generateTemplateMode() (string, int, error) {
+	tmpl, err := template.New("prompt").Parse(g.Template)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	data := TemplateData{
+		RoleMessage:  g.RoleMessage,
+		ExtraContext: g.ExtraContext,
+		LastWords:    g.LastWords,
+	}
+
+	if g.IncludeTree {
+		header, tree, err := g.projectTree()
+		if err != nil {
+			if g.Verbosity >= VerbosityNormal {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to get project tree: %v\n", err)
+			}
+			tree = "Error building project tree.\n"
+		}
+		data.TreeHeader = header
+		data.Tree = tree
+	}
+
+	if g.IncludeDeps {
+		_, data.Dependencies = g.dependencySummary()
+	}
+
+	for _, file := range append(g.autoIncludedFiles(), g.selectedFiles()...) {
+		content, ok := g.readFileContent(file)
+		if !ok {
+			continue
+		}
+		templateFile := TemplateFile{
+			Path:     g.displayPath(file.Path),
+			Language: file.Language,
+			Content:  string(content),
+
+			AutoIncluded: file.AutoIncluded,
+		}
+		if g.HashContent {
+			templateFile.Hash = shortHash(content)
+			g.recordContent(file, content)
+		}
+		if g.FileMetadata {
+			templateFile.Metadata = fileMetadata(file)
+		}
+		data.Files = append(data.Files, templateFile)
+	}
+	if g.HashContent {
+		data.ContentHash = g.hasher.sum()
+	}
+
+	for _, q := range g.Questions {
+		data.Questions = append(data.Questions, g.formatQuestion(q.Content))
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", 0, fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	return builder.String(), len(data.Files), nil
+}
@@ -0,0 +1,154 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+// fileOverheadTokens estimates the tokens of the separators around a file, beyond its path
+const fileOverheadTokens = 8
+
+// FileRanker orders files by relevance, most relevant first. AutoTrim drops the last ones first.
+type FileRanker func(fileList []files.FileInfo) []files.FileInfo
+
+// RelevanceRanker returns the default ranking of AutoTrim: force included files first, then
+// the files matched by earlier include patterns, then smaller files, then the most recently
+// modified ones. patterns are the include patterns in command-line order.
+func RelevanceRanker(patterns []string) FileRanker {
+	patternRank := make(map[string]int, len(patterns))
+	for i, pattern := range patterns {
+		if _, exists := patternRank[pattern]; !exists {
+			patternRank[pattern] = i
+		}
+	}
+	rankOf := func(file files.FileInfo) int {
+		if rank, ok := patternRank[file.MatchedPattern]; ok {
+			return rank
+		}
+		return len(patterns)
+	}
+
+	return func(fileList []files.FileInfo) []files.FileInfo {
+		ranked := append([]files.FileInfo{}, fileList...)
+		sort.SliceStable(ranked, func(i, j int) bool {
+			a, b := ranked[i], ranked[j]
+			switch {
+			case a.IsForced != b.IsForced:
+				return a.IsForced
+			case rankOf(a) != rankOf(b):
+				return rankOf(a) < rankOf(b)
+			case a.Size != b.Size:
+				return a.Size < b.Size
+			}
+			return a.ModTime.After(b.ModTime)
+		})
+		return ranked
+	}
+}
+
+// estimateFileTokens estimates the tokens a file adds to the prompt, from its size
+func estimateFileTokens(file files.FileInfo) int {
+	return EstimateTokens(file.Path)*2 + int(file.Size)/bytesPerToken + fileOverheadTokens
+}
+
+// autoTrim drops the least relevant files until the prompt fits MaxTokens, regenerating the
+// prompt after each round of drops, and reports the dropped files
+func (g *Generator) autoTrim(promptText string, fileCount int) (string, int, error) {
+	ranker := g.Ranker
+	if ranker == nil {
+		ranker = RelevanceRanker(nil)
+	}
+	ranked := ranker(g.listedFiles())
+
+	// The files, items and diagnostics of the caller are restored once trimmed
+	originalFiles, originalItems := g.Files, g.ContentItems
+	verbosity, showProgress := g.Verbosity, g.ShowProgress
+	defer func() {
+		g.Files, g.ContentItems = originalFiles, originalItems
+		g.Verbosity, g.ShowProgress = verbosity, showProgress
+	}()
+	g.Verbosity, g.ShowProgress = VerbosityQuiet, false
+
+	var dropped []files.FileInfo
+	droppedPaths := make(map[string]bool)
+	tokens := EstimateTokens(promptText)
+	for tokens > g.MaxTokens && len(ranked) > 0 {
+		for excess := tokens - g.MaxTokens; excess > 0 && len(ranked) > 0; {
+			last := ranked[len(ranked)-1]
+			ranked = ranked[:len(ranked)-1]
+			dropped = append(dropped, last)
+			droppedPaths[last.Path] = true
+			excess -= estimateFileTokens(last)
+		}
+
+		g.Files = withoutFiles(originalFiles, droppedPaths)
+		g.ContentItems = make([]ContentItem, len(originalItems))
+		for i, item := range originalItems {
+			if item.Type == "file_group" {
+				item.Files = withoutFiles(item.Files, droppedPaths)
+			}
+			g.ContentItems[i] = item
+		}
+
+		var err error
+		promptText, fileCount, err = g.generate()
+		if err != nil {
+			return "", 0, err
+		}
+		tokens = EstimateTokens(promptText)
+	}
+
+	if verbosity >= VerbosityNormal {
+		fmt.Fprintf(os.Stderr, "Info: Auto-trim dropped %d file(s) to fit --max-tokens %d (now ~%d tokens), least relevant first:\n", len(dropped), g.MaxTokens, tokens)
+		for _, file := range dropped {
+			fmt.Fprintf(os.Stderr, "  - %s (%s)\n", file.Path, trimReason(file))
+		}
+		if tokens > g.MaxTokens {
+			fmt.Fprintf(os.Stderr, "Warning: The prompt is still ~%d tokens without any file, above --max-tokens %d.\n", tokens, g.MaxTokens)
+		}
+	}
+	return promptText, fileCount, nil
+}
+
+// listedFiles returns the files the prompt may include, once each, in listing order
+func (g *Generator) listedFiles() []files.FileInfo {
+	if len(g.ContentItems) == 0 {
+		return g.Files
+	}
+	var listed []files.FileInfo
+	seen := make(map[string]bool)
+	for _, item := range g.ContentItems {
+		for _, file := range item.Files {
+			if !seen[file.Path] {
+				seen[file.Path] = true
+				listed = append(listed, file)
+			}
+		}
+	}
+	return listed
+}
+
+// withoutFiles returns the files of a list whose path is not in dropped
+func withoutFiles(fileList []files.FileInfo, dropped map[string]bool) []files.FileInfo {
+	var kept []files.FileInfo
+	for _, file := range fileList {
+		if !dropped[file.Path] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// trimReason describes the ranking criteria of a dropped file
+func trimReason(file files.FileInfo) string {
+	reason := "matched no include pattern"
+	if file.IsForced {
+		reason = "force included"
+	} else if file.MatchedPattern != "" {
+		reason = "matched '" + file.MatchedPattern + "'"
+	}
+	return reason + ", " + formatSize(file.Size) + ", modified " + file.ModTime.Format("2006-01-02")
+}
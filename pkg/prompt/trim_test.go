@@ -0,0 +1,78 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/briossant/make-project-prompt/pkg/files"
+)
+
+func TestRelevanceRanker(t *testing.T) {
+	now := time.Now()
+	fileList := []files.FileInfo{
+		{Path: "docs/guide.md", MatchedPattern: "docs/*", Size: 10},
+		{Path: "src/big.go", MatchedPattern: "src/*", Size: 5000},
+		{Path: "src/old.go", MatchedPattern: "src/*", Size: 100, ModTime: now.Add(-time.Hour)},
+		{Path: "src/new.go", MatchedPattern: "src/*", Size: 100, ModTime: now},
+		{Path: "Makefile", IsForced: true, Size: 9000},
+	}
+
+	ranked := RelevanceRanker([]string{"src/*", "docs/*"})(fileList)
+	var paths []string
+	for _, file := range ranked {
+		paths = append(paths, file.Path)
+	}
+	expected := "Makefile,src/new.go,src/old.go,src/big.go,docs/guide.md"
+	if got := strings.Join(paths, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if fileList[0].Path != "docs/guide.md" {
+		t.Error("Expected the input list to be left as is")
+	}
+}
+
+func TestGenerator_AutoTrim(t *testing.T) {
+	body := strings.Repeat("x", 399) + "\n"
+	fileInfos := []files.FileInfo{
+		{Path: "a.go", IsText: true, Size: 400, IsRegular: true, MatchedPattern: "*.go"},
+		{Path: "b.md", IsText: true, Size: 400, IsRegular: true, MatchedPattern: "*.md"},
+		{Path: "c.go", IsText: true, Size: 400, IsRegular: true, MatchedPattern: "*.go"},
+	}
+	reader := memoryReader{"a.go": body, "b.md": body, "c.go": body}
+
+	newGenerator := func() *Generator {
+		generator := NewGenerator(fileInfos, "Why?", true)
+		generator.IncludeTree = false
+		generator.Reader = reader
+		generator.Verbosity = VerbosityQuiet
+		return generator
+	}
+
+	// Without auto-trim, the prompt is only warned about
+	generator := newGenerator()
+	full, count, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	generator.MaxTokens = EstimateTokens(full) - 50
+	if _, count2, _ := generator.Generate(); count2 != count {
+		t.Errorf("Expected all %d files without --auto-trim, got %d", count, count2)
+	}
+
+	generator.AutoTrim = true
+	generator.Ranker = RelevanceRanker([]string{"*.go", "*.md"})
+	promptText, count, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if count != 2 || strings.Contains(promptText, "--- FILE: b.md ---") {
+		t.Errorf("Expected the file of the last pattern to be dropped, got %d files:\n%s", count, promptText)
+	}
+	if EstimateTokens(promptText) > generator.MaxTokens {
+		t.Errorf("Expected the prompt to fit %d tokens, got %d", generator.MaxTokens, EstimateTokens(promptText))
+	}
+	if len(generator.Files) != 3 {
+		t.Errorf("Expected the files of the generator to be restored, got %d", len(generator.Files))
+	}
+}
@@ -0,0 +1,11 @@
+package prompt
+
+// Verbosity is the amount of diagnostics written on stderr
+type Verbosity int
+
+const (
+	VerbosityQuiet   Verbosity = iota // Essential output only (--quiet)
+	VerbosityNormal                   // Info messages and warnings (default)
+	VerbosityVerbose                  // Per-file decisions as well (-v)
+	VerbosityDebug                    // Timings as well (-vv)
+)